@@ -3,17 +3,17 @@ package commands
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/dgraph-io/travel/business/data"
 	"github.com/dgraph-io/travel/business/data/user"
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
 // AddUser handles the creation of users.
-func AddUser(log *log.Logger, gqlConfig data.GraphQLConfig, newUser user.NewUser) error {
+func AddUser(log *logger.Logger, gqlConfig data.GraphQLConfig, newUser user.NewUser) error {
 	if newUser.Name == "" || newUser.Email == "" || newUser.Password == "" || newUser.Role == "" {
 		fmt.Println("help: adduser <name> <email> <password> <role>")
 		return ErrHelp
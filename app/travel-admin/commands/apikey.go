@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/apikey"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// AddAPIKey creates a new api key for a machine client. The key is only
+// displayed once since only its hash is stored.
+func AddAPIKey(log *logger.Logger, gqlConfig data.GraphQLConfig, nak apikey.NewAPIKey) error {
+	if nak.Name == "" || len(nak.Scopes) == 0 || nak.Lifetime <= 0 {
+		fmt.Println("help: addapikey <name> <scopes> <lifetime>")
+		fmt.Println("scopes: comma separated list, eg feed:upload,city:write")
+		fmt.Println("lifetime: how long the key is valid, eg 8760h")
+		return ErrHelp
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := apikey.NewStore(
+		log,
+		data.NewGraphQL(gqlConfig),
+	)
+	traceID := uuid.New().String()
+
+	key, ak, err := store.Add(ctx, traceID, nak, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "adding api key")
+	}
+
+	fmt.Printf("api key id: %s expires: %s\n", ak.ID, ak.ExpiresAt.Format(time.RFC3339))
+	fmt.Printf("-----BEGIN API KEY-----\n%s\n-----END API KEY-----\n", key)
+	return nil
+}
+
+// GetAPIKeys lists the api keys without the keys themselves.
+func GetAPIKeys(log *logger.Logger, gqlConfig data.GraphQLConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := apikey.NewStore(
+		log,
+		data.NewGraphQL(gqlConfig),
+	)
+	traceID := uuid.New().String()
+
+	keys, err := store.QueryAll(ctx, traceID)
+	if err != nil {
+		return errors.Wrap(err, "getting api keys")
+	}
+
+	for _, ak := range keys {
+		fmt.Printf("%s\t%s\t%s\texpires: %s\n", ak.ID, ak.Name, strings.Join(ak.Scopes, ","), ak.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// RevokeAPIKey removes the api key so it can't be used again.
+func RevokeAPIKey(log *logger.Logger, gqlConfig data.GraphQLConfig, keyID string) error {
+	if keyID == "" {
+		fmt.Println("help: revokeapikey <id>")
+		return ErrHelp
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := apikey.NewStore(
+		log,
+		data.NewGraphQL(gqlConfig),
+	)
+	traceID := uuid.New().String()
+
+	if err := store.Delete(ctx, traceID, keyID); err != nil {
+		return errors.Wrap(err, "revoking api key")
+	}
+
+	fmt.Println("api key revoked")
+	return nil
+}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/city"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// DeleteCity removes a city along with its weather, advisory and places.
+func DeleteCity(log *logger.Logger, gqlConfig data.GraphQLConfig, cityID string) error {
+	if cityID == "" {
+		fmt.Println("help: deletecity <id>")
+		return ErrHelp
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := city.NewStore(
+		log,
+		data.NewGraphQL(gqlConfig),
+	)
+	traceID := uuid.New().String()
+
+	if err := store.Delete(ctx, traceID, cityID); err != nil {
+		return errors.Wrap(err, "deleting city")
+	}
+
+	fmt.Println("city deleted:", cityID)
+	return nil
+}
@@ -2,10 +2,7 @@ package commands
 
 import (
 	"context"
-	"crypto/rsa"
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"time"
 
@@ -13,16 +10,18 @@ import (
 	"github.com/dgraph-io/travel/business/data/user"
 	"github.com/dgraph-io/travel/business/sys/auth"
 	"github.com/dgraph-io/travel/foundation/keystore"
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
-// GenToken generates a JWT for the specified user.
-func GenToken(log *log.Logger, gqlConfig data.GraphQLConfig, email string, privateKeyFile string, algorithm string) error {
-	if email == "" || privateKeyFile == "" || algorithm == "" {
-		fmt.Println("help: gentoken <email> <private_key_file> <algorithm>")
-		fmt.Println("algorithm: RS256, HS256")
+// GenToken generates a JWT for the specified user signed with the active
+// key in the keys folder.
+func GenToken(log *logger.Logger, gqlConfig data.GraphQLConfig, email string, keysFolder string, algorithm string) error {
+	if email == "" || keysFolder == "" || algorithm == "" {
+		fmt.Println("help: gentoken <email> <keys_folder> <algorithm>")
+		fmt.Println("algorithm: used with RSA keys (RS256, RS384, RS512, PS256); other keys use the algorithm of their type")
 		return ErrHelp
 	}
 
@@ -41,36 +40,27 @@ func GenToken(log *log.Logger, gqlConfig data.GraphQLConfig, email string, priva
 		return errors.Wrap(err, "getting user")
 	}
 
-	// limit PEM file size to 1 megabyte. This should be reasonable for
-	// almost any PEM file and prevents shenanegans like linking the file
-	// to /dev/random or something like that.
-	pkf, err := os.Open(privateKeyFile)
-	if err != nil {
-		return errors.Wrap(err, "opening PEM private key file")
-	}
-	defer pkf.Close()
-	privatePEM, err := io.ReadAll(io.LimitReader(pkf, 1024*1024))
-	if err != nil {
-		return errors.Wrap(err, "reading PEM private key file")
-	}
-
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+	// In a production system, a key id (KID) is used to retrieve the correct
+	// public key to parse a JWT for auth and claims. The keys folder records
+	// which key is active so tokens are signed with the latest rotated key.
+	ks, err := keystore.NewFS(os.DirFS(keysFolder))
 	if err != nil {
-		return errors.Wrap(err, "parsing PEM into private key")
+		return errors.Wrap(err, "reading keys")
 	}
 
-	// In a production system, a key id (KID) is used to retrieve the correct
-	// public key to parse a JWT for auth and claims.
-	const keyID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
-
 	// An authenticator maintains the state required to handle JWT processing.
 	// It requires a keystore to lookup private and public keys based on a
 	// key id. There is a keystore implementation in the project.
-	a, err := auth.New("RS256", keystore.NewMap(map[string]*rsa.PrivateKey{keyID: privateKey}))
+	a, err := auth.New(algorithm, ks, nil)
 	if err != nil {
 		return errors.Wrap(err, "constructing authenticator")
 	}
 
+	keyID, err := a.ActiveKID()
+	if err != nil {
+		return errors.Wrap(err, "looking up active key")
+	}
+
 	// Generating a token requires defining a set of claims. In this applications
 	// case, we only care about defining the subject and the user in question and
 	// the roles they have on the database. This token will expire in a year.
@@ -90,14 +80,15 @@ func GenToken(log *log.Logger, gqlConfig data.GraphQLConfig, email string, priva
 			IssuedAt:  jwt.Now(),
 		},
 		Auth: auth.StandardClaims{
-			Role: usr.Role,
+			Role:   usr.Role,
+			Scopes: auth.RoleScopes(usr.Role),
+			Email:  usr.Email,
 		},
 	}
 
 	// This will generate a JWT with the claims embedded in them. The database
 	// with need to be configured with the information found in the public key
-	// file to validate these claims. Dgraph does not support key rotate at
-	// this time.
+	// file, or the travel-api jwks endpoint, to validate these claims.
 	token, err := a.GenerateToken(keyID, claims)
 	if err != nil {
 		return errors.Wrap(err, "generating token")
@@ -106,3 +97,43 @@ func GenToken(log *log.Logger, gqlConfig data.GraphQLConfig, email string, priva
 	fmt.Printf("-----BEGIN TOKEN-----\n%s\n-----END TOKEN-----\n", token)
 	return nil
 }
+
+// AdminToken generates a short lived ADMIN token signed with the active key
+// in the keys folder. It's used to access the database when no token is
+// configured.
+func AdminToken(keysFolder string, algorithm string, lifetime time.Duration) (string, error) {
+	ks, err := keystore.NewFS(os.DirFS(keysFolder))
+	if err != nil {
+		return "", errors.Wrap(err, "reading keys")
+	}
+
+	a, err := auth.New(algorithm, ks, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "constructing authenticator")
+	}
+
+	keyID, err := a.ActiveKID()
+	if err != nil {
+		return "", errors.Wrap(err, "looking up active key")
+	}
+
+	claims := auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "travel project",
+			Subject:   "travel-admin",
+			ExpiresAt: jwt.At(time.Now().Add(lifetime)),
+			IssuedAt:  jwt.Now(),
+		},
+		Auth: auth.StandardClaims{
+			Role:   auth.RoleAdmin,
+			Scopes: auth.RoleScopes(auth.RoleAdmin),
+		},
+	}
+
+	token, err := a.GenerateToken(keyID, claims)
+	if err != nil {
+		return "", errors.Wrap(err, "generating token")
+	}
+
+	return token, nil
+}
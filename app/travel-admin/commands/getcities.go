@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/city"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// GetCities returns a page of cities loaded in the system.
+func GetCities(log *logger.Logger, gqlConfig data.GraphQLConfig, pageNumber int, rowsPerPage int) error {
+	if pageNumber < 1 || rowsPerPage < 1 {
+		fmt.Println("help: getcities <page_number> <rows_per_page>")
+		return ErrHelp
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := city.NewStore(
+		log,
+		data.NewGraphQL(gqlConfig),
+	)
+	traceID := uuid.New().String()
+
+	cities, err := store.QueryAll(ctx, traceID, pageNumber, rowsPerPage)
+	if err != nil {
+		return errors.Wrap(err, "getting cities")
+	}
+
+	for _, cty := range cities {
+		fmt.Printf("city: %#v\n", cty)
+	}
+	return nil
+}
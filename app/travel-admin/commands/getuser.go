@@ -3,17 +3,17 @@ package commands
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/dgraph-io/travel/business/data"
 	"github.com/dgraph-io/travel/business/data/user"
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
 // GetUser returns information about a user by email.
-func GetUser(log *log.Logger, gqlConfig data.GraphQLConfig, email string) error {
+func GetUser(log *logger.Logger, gqlConfig data.GraphQLConfig, email string) error {
 	if email == "" {
 		fmt.Println("help: getuser <email>")
 		return ErrHelp
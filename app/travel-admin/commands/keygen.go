@@ -1,6 +1,10 @@
 package commands
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -11,12 +15,18 @@ import (
 	"github.com/pkg/errors"
 )
 
-// KeyGen creates an x509 private/public key for auth tokens.
-func KeyGen() error {
+// KeyGen creates an x509 private/public key for auth tokens signed with the
+// specified algorithm.
+func KeyGen(algorithm string) error {
+	if algorithm == "" {
+		algorithm = "RS256"
+	}
 
 	// Generate a new private key.
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	privateKey, err := generateKey(algorithm)
 	if err != nil {
+		fmt.Println("help: keygen <algorithm>")
+		fmt.Println("algorithm: RS256, ES256, ES384, EdDSA")
 		return ErrHelp
 	}
 
@@ -28,18 +38,18 @@ func KeyGen() error {
 	defer privateFile.Close()
 
 	// Construct a PEM block for the private key.
-	privateBlock := pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	privateBlock, err := privateKeyBlock(privateKey)
+	if err != nil {
+		return err
 	}
 
 	// Write the private key to the private key file.
-	if err := pem.Encode(privateFile, &privateBlock); err != nil {
+	if err := pem.Encode(privateFile, privateBlock); err != nil {
 		return errors.Wrap(err, "encoding to private file")
 	}
 
 	// Marshal the public key from the private key to PKIX.
-	asn1Bytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	asn1Bytes, err := x509.MarshalPKIXPublicKey(privateKey.Public())
 	if err != nil {
 		return errors.Wrap(err, "marshaling public key")
 	}
@@ -49,13 +59,16 @@ func KeyGen() error {
 	if err != nil {
 		return errors.Wrap(err, "creating public file")
 	}
-	defer privateFile.Close()
+	defer publicFile.Close()
 
 	// Construct a PEM block for the public key.
 	publicBlock := pem.Block{
-		Type:  "RSA PUBLIC KEY",
+		Type:  "PUBLIC KEY",
 		Bytes: asn1Bytes,
 	}
+	if _, ok := privateKey.(*rsa.PrivateKey); ok {
+		publicBlock.Type = "RSA PUBLIC KEY"
+	}
 
 	// Write the public key to the private key file.
 	if err := pem.Encode(publicFile, &publicBlock); err != nil {
@@ -65,3 +78,42 @@ func KeyGen() error {
 	fmt.Println("private and public key files generated")
 	return nil
 }
+
+// generateKey generates a private key for signing with the algorithm.
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case "RS256", "RS384", "RS512":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "EdDSA":
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	}
+
+	return nil, errors.Errorf("unsupported algorithm %q", algorithm)
+}
+
+// privateKeyBlock constructs the PEM block for a private key. RSA and ECDSA
+// keys use their traditional encodings and Ed25519 keys use PKCS8.
+func privateKeyBlock(privateKey crypto.Signer) (*pem.Block, error) {
+	switch pk := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(pk)}, nil
+
+	case *ecdsa.PrivateKey:
+		b, err := x509.MarshalECPrivateKey(pk)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshaling private key")
+		}
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}, nil
+	}
+
+	b, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling private key")
+	}
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: b}, nil
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/keystore"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// KeyRotate generates a new private key in the keys folder and marks it as
+// the active signing key. The previous active key is retired and kept to
+// verify the tokens it signed. Retired keys are removed once they have been
+// retired for longer than the retain duration, which should be at least the
+// lifetime of the longest lived token.
+func KeyRotate(keysFolder string, retain time.Duration, algorithm string) error {
+	if keysFolder == "" || retain <= 0 {
+		fmt.Println("help: keyrotate <keys_folder> <retain> <algorithm>")
+		fmt.Println("retain: how long retired keys are kept for verification, eg 8760h")
+		fmt.Println("algorithm: RS256, ES256, ES384, EdDSA (default RS256)")
+		return ErrHelp
+	}
+	if algorithm == "" {
+		algorithm = "RS256"
+	}
+
+	now := time.Now()
+
+	// Capture the key being retired so its retirement time can be recorded.
+	var retiredKID string
+	if ks, err := keystore.NewFS(os.DirFS(keysFolder)); err == nil {
+		retiredKID = ks.ActiveKID()
+	}
+
+	// Generate the new key and write it to the folder using the kid as the
+	// name of the file.
+	privateKey, err := generateKey(algorithm)
+	if err != nil {
+		return errors.Wrap(err, "generating key")
+	}
+	kid := uuid.New().String()
+
+	privateBlock, err := privateKeyBlock(privateKey)
+	if err != nil {
+		return err
+	}
+	privateFile := filepath.Join(keysFolder, kid+".pem")
+	if err := os.WriteFile(privateFile, pem.EncodeToMemory(privateBlock), 0600); err != nil {
+		return errors.Wrap(err, "writing private file")
+	}
+
+	// The modification time of a retired key file records when the key was
+	// retired.
+	if retiredKID != "" {
+		retiredFile := filepath.Join(keysFolder, retiredKID+".pem")
+		if err := os.Chtimes(retiredFile, now, now); err != nil {
+			return errors.Wrap(err, "recording retired key")
+		}
+	}
+
+	// Replace the active file in one step so a service reloading the folder
+	// never sees a partial kid.
+	activeFile := filepath.Join(keysFolder, keystore.ActiveFile)
+	tmpFile := activeFile + ".tmp"
+	if err := os.WriteFile(tmpFile, []byte(kid+"\n"), 0644); err != nil {
+		return errors.Wrap(err, "writing active file")
+	}
+	if err := os.Rename(tmpFile, activeFile); err != nil {
+		return errors.Wrap(err, "replacing active file")
+	}
+
+	// Remove the retired keys that can no longer have valid tokens.
+	entries, err := os.ReadDir(keysFolder)
+	if err != nil {
+		return errors.Wrap(err, "reading keys folder")
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".pem")
+		if name == kid || name == retiredKID {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return errors.Wrap(err, "reading key file info")
+		}
+		if now.Sub(info.ModTime()) <= retain {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(keysFolder, entry.Name())); err != nil {
+			return errors.Wrap(err, "removing expired key")
+		}
+		fmt.Printf("removed expired key: %s\n", name)
+	}
+
+	fmt.Printf("active key: %s\n", kid)
+	if retiredKID != "" {
+		fmt.Printf("retired key: %s\n", retiredKID)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/token"
+	"github.com/dgraph-io/travel/business/data/user"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// RevokeTokens revokes every access and refresh token issued to a user up
+// until now. Only services tracking revocations in Dgraph will see it.
+func RevokeTokens(log *logger.Logger, gqlConfig data.GraphQLConfig, email string) error {
+	if email == "" {
+		fmt.Println("help: revoketokens <email>")
+		return ErrHelp
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gql := data.NewGraphQL(gqlConfig)
+	userStore := user.NewStore(log, gql)
+	tokenStore := token.NewStore(log, gql)
+	traceID := uuid.New().String()
+
+	usr, err := userStore.QueryByEmail(ctx, traceID, email)
+	if err != nil {
+		return errors.Wrap(err, "getting user")
+	}
+
+	// Keep the revocation for as long as the tokens generated by the
+	// gentoken command are valid.
+	now := time.Now()
+	if err := tokenStore.RevokeUser(ctx, traceID, usr.ID, now, now.Add(8760*time.Hour)); err != nil {
+		return errors.Wrap(err, "revoking tokens")
+	}
+
+	if err := tokenStore.Prune(ctx, traceID, now); err != nil {
+		return errors.Wrap(err, "pruning expired tokens")
+	}
+
+	fmt.Printf("revoked tokens for user: %s\n", usr.ID)
+	return nil
+}
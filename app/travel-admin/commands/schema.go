@@ -1,14 +1,15 @@
 package commands
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"os"
 
 	"github.com/dgraph-io/travel/business/data"
 	"github.com/dgraph-io/travel/business/data/schema"
 	"github.com/dgraph-io/travel/business/data/user"
 	"github.com/dgraph-io/travel/business/feeds/loader"
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
@@ -24,7 +25,7 @@ func Schema(gqlConfig data.GraphQLConfig, config schema.Config) error {
 }
 
 // Seed handles loading the databse with a user and city data.
-func Seed(log *log.Logger, gqlConfig data.GraphQLConfig, config loader.Config) error {
+func Seed(log *logger.Logger, gqlConfig data.GraphQLConfig, config loader.Config) error {
 	if os.Getenv("TRAVEL_API_KEYS_MAPS_KEY") == "" {
 		return errors.New("TRAVEL_API_KEYS_MAPS_KEY is not set with map key")
 	}
@@ -36,7 +37,7 @@ func Seed(log *log.Logger, gqlConfig data.GraphQLConfig, config loader.Config) e
 		Role:     "ADMIN",
 	}
 
-	log.Println("main: Adding User:", newUser.Name)
+	log.Info("adding user", "name", newUser.Name)
 	if err := AddUser(log, gqlConfig, newUser); err != nil {
 		if errors.Cause(err) != user.ErrExists {
 			return errors.Wrap(err, "adding user")
@@ -62,9 +63,9 @@ func Seed(log *log.Logger, gqlConfig data.GraphQLConfig, config loader.Config) e
 			Lng:         city.Lng,
 		}
 
-		log.Println("main: Adding city:", search.CityName)
 		traceID := uuid.New().String()
-		if err := loader.UpdateData(log, gqlConfig, traceID, config, search); err != nil {
+		log.Info("adding city", "traceid", traceID, "city", search.CityName)
+		if err := loader.UpdateData(context.Background(), log, gqlConfig, traceID, config, search); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/city"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// UpdateCity handles renaming or relocating a city.
+func UpdateCity(log *logger.Logger, gqlConfig data.GraphQLConfig, cty city.City) error {
+	if cty.ID == "" || cty.Name == "" {
+		fmt.Println("help: updatecity <id> <name> <lat> <lng>")
+		return ErrHelp
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := city.NewStore(
+		log,
+		data.NewGraphQL(gqlConfig),
+	)
+	traceID := uuid.New().String()
+
+	if err := store.Update(ctx, traceID, cty); err != nil {
+		return errors.Wrap(err, "updating city")
+	}
+
+	fmt.Println("city updated:", cty.ID)
+	return nil
+}
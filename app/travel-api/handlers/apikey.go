@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data/apikey"
+	"github.com/dgraph-io/travel/business/sys/auth"
+	"github.com/dgraph-io/travel/business/web/mid"
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/pkg/errors"
+)
+
+// apiKeyAuthenticator constructs the function used by the Authenticate
+// middleware to exchange an api key for claims. The claims carry only the
+// scopes granted to the key and no role.
+func apiKeyAuthenticator(store apikey.Store) mid.KeyAuthenticator {
+	return func(ctx context.Context, key string) (auth.Claims, error) {
+		traceID := "00000000-0000-0000-0000-000000000000"
+		now := time.Now()
+		if v, ok := ctx.Value(web.KeyValues).(*web.Values); ok {
+			traceID = v.TraceID
+			now = v.Now
+		}
+
+		ak, err := store.Authenticate(ctx, traceID, key, now)
+		if err != nil {
+			return auth.Claims{}, errors.Wrap(err, "authenticating api key")
+		}
+
+		claims := auth.Claims{
+			StandardClaims: jwt.StandardClaims{
+				Issuer:    "travel project",
+				Subject:   "apikey:" + ak.ID,
+				ExpiresAt: jwt.At(ak.ExpiresAt),
+				IssuedAt:  jwt.At(ak.DateCreated),
+			},
+			Auth: auth.StandardClaims{
+				Scopes: ak.Scopes,
+			},
+		}
+
+		return claims, nil
+	}
+}
@@ -1,46 +1,65 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
-	"log"
 	"net/http"
-	"time"
+	"os"
 
-	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/foundation/health"
+	"github.com/dgraph-io/travel/foundation/logger"
 )
 
 type checkGroup struct {
-	build     string
-	gqlConfig data.GraphQLConfig
+	build  string
+	log    *logger.Logger
+	health *health.Registry
 }
 
-func (cg *checkGroup) readiness(w http.ResponseWriter, r *http.Request) {
-	health := struct {
+// liveness reports the service is running. No dependency is checked so a
+// problem with one doesn't get the service restarted.
+func (cg *checkGroup) liveness(w http.ResponseWriter, r *http.Request) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unavailable"
+	}
+
+	info := struct {
 		Version string `json:"version"`
 		Status  string `json:"status"`
+		Host    string `json:"host"`
 	}{
 		Version: cg.build,
+		Status:  health.StatusUp,
+		Host:    host,
 	}
 
-	// Wait for a second to see if the database is ready.
-	ctx, cancel := context.WithTimeout(r.Context(), time.Second)
-	defer cancel()
+	if err := response(w, http.StatusOK, info); err != nil {
+		cg.log.Error("liveness", "error", err)
+	}
+}
 
-	if err := data.Validate(ctx, cg.gqlConfig.URL, 100*time.Millisecond); err != nil {
+// readiness reports whether the service can handle requests along with the
+// health of each component it depends on. A service that is only degraded
+// is still ready.
+func (cg *checkGroup) readiness(w http.ResponseWriter, r *http.Request) {
+	report := struct {
+		Version string `json:"version"`
+		health.Report
+	}{
+		Version: cg.build,
+		Report:  cg.health.Check(r.Context()),
+	}
 
-		// If the database is not ready we will tell the client and use a 500
-		// status. Do not respond by just returning an error because further up in
-		// the call stack will interpret that as an unhandled error.
-		health.Status = "db not ready"
-		if err := response(w, http.StatusInternalServerError, health); err != nil {
-			log.Println("liveness", "ERROR", err)
-		}
+	// If a critical component is down we will tell the client and use a
+	// 503 status. Do not respond by just returning an error because further
+	// up in the call stack will interpret that as an unhandled error.
+	statusCode := http.StatusOK
+	if report.Status == health.StatusDown {
+		statusCode = http.StatusServiceUnavailable
 	}
 
-	health.Status = "ok"
-	if err := response(w, http.StatusOK, health); err != nil {
-		log.Println("liveness", "ERROR", err)
+	if err := response(w, statusCode, report); err != nil {
+		cg.log.Error("readiness", "error", err)
 	}
 }
 
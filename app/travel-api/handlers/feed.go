@@ -3,21 +3,24 @@ package handlers
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/http"
 	"time"
 
 	"github.com/dgraph-io/travel/business/data"
 	"github.com/dgraph-io/travel/business/data/schema"
 	"github.com/dgraph-io/travel/business/feeds/loader"
+	"github.com/dgraph-io/travel/business/sys/validate"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/dgraph-io/travel/foundation/web"
 	"github.com/pkg/errors"
 )
 
 type feedGroup struct {
-	log          *log.Logger
+	log          *logger.Logger
 	gqlConfig    data.GraphQLConfig
 	loaderConfig loader.Config
+	app          *web.App
 }
 
 func (fg *feedGroup) upload(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -31,11 +34,13 @@ func (fg *feedGroup) upload(ctx context.Context, w http.ResponseWriter, r *http.
 		return web.NewShutdownError("web value missing from context")
 	}
 
-	go func() {
-		fg.log.Printf("%s: started G: %s %s -> %s",
-			v.TraceID,
-			r.Method, r.URL.Path, r.RemoteAddr,
-		)
+	// The feed is loaded after the response is sent so the work can't use
+	// the request context. It continues the trace of the request instead
+	// and is tracked by the app so shutdown waits for it.
+	loadCtx := tracer.ContextWithSpan(context.Background(), tracer.SpanFromContext(ctx))
+
+	load := func(loadCtx context.Context) {
+		fg.log.Info("feed started", "traceid", v.TraceID, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
 
 		search := loader.Search{
 			CityName:    request.CityName,
@@ -43,21 +48,22 @@ func (fg *feedGroup) upload(ctx context.Context, w http.ResponseWriter, r *http.
 			Lat:         request.Lat,
 			Lng:         request.Lng,
 		}
-		if err := loader.UpdateData(fg.log, fg.gqlConfig, v.TraceID, fg.loaderConfig, search); err != nil {
-			fg.log.Printf("%s: completed G: %s %s -> %s (%d) (%s) : ERROR : %v",
-				v.TraceID,
-				r.Method, r.URL.Path, r.RemoteAddr,
-				v.StatusCode, time.Since(v.Now), err,
+		if err := loader.UpdateData(loadCtx, fg.log, fg.gqlConfig, v.TraceID, fg.loaderConfig, search); err != nil {
+			fg.log.Error("feed completed", "traceid", v.TraceID, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr,
+				"since", time.Since(v.Now), "error", err,
 			)
 			return
 		}
 
-		fg.log.Printf("%s: completed G: %s %s -> %s (%d) (%s)",
-			v.TraceID,
-			r.Method, r.URL.Path, r.RemoteAddr,
-			v.StatusCode, time.Since(v.Now),
+		fg.log.Info("feed completed", "traceid", v.TraceID, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr,
+			"since", time.Since(v.Now),
 		)
-	}()
+	}
+
+	name := fmt.Sprintf("feed upload of %s, %s", request.CityName, request.CountryCode)
+	if err := fg.app.Go(loadCtx, name, load); err != nil {
+		return validate.NewRequestError(err, http.StatusServiceUnavailable)
+	}
 
 	resp := schema.UploadFeedResponse{
 		CountryCode: request.CountryCode,
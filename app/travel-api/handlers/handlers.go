@@ -3,16 +3,29 @@
 package handlers
 
 import (
+	"context"
 	"expvar"
-	"log"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"time"
 
 	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/apikey"
+	"github.com/dgraph-io/travel/business/data/city"
+	"github.com/dgraph-io/travel/business/data/place"
+	"github.com/dgraph-io/travel/business/data/rating"
+	"github.com/dgraph-io/travel/business/data/token"
+	"github.com/dgraph-io/travel/business/data/user"
 	"github.com/dgraph-io/travel/business/feeds/loader"
+	"github.com/dgraph-io/travel/business/sys/auth"
+	"github.com/dgraph-io/travel/business/sys/mail"
 	"github.com/dgraph-io/travel/business/sys/metrics"
 	"github.com/dgraph-io/travel/business/web/mid"
+	"github.com/dgraph-io/travel/foundation/health"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/ratelimit"
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/dgraph-io/travel/foundation/web"
 )
 
@@ -38,32 +51,160 @@ func DebugStandardLibraryMux() *http.ServeMux {
 // debug application routes for the service. This bypassing the use of the
 // DefaultServerMux. Using the DefaultServerMux would be a security risk since
 // a dependency could inject a handler into our service without us knowing it.
-func DebugMux(build string, gqlConfig data.GraphQLConfig) http.Handler {
+func DebugMux(build string, log *logger.Logger, checks *health.Registry, metrics *metrics.Metrics) http.Handler {
 	mux := DebugStandardLibraryMux()
 
+	// Publish the metrics in the Prometheus text format next to the
+	// expvar output.
+	mux.Handle("/debug/metrics", metrics.Handler())
+
 	// Register the check endpoints.
 	cg := checkGroup{
-		build:     build,
-		gqlConfig: gqlConfig,
+		build:  build,
+		log:    log,
+		health: checks,
 	}
+	mux.HandleFunc("/debug/liveness", cg.liveness)
 	mux.HandleFunc("/debug/readiness", cg.readiness)
 
 	return mux
 }
 
+// AuthConfig contains the settings required to authenticate users and
+// issue tokens. The mail sender delivers password reset tokens. Without a
+// mail sender the password reset routes aren't registered.
+type AuthConfig struct {
+	Auth            *auth.Auth
+	Mail            mail.Sender
+	Lockout         user.Lockout
+	TokenLifetime   time.Duration
+	RefreshLifetime time.Duration
+	ResetLifetime   time.Duration
+}
+
+// RateLimitConfig contains the limits applied to each client on a route. The
+// feed upload triggers expensive work upstream and the token routes guard
+// credentials so they have their own limits. The IP limit applies to each IP
+// address across the authenticated routes before credentials are checked.
+type RateLimitConfig struct {
+	Store   *ratelimit.Store
+	Default ratelimit.Limit
+	Feed    ratelimit.Limit
+	Token   ratelimit.Limit
+	IP      ratelimit.Limit
+}
+
+// HeadersConfig contains the CORS and security headers written on the
+// responses.
+type HeadersConfig struct {
+	CORS   mid.CORSConfig
+	Secure mid.SecureHeadersConfig
+}
+
 // APIMux constructs an http.Handler with all application routes defined.
-func APIMux(build string, shutdown chan os.Signal, log *log.Logger, metrics *metrics.Metrics, gqlConfig data.GraphQLConfig, loaderConfig loader.Config) *web.App {
+func APIMux(build string, shutdown chan os.Signal, log *logger.Logger, tracer *tracer.Tracer, metrics *metrics.Metrics, authConfig AuthConfig, rateConfig RateLimitConfig, headersConfig HeadersConfig, gqlConfig data.GraphQLConfig, loaderConfig loader.Config) *web.App {
+
+	a := authConfig.Auth
+	gql := data.NewGraphQL(gqlConfig)
+
+	// Stores working with user data forward the token of the caller so the
+	// database applies its rules for that caller. The service token is used
+	// when no caller is authenticated, such as when logging in, and for
+	// changes the handlers authorize themselves.
+	callerConfig := gqlConfig
+	callerConfig.ForwardAuth = true
+	callerGQL := data.NewGraphQL(callerConfig)
 
 	// Construct the web.App which holds all routes as well as common Middleware.
-	app := web.NewApp(shutdown, mid.Logger(log), mid.Errors(log), mid.Metrics(metrics), mid.Panics(log))
+	app := web.NewApp(shutdown, tracer, mid.Logger(log), mid.Errors(log), mid.Metrics(metrics), mid.Panics(log), mid.SecureHeaders(headersConfig.Secure), mid.CORS(headersConfig.CORS))
+
+	// Send OPTIONS requests, such as CORS preflight requests, through the
+	// middleware.
+	app.HandleOptions(options)
+
+	// Let clients ask for responses in other formats than JSON, such as
+	// CSV exports.
+	app.RegisterEncoder("text/csv; charset=utf-8", web.CSV)
+	app.RegisterEncoder("application/msgpack", web.MessagePack)
+
+	// Authenticate requests using a token or an api key.
+	authen := mid.Authenticate(a, apiKeyAuthenticator(apikey.NewStore(log, gql)))
+
+	// Limit the requests of each client. The limits run after authentication
+	// so authenticated clients are limited by subject. Checking credentials
+	// costs database lookups so the IP address is limited before that. The
+	// routes taking credentials in the body are limited by IP address since
+	// nothing the client sends has been verified.
+	limit := mid.RateLimit(rateConfig.Store, rateConfig.Default)
+	limitFeed := mid.RateLimit(rateConfig.Store, rateConfig.Feed)
+	limitToken := mid.RateLimit(rateConfig.Store, rateConfig.Token)
+	limitIP := mid.RateLimitIP(rateConfig.Store, rateConfig.IP)
+
+	// Register the endpoint publishing our public keys.
+	jg := jwksGroup{
+		auth: a,
+	}
+	app.Handle(http.MethodGet, "/.well-known/jwks.json", jg.jwks, limit)
 
 	// Register the feed endpoints.
 	fg := feedGroup{
 		log:          log,
 		gqlConfig:    gqlConfig,
 		loaderConfig: loaderConfig,
+		app:          app,
+	}
+	app.Handle(http.MethodPost, "/v1/feed/upload", fg.upload, limitIP, authen, mid.RequireScopes(auth.ScopeFeedUpload), limitFeed)
+
+	// Register the user endpoints.
+	ug := userGroup{
+		user:            user.NewStore(log, callerGQL),
+		admin:           user.NewStore(log, gql),
+		tokens:          token.NewStore(log, gql),
+		auth:            a,
+		mail:            authConfig.Mail,
+		lockout:         authConfig.Lockout,
+		tokenLifetime:   authConfig.TokenLifetime,
+		refreshLifetime: authConfig.RefreshLifetime,
+		resetLifetime:   authConfig.ResetLifetime,
+	}
+	app.Handle(http.MethodGet, "/v1/users/token", ug.token, limitIP, limitToken)
+	app.Handle(http.MethodPost, "/v1/users/token/refresh", ug.refresh, limitIP, limitToken)
+	if authConfig.Mail != nil {
+		app.Handle(http.MethodPost, "/v1/users/password/forgot", ug.forgot, limitIP, limitToken)
+		app.Handle(http.MethodPost, "/v1/users/password/reset", ug.reset, limitIP, limitToken)
 	}
-	app.Handle(http.MethodPost, "/v1/feed/upload", fg.upload)
+	app.Handle(http.MethodPost, "/v1/users/logout", ug.logout, limitIP, mid.Authenticate(a, nil), limit)
+	app.Handle(http.MethodGet, "/v1/users", ug.query, limitIP, authen, mid.RequireScopes(auth.ScopeUserRead), limit)
+	app.Handle(http.MethodPost, "/v1/users", ug.create, limitIP, authen, mid.RequireScopes(auth.ScopeUserWrite), limit)
+	app.Handle(http.MethodGet, "/v1/users/:id", ug.queryByID, limitIP, authen, limit)
+	app.Handle(http.MethodPut, "/v1/users/:id", ug.update, limitIP, authen, limit)
+	app.Handle(http.MethodDelete, "/v1/users/:id", ug.delete, limitIP, authen, limit)
+
+	// Register the rating endpoints. Rating a place changes the record of the
+	// user which only admins can do in the database so the handlers authorize
+	// the caller and use the service token.
+	rg := ratingGroup{
+		rating: rating.NewStore(log, gql),
+		user:   user.NewStore(log, callerGQL),
+	}
+	app.Handle(http.MethodGet, "/v1/users/:id/ratings", rg.query, limitIP, authen, limit)
+	app.Handle(http.MethodPost, "/v1/users/:id/ratings", rg.add, limitIP, authen, limit)
+	app.Handle(http.MethodPut, "/v1/users/:id/ratings/:place_id", rg.update, limitIP, authen, limit)
+	app.Handle(http.MethodDelete, "/v1/users/:id/ratings/:place_id", rg.delete, limitIP, authen, limit)
+	app.Handle(http.MethodGet, "/v1/places/:place_id/rating", rg.average, limitIP, authen, limit)
+
+	// Register the place endpoints.
+	pg := placeGroup{
+		city:  city.NewStore(log, gql),
+		place: place.NewStore(log, gql),
+	}
+	app.Handle(http.MethodGet, "/v1/cities/:city_id/places", pg.queryByCity, limitIP, authen, limit)
 
 	return app
 }
+
+// options answers OPTIONS requests. CORS preflight requests are answered by
+// the CORS middleware before reaching it.
+func options(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
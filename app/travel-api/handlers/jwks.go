@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dgraph-io/travel/business/sys/auth"
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/pkg/errors"
+)
+
+type jwksGroup struct {
+	auth *auth.Auth
+}
+
+// jwks publishes the public keys for every key in the keystore so other
+// services can verify the tokens we sign, including after keys are rotated.
+func (jg jwksGroup) jwks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	jwks, err := jg.auth.JWKS()
+	if err != nil {
+		return errors.Wrap(err, "building jwks")
+	}
+
+	return web.Respond(ctx, w, jwks, http.StatusOK)
+}
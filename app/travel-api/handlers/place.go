@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dgraph-io/travel/business/data/city"
+	"github.com/dgraph-io/travel/business/data/place"
+	"github.com/dgraph-io/travel/business/sys/validate"
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/pkg/errors"
+)
+
+type placeGroup struct {
+	city  city.Store
+	place place.Store
+}
+
+// queryByCity returns the places of a city. Clients can ask for text/csv to
+// export them. A city that doesn't exist is reported as not found rather
+// than having no places.
+func (pg placeGroup) queryByCity(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	cityID := web.Param(r, "city_id")
+	if _, err := pg.city.QueryByID(ctx, v.TraceID, cityID); err != nil {
+		if errors.Cause(err) == city.ErrNotFound {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return errors.Wrapf(err, "querying city %s", cityID)
+	}
+
+	places, err := pg.place.QueryByCity(ctx, v.TraceID, cityID)
+	if err != nil {
+		return errors.Wrapf(err, "querying places for city %s", cityID)
+	}
+
+	return web.Respond(ctx, w, places, http.StatusOK)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dgraph-io/travel/business/data/rating"
+	"github.com/dgraph-io/travel/business/data/user"
+	"github.com/dgraph-io/travel/business/sys/auth"
+	"github.com/dgraph-io/travel/business/sys/validate"
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/pkg/errors"
+)
+
+type ratingGroup struct {
+	rating rating.Store
+	user   user.Store
+}
+
+func (rg ratingGroup) query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	usr, err := rg.queryUser(ctx, v.TraceID, web.Param(r, "id"), auth.ScopeUserRead)
+	if err != nil {
+		return err
+	}
+
+	ratings, err := rg.rating.QueryByUser(ctx, v.TraceID, usr.Email)
+	if err != nil {
+		return errors.Wrapf(err, "querying ratings for user %s", usr.ID)
+	}
+
+	return web.Respond(ctx, w, ratings, http.StatusOK)
+}
+
+func (rg ratingGroup) add(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nr rating.NewRating
+	if err := web.Decode(r, &nr); err != nil {
+		return errors.Wrap(err, "decoding request")
+	}
+
+	usr, err := rg.queryUser(ctx, v.TraceID, web.Param(r, "id"), auth.ScopeUserWrite)
+	if err != nil {
+		return err
+	}
+
+	if err := rg.rating.Add(ctx, v.TraceID, usr.Email, nr); err != nil {
+		if errors.Cause(err) == rating.ErrNotExists {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return errors.Wrapf(err, "adding rating for user %s", usr.ID)
+	}
+
+	return web.Respond(ctx, w, nr, http.StatusCreated)
+}
+
+func (rg ratingGroup) update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var ur rating.UpdateRating
+	if err := web.Decode(r, &ur); err != nil {
+		return errors.Wrap(err, "decoding request")
+	}
+
+	nr := rating.NewRating{
+		PlaceID: web.Param(r, "place_id"),
+		Stars:   ur.Stars,
+	}
+
+	usr, err := rg.queryUser(ctx, v.TraceID, web.Param(r, "id"), auth.ScopeUserWrite)
+	if err != nil {
+		return err
+	}
+
+	if err := rg.rating.Update(ctx, v.TraceID, usr.Email, nr); err != nil {
+		switch errors.Cause(err) {
+		case rating.ErrNotFound, rating.ErrNotExists:
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return errors.Wrapf(err, "updating rating for user %s", usr.ID)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+func (rg ratingGroup) delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	usr, err := rg.queryUser(ctx, v.TraceID, web.Param(r, "id"), auth.ScopeUserWrite)
+	if err != nil {
+		return err
+	}
+
+	placeID := web.Param(r, "place_id")
+	if err := rg.rating.Remove(ctx, v.TraceID, usr.Email, placeID); err != nil {
+		if errors.Cause(err) == rating.ErrNotFound {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return errors.Wrapf(err, "removing rating for user %s place %s", usr.ID, placeID)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+func (rg ratingGroup) average(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	placeID := web.Param(r, "place_id")
+	avg, err := rg.rating.QueryAverage(ctx, v.TraceID, placeID)
+	if err != nil {
+		if errors.Cause(err) == rating.ErrNotFound {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return errors.Wrapf(err, "querying average rating for place %s", placeID)
+	}
+
+	return web.Respond(ctx, w, avg, http.StatusOK)
+}
+
+// queryUser returns the user the ratings belong to. Only the user
+// themselves and callers granted the scope are allowed to access the ratings.
+func (rg ratingGroup) queryUser(ctx context.Context, traceID string, userID string, scope string) (user.User, error) {
+	if err := authorizeUser(ctx, userID, scope); err != nil {
+		return user.User{}, err
+	}
+
+	usr, err := rg.user.QueryByID(ctx, traceID, userID)
+	if err != nil {
+		if errors.Cause(err) == user.ErrNotFound {
+			return user.User{}, validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return user.User{}, errors.Wrapf(err, "querying user %s", userID)
+	}
+
+	return usr, nil
+}
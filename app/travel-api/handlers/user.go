@@ -0,0 +1,554 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data/token"
+	"github.com/dgraph-io/travel/business/data/user"
+	"github.com/dgraph-io/travel/business/sys/auth"
+	"github.com/dgraph-io/travel/business/sys/mail"
+	"github.com/dgraph-io/travel/business/sys/validate"
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/pkg/errors"
+)
+
+type userGroup struct {
+	user            user.Store
+	admin           user.Store
+	tokens          token.Store
+	auth            *auth.Auth
+	mail            mail.Sender
+	lockout         user.Lockout
+	tokenLifetime   time.Duration
+	refreshLifetime time.Duration
+	resetLifetime   time.Duration
+}
+
+// tokenPair is the set of tokens handed to a user once authenticated.
+type tokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// refreshRequest is the information provided to exchange or discard a
+// refresh token.
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// Validate checks the fields of the request against their tags.
+func (rr refreshRequest) Validate() error {
+	return validate.Check(rr)
+}
+
+// logoutRequest is the information provided to sign out. The refresh token
+// is optional so it isn't validated.
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// forgotRequest is the information provided to request a password reset.
+type forgotRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Validate checks the fields of the request against their tags.
+func (fr forgotRequest) Validate() error {
+	return validate.Check(fr)
+}
+
+// resetRequest is the information provided to reset a forgotten password.
+type resetRequest struct {
+	Token           string `json:"token" validate:"required"`
+	Password        string `json:"password" validate:"required,password"`
+	PasswordConfirm string `json:"password_confirm" validate:"eqfield=Password"`
+}
+
+// Validate checks the fields of the request against their tags.
+func (rr resetRequest) Validate() error {
+	return validate.Check(rr)
+}
+
+func (ug userGroup) token(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	email, pass, ok := r.BasicAuth()
+	if !ok {
+		err := errors.New("must provide email and password in Basic auth")
+		return validate.NewRequestError(err, http.StatusUnauthorized)
+	}
+
+	usr, err := ug.user.Authenticate(ctx, v.TraceID, email, pass, ug.lockout, v.Now)
+	if err != nil {
+		// A locked account gets the same response as a wrong password so
+		// the endpoint can't be used to find out which accounts exist or
+		// are under attack.
+		switch errors.Cause(err) {
+		case user.ErrAuthenticationFailure, user.ErrAccountLocked:
+			return validate.NewRequestError(user.ErrAuthenticationFailure, http.StatusUnauthorized)
+		default:
+			return errors.Wrap(err, "authenticating")
+		}
+	}
+
+	tkns, err := ug.issue(ctx, v, usr, v.Now)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, tkns, http.StatusOK)
+}
+
+func (ug userGroup) refresh(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var rr refreshRequest
+	if err := web.Decode(r, &rr); err != nil {
+		return errors.Wrap(err, "decoding request")
+	}
+
+	refreshToken, ref, err := ug.tokens.Rotate(ctx, v.TraceID, rr.RefreshToken, ug.refreshLifetime, v.Now)
+	if err != nil {
+		switch errors.Cause(err) {
+		case token.ErrNotFound, token.ErrExpired:
+			return validate.NewRequestError(err, http.StatusUnauthorized)
+		default:
+			return errors.Wrap(err, "rotating refresh token")
+		}
+	}
+
+	usr, err := ug.user.QueryByID(ctx, v.TraceID, ref.UserID)
+	if err != nil {
+		if errors.Cause(err) == user.ErrNotFound {
+			return validate.NewRequestError(err, http.StatusUnauthorized)
+		}
+		return errors.Wrapf(err, "querying user: %s", ref.UserID)
+	}
+
+	tkns := tokenPair{
+		RefreshToken: refreshToken,
+	}
+	tkns.Token, err = ug.sign(ug.claims(usr, v.Now))
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, tkns, http.StatusOK)
+}
+
+func (ug userGroup) logout(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The refresh token is optional since the client may have lost it.
+	if r.ContentLength != 0 {
+		var lr logoutRequest
+		if err := web.Decode(r, &lr); err != nil {
+			return errors.Wrap(err, "decoding request")
+		}
+
+		if lr.RefreshToken != "" {
+			// A token that is already gone can't be used either.
+			if err := ug.tokens.DeleteRefresh(ctx, v.TraceID, lr.RefreshToken); err != nil && errors.Cause(err) != token.ErrNotFound {
+				return errors.Wrap(err, "deleting refresh token")
+			}
+		}
+	}
+
+	if err := ug.auth.Revoke(ctx, v.TraceID, claims); err != nil {
+		return errors.Wrapf(err, "revoking token: %s", claims.ID)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+func (ug userGroup) forgot(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var fr forgotRequest
+	if err := web.Decode(r, &fr); err != nil {
+		return errors.Wrap(err, "decoding request")
+	}
+
+	// Respond the same way when the user doesn't exist so the endpoint
+	// can't be used to find out who has an account.
+	usr, err := ug.user.QueryByEmail(ctx, v.TraceID, fr.Email)
+	if err != nil {
+		if errors.Cause(err) == user.ErrNotFound {
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+		return errors.Wrapf(err, "querying user: %s", fr.Email)
+	}
+
+	tkn, err := ug.tokens.AddReset(ctx, v.TraceID, usr.ID, ug.resetLifetime, v.Now)
+	if err != nil {
+		return errors.Wrap(err, "generating reset token")
+	}
+
+	msg := mail.Message{
+		To:      usr.Email,
+		Subject: "Reset your travel password",
+		Body:    fmt.Sprintf("Use this token to reset your password before %s:\n\n%s", v.Now.Add(ug.resetLifetime).Format(time.RFC1123), tkn),
+	}
+	if err := ug.mail.Send(ctx, msg); err != nil {
+		return errors.Wrapf(err, "sending reset mail: %s", usr.ID)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+func (ug userGroup) reset(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var rr resetRequest
+	if err := web.Decode(r, &rr); err != nil {
+		return errors.Wrap(err, "decoding request")
+	}
+
+	rst, err := ug.tokens.ConsumeReset(ctx, v.TraceID, rr.Token, v.Now)
+	if err != nil {
+		switch errors.Cause(err) {
+		case token.ErrNotFound, token.ErrExpired:
+			return validate.NewRequestError(err, http.StatusUnauthorized)
+		default:
+			return errors.Wrap(err, "consuming reset token")
+		}
+	}
+
+	uu := user.UpdateUser{
+		Password: &rr.Password,
+	}
+	if _, err := ug.admin.Update(ctx, v.TraceID, rst.UserID, uu, v.Now); err != nil {
+		if errors.Cause(err) == user.ErrNotExists {
+			return validate.NewRequestError(err, http.StatusUnauthorized)
+		}
+		return errors.Wrapf(err, "resetting password: %s", rst.UserID)
+	}
+
+	// Sign the user out everywhere in case someone else knew the password.
+	// The access tokens are revoked in the revocation list the service is
+	// configured with.
+	if err := ug.tokens.DeleteUserRefresh(ctx, v.TraceID, rst.UserID); err != nil {
+		return errors.Wrapf(err, "deleting refresh tokens: %s", rst.UserID)
+	}
+	if err := ug.auth.RevokeSubject(ctx, v.TraceID, rst.UserID, v.Now, v.Now.Add(ug.tokenLifetime)); err != nil {
+		return errors.Wrapf(err, "revoking tokens: %s", rst.UserID)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+func (ug userGroup) create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nu user.NewUser
+	if err := web.Decode(r, &nu); err != nil {
+		return errors.Wrap(err, "decoding request")
+	}
+
+	usr, err := ug.user.Add(ctx, v.TraceID, nu, v.Now)
+	if err != nil {
+		if errors.Cause(err) == user.ErrExists {
+			return validate.NewRequestError(err, http.StatusConflict)
+		}
+		return errors.Wrapf(err, "adding user: %s", nu.Email)
+	}
+
+	usr.PasswordHash = ""
+	return web.Respond(ctx, w, usr, http.StatusCreated)
+}
+
+func (ug userGroup) query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	pageNumber, rowsPerPage, err := page(r)
+	if err != nil {
+		return err
+	}
+
+	users, err := ug.user.QueryAll(ctx, v.TraceID, pageNumber, rowsPerPage)
+	if err != nil {
+		return errors.Wrap(err, "querying users")
+	}
+
+	for i := range users {
+		users[i].PasswordHash = ""
+	}
+	return web.Respond(ctx, w, users, http.StatusOK)
+}
+
+func (ug userGroup) queryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	userID := web.Param(r, "id")
+	if err := authorizeUser(ctx, userID, auth.ScopeUserRead); err != nil {
+		return err
+	}
+
+	usr, err := ug.user.QueryByID(ctx, v.TraceID, userID)
+	if err != nil {
+		if errors.Cause(err) == user.ErrNotFound {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return errors.Wrapf(err, "querying user: %s", userID)
+	}
+
+	usr.PasswordHash = ""
+	return web.Respond(ctx, w, usr, http.StatusOK)
+}
+
+func (ug userGroup) update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	userID := web.Param(r, "id")
+	if err := authorizeUser(ctx, userID, auth.ScopeUserWrite); err != nil {
+		return err
+	}
+
+	var uu user.UpdateUser
+	if err := web.Decode(r, &uu); err != nil {
+		return errors.Wrap(err, "decoding request")
+	}
+
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Only callers allowed to manage users can change the role of a user.
+	if uu.Role != nil && !claims.HasScope(auth.ScopeUserWrite) {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	// Users changing the password or email they log in with must prove they
+	// know the current password, so a stolen token can't take the account.
+	self := claims.Subject == userID
+	credentials := uu.Password != nil || uu.Email != nil
+	if self && credentials {
+		if err := ug.checkPassword(ctx, v, userID, uu.CurrentPassword); err != nil {
+			return err
+		}
+	}
+
+	// The database only lets admins change users so the change is made with
+	// the service token once the caller is authorized.
+	usr, err := ug.admin.Update(ctx, v.TraceID, userID, uu, v.Now)
+	if err != nil {
+		switch errors.Cause(err) {
+		case user.ErrNotExists:
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case user.ErrExists:
+			return validate.NewRequestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "updating user: %s", userID)
+		}
+	}
+
+	// Sign the user out everywhere once the password changes. The new
+	// tokens are issued after the revocation so they stay valid.
+	issuedAt := v.Now
+	if uu.Password != nil {
+		if err := ug.tokens.DeleteUserRefresh(ctx, v.TraceID, userID); err != nil {
+			return errors.Wrapf(err, "deleting refresh tokens: %s", userID)
+		}
+		if err := ug.auth.RevokeSubject(ctx, v.TraceID, userID, v.Now, v.Now.Add(ug.tokenLifetime)); err != nil {
+			return errors.Wrapf(err, "revoking tokens: %s", userID)
+		}
+		issuedAt = auth.IssuedAfter(v.Now)
+	}
+
+	// Users changing their own credentials get new tokens so they stay
+	// signed in, with their new email when it changed.
+	if self && credentials {
+		tkns, err := ug.issue(ctx, v, usr, issuedAt)
+		if err != nil {
+			return err
+		}
+		return web.Respond(ctx, w, tkns, http.StatusOK)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// checkPassword verifies the current password provided by users changing
+// their own credentials. A wrong password counts as a failed login.
+func (ug userGroup) checkPassword(ctx context.Context, v *web.Values, userID string, password *string) error {
+	if password == nil || *password == "" {
+		return validate.FieldErrors{{Field: "current_password", Error: "current_password is required to change the password or email"}}
+	}
+
+	usr, err := ug.admin.QueryByID(ctx, v.TraceID, userID)
+	if err != nil {
+		if errors.Cause(err) == user.ErrNotFound {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return errors.Wrapf(err, "querying user: %s", userID)
+	}
+
+	if _, err := ug.admin.Authenticate(ctx, v.TraceID, usr.Email, *password, ug.lockout, v.Now); err != nil {
+		switch errors.Cause(err) {
+		case user.ErrAuthenticationFailure, user.ErrAccountLocked:
+			return validate.NewRequestError(errors.New("current password is incorrect"), http.StatusForbidden)
+		default:
+			return errors.Wrap(err, "checking current password")
+		}
+	}
+
+	return nil
+}
+
+func (ug userGroup) delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	userID := web.Param(r, "id")
+	if err := authorizeUser(ctx, userID, auth.ScopeUserWrite); err != nil {
+		return err
+	}
+
+	// The database only lets admins delete users so the user is deleted with
+	// the service token once the caller is authorized.
+	if err := ug.admin.Delete(ctx, v.TraceID, userID); err != nil {
+		if errors.Cause(err) == user.ErrNotExists {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return errors.Wrapf(err, "deleting user: %s", userID)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// issue generates a new access token issued at the specified time and a
+// refresh token for the user.
+func (ug userGroup) issue(ctx context.Context, v *web.Values, usr user.User, issuedAt time.Time) (tokenPair, error) {
+	tkn, err := ug.sign(ug.claims(usr, issuedAt))
+	if err != nil {
+		return tokenPair{}, err
+	}
+
+	refreshToken, err := ug.tokens.AddRefresh(ctx, v.TraceID, usr.ID, ug.refreshLifetime, v.Now)
+	if err != nil {
+		return tokenPair{}, errors.Wrap(err, "generating refresh token")
+	}
+
+	return tokenPair{Token: tkn, RefreshToken: refreshToken}, nil
+}
+
+// sign generates a token for the claims signed with the active key.
+func (ug userGroup) sign(claims auth.Claims) (string, error) {
+	kid, err := ug.auth.ActiveKID()
+	if err != nil {
+		return "", errors.Wrap(err, "looking up active key")
+	}
+
+	tkn, err := ug.auth.GenerateToken(kid, claims)
+	if err != nil {
+		return "", errors.Wrap(err, "generating token")
+	}
+
+	return tkn, nil
+}
+
+// claims constructs the claims for an access token issued to the user at
+// the specified time.
+func (ug userGroup) claims(usr user.User, issuedAt time.Time) auth.Claims {
+	return auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "travel project",
+			Subject:   usr.ID,
+			ExpiresAt: jwt.At(issuedAt.Add(ug.tokenLifetime)),
+			IssuedAt:  jwt.At(issuedAt),
+		},
+		Auth: auth.StandardClaims{
+			Role:   usr.Role,
+			Scopes: auth.RoleScopes(usr.Role),
+			Email:  usr.Email,
+		},
+	}
+}
+
+// claimsFromContext returns the claims of the authenticated caller.
+func claimsFromContext(ctx context.Context) (auth.Claims, error) {
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return auth.Claims{}, validate.NewRequestError(errors.New("claims missing from context"), http.StatusUnauthorized)
+	}
+	return claims, nil
+}
+
+// authorizeUser checks the caller is the user being accessed or has been
+// granted the scope to access other users.
+func authorizeUser(ctx context.Context, userID string, scope string) error {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if claims.Subject != userID && !claims.HasScope(scope) {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	return nil
+}
+
+// page returns the page number and rows per page requested in the query
+// string. The first page of 20 rows is used by default.
+func page(r *http.Request) (int, int, error) {
+	pageNumber, rowsPerPage := 1, 20
+
+	if v := r.URL.Query().Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, validate.NewRequestError(errors.Errorf("invalid page format: %s", v), http.StatusBadRequest)
+		}
+		pageNumber = n
+	}
+
+	if v := r.URL.Query().Get("rows"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, validate.NewRequestError(errors.Errorf("invalid rows format: %s", v), http.StatusBadRequest)
+		}
+		rowsPerPage = n
+	}
+
+	return pageNumber, rowsPerPage, nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"expvar"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,8 +13,19 @@ import (
 	"github.com/ardanlabs/conf"
 	"github.com/dgraph-io/travel/app/travel-api/handlers"
 	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/token"
+	"github.com/dgraph-io/travel/business/data/user"
 	"github.com/dgraph-io/travel/business/feeds/loader"
+	"github.com/dgraph-io/travel/business/feeds/notify"
+	"github.com/dgraph-io/travel/business/sys/auth"
+	"github.com/dgraph-io/travel/business/sys/mail"
 	"github.com/dgraph-io/travel/business/sys/metrics"
+	"github.com/dgraph-io/travel/business/web/mid"
+	"github.com/dgraph-io/travel/foundation/health"
+	"github.com/dgraph-io/travel/foundation/keystore"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/ratelimit"
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/pkg/errors"
 )
 
@@ -23,15 +33,13 @@ import (
 var build = "develop"
 
 func main() {
-	log := log.New(os.Stdout, "TRAVEL : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
-
-	if err := run(log); err != nil {
-		log.Println("error:", err)
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
 	}
 }
 
-func run(log *log.Logger) error {
+func run() error {
 
 	// =========================================================================
 	// Configuration
@@ -45,6 +53,7 @@ func run(log *log.Logger) error {
 			WriteTimeout    time.Duration `conf:"default:10s"`
 			IdleTimeout     time.Duration `conf:"default:120s"`
 			ShutdownTimeout time.Duration `conf:"default:20s"`
+			MaxBodyBytes    int64         `conf:"default:1048576"`
 		}
 		Search struct {
 			Categories []string `conf:"default:restaurant;bar;supermarket"`
@@ -61,12 +70,73 @@ func run(log *log.Logger) error {
 			Advisory string `conf:"default:https://www.travel-advisory.info/api"`
 			Weather  string `conf:"default:http://api.openweathermap.org/data/2.5/weather"`
 		}
+		Retention struct {
+			Weather  time.Duration `conf:"default:720h"`
+			Advisory time.Duration `conf:"default:2160h"`
+		}
+		Notify struct {
+			AdvisoryThreshold float64 `conf:"default:1.0"`
+			Log               bool    `conf:"default:true"`
+			WebhookURL        string
+			File              string
+		}
+		Auth struct {
+			KeysFolder      string        `conf:"default:zarf/keys/"`
+			KeysReload      time.Duration `conf:"default:1m,help:how often the keys folder is checked for rotated keys"`
+			Algorithm       string        `conf:"default:RS256,help:algorithm used with RSA keys; other keys use the algorithm of their type"`
+			TokenLifetime   time.Duration `conf:"default:15m"`
+			RefreshLifetime time.Duration `conf:"default:720h"`
+			Revocations     string        `conf:"default:dgraph,help:where revoked tokens are tracked: dgraph or memory"`
+			RevocationCache time.Duration `conf:"default:5s,help:how long revocation lookups in dgraph are reused; tokens revoked by other instances are rejected after it"`
+			ResetLifetime   time.Duration `conf:"default:1h"`
+			LockoutAttempts int           `conf:"default:5,help:failed logins in a row before an account is locked or 0 to disable"`
+			LockoutDuration time.Duration `conf:"default:15m"`
+		}
+		RateLimit struct {
+			Rate          float64       `conf:"default:10,help:requests per second allowed for each client on a route or 0 to disable"`
+			Burst         int           `conf:"default:20"`
+			FeedInterval  time.Duration `conf:"default:1m,help:time between feed uploads allowed for each client"`
+			FeedBurst     int           `conf:"default:2"`
+			TokenInterval time.Duration `conf:"default:6s,help:time between token and password requests allowed for each client"`
+			TokenBurst    int           `conf:"default:5"`
+			IPRate        float64       `conf:"default:50,help:requests per second allowed for each IP address on the authenticated routes before credentials are checked or 0 to disable"`
+			IPBurst       int           `conf:"default:100"`
+			EvictInterval time.Duration `conf:"default:1m"`
+			EvictIdle     time.Duration `conf:"default:10m,help:idle time after which a client is forgotten; longer than any bucket takes to refill"`
+		}
+		Headers struct {
+			AllowedOrigins []string      `conf:"default:*,help:origins allowed to make cross-origin requests"`
+			AllowedMethods []string      `conf:"default:GET;POST;PUT;DELETE"`
+			AllowedHeaders []string      `conf:"default:Authorization;Content-Type;X-API-Key;traceparent"`
+			CORSMaxAge     time.Duration `conf:"default:10m,help:how long browsers cache the preflight response"`
+			HSTSMaxAge     time.Duration `conf:"default:0s,help:max age of the HSTS header; only set it when served over TLS"`
+			CSP            string        `conf:"default:default-src 'none'; frame-ancestors 'none'"`
+		}
+		Mail struct {
+			Sender string `conf:"default:none,help:where password reset mails go: none disables password resets and file writes the reset tokens to the folder for local development only"`
+			Folder string `conf:"default:zarf/mail/,help:folder the password reset mails are written to by the file sender"`
+		}
+		Trace struct {
+			Exporter     string        `conf:"default:none,help:where spans are exported: none stdout or otlp"`
+			OTLPURL      string        `conf:"default:http://0.0.0.0:4318/v1/traces"`
+			FlushTimeout time.Duration `conf:"default:5s,help:how long the remaining spans are exported for on shutdown"`
+		}
+		Health struct {
+			CacheTTL  time.Duration `conf:"default:5s,help:how long the result of the health checks is reused"`
+			PingFeeds bool          `conf:"default:false,help:send requests to the feed providers when checking their health"`
+		}
+		Log struct {
+			Level   string `conf:"default:info,help:lowest level logged: debug info warn or error"`
+			Format  string `conf:"default:json,help:format of the log entries: json or console"`
+			Queries string `conf:"default:full,help:how much of each database query is logged: off summary or full"`
+		}
 		Dgraph struct {
-			URL             string `conf:"default:http://0.0.0.0:8080"`
-			AuthHeaderName  string `conf:"default:X-Travel-Auth"`
-			AuthToken       string
-			CloudHeaderName string `conf:"default:X-Auth-Token"`
-			CloudToken      string
+			URL             string        `conf:"default:http://0.0.0.0:8080"`
+			AuthHeaderName  string        `conf:"default:X-Travel-Auth"`
+			AuthToken       string        `conf:"mask,help:ADMIN token used to access the database; one is signed with the active key when empty"`
+			TokenLifetime   time.Duration `conf:"default:1h,help:lifetime of the signed ADMIN tokens; they are replaced halfway through"`
+			CloudHeaderName string        `conf:"default:X-Auth-Token"`
+			CloudToken      string        `conf:"mask"`
 		}
 	}{
 		Version: conf.Version{
@@ -96,23 +166,64 @@ func run(log *log.Logger) error {
 		return errors.Wrap(err, "parsing config")
 	}
 
+	// =========================================================================
+	// Initialize Logging Support
+
+	level, err := logger.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return errors.Wrap(err, "parsing log level")
+	}
+	log, err := logger.New(os.Stdout, logger.Config{Service: "travel-api", Level: level, Format: cfg.Log.Format})
+	if err != nil {
+		return errors.Wrap(err, "constructing logger")
+	}
+
+	// The stores redact sensitive values from the queries they log.
+	queryLog, err := data.ParseQueryLog(cfg.Log.Queries)
+	if err != nil {
+		return errors.Wrap(err, "parsing query log mode")
+	}
+	data.SetQueryLog(queryLog)
+
 	// =========================================================================
 	// App Starting
 
 	// Print the build version for our logs. Also expose it under /debug/vars.
 	expvar.NewString("build").Set(build)
-	log.Printf("main : Started : Application initializing : version %q", build)
-	defer log.Println("main: Completed")
+	log.Info("starting service", "version", build)
+	defer log.Info("shutdown complete")
 
 	out, err := conf.String(&cfg)
 	if err != nil {
 		return errors.Wrap(err, "generating config for output")
 	}
-	log.Printf("main: Config:\n%v\n", out)
+	log.Info("startup", "config", out)
+
+	// =========================================================================
+	// Initialize authentication support
+
+	log.Info("startup", "status", "initializing authentication support")
+
+	// Construct a key store based on the key files stored in
+	// the specified directory.
+	ks, err := keystore.NewFS(os.DirFS(cfg.Auth.KeysFolder))
+	if err != nil {
+		return errors.Wrap(err, "reading keys")
+	}
+
+	// Reload the keys when they are rotated so new tokens are signed with
+	// the active key without restarting the service.
+	keysCtx, keysCancel := context.WithCancel(context.Background())
+	defer keysCancel()
+	go ks.Watch(keysCtx, cfg.Auth.KeysReload, func(err error) {
+		log.Error("reloading keys", "error", err)
+	})
 
 	// =========================================================================
 	// Initialize GraphQL Support
 
+	log.Info("startup", "status", "initializing graphql support")
+
 	// Capture the configuration for Dgraph.
 	gqlConfig := data.GraphQLConfig{
 		URL:             cfg.Dgraph.URL,
@@ -122,31 +233,200 @@ func run(log *log.Logger) error {
 		CloudToken:      cfg.Dgraph.CloudToken,
 	}
 
+	// Logging in, the token stores and the feeds need ADMIN access to the
+	// database. The service can't work without it so a configured token
+	// must grant it, otherwise the service signs its own tokens. The signer
+	// doesn't check revocations since the revocation list needs the token.
+	signer, err := auth.New(cfg.Auth.Algorithm, ks, nil)
+	if err != nil {
+		return errors.Wrap(err, "constructing token signer")
+	}
+	if cfg.Dgraph.AuthToken != "" {
+		if err := signer.CheckServiceToken(cfg.Dgraph.AuthToken); err != nil {
+			return errors.Wrap(err, "checking dgraph auth token")
+		}
+	} else {
+		serviceToken := signer.NewServiceToken("travel-api", cfg.Dgraph.TokenLifetime)
+		if _, err := serviceToken.Token(); err != nil {
+			return errors.Wrap(err, "signing dgraph auth token")
+		}
+		gqlConfig.AuthTokenSource = serviceToken.Token
+	}
+
+	// =========================================================================
+	// Initialize authentication support
+
+	// Construct the list used to track revoked tokens. The in-memory list
+	// is only suitable when a single instance of the service is running.
+	// Lookups in the database are cached since every request is checked.
+	var revocations auth.RevocationList
+	switch cfg.Auth.Revocations {
+	case "dgraph":
+		revocations = auth.NewCachedRevocations(token.NewStore(log, data.NewGraphQL(gqlConfig)), cfg.Auth.RevocationCache)
+	case "memory":
+		revocations = auth.NewRevocations()
+	default:
+		return errors.Errorf("unknown revocation list %q", cfg.Auth.Revocations)
+	}
+
+	authn, err := auth.New(cfg.Auth.Algorithm, ks, revocations)
+	if err != nil {
+		return errors.Wrap(err, "constructing auth")
+	}
+
+	// The file sender writes the reset tokens to disk in plain text so it
+	// must only be used for local development.
+	var sender mail.Sender
+	switch cfg.Mail.Sender {
+	case "none":
+		log.Info("startup", "status", "password resets disabled: no mail sender configured")
+	case "file":
+		log.Warn("startup", "status", "password reset mails written to disk: only use this for local development", "folder", cfg.Mail.Folder)
+		sender = mail.NewFile(cfg.Mail.Folder)
+	default:
+		return errors.Errorf("unknown mail sender %q", cfg.Mail.Sender)
+	}
+
+	authConfig := handlers.AuthConfig{
+		Auth: authn,
+		Mail: sender,
+		Lockout: user.Lockout{
+			Attempts: cfg.Auth.LockoutAttempts,
+			Duration: cfg.Auth.LockoutDuration,
+		},
+		TokenLifetime:   cfg.Auth.TokenLifetime,
+		RefreshLifetime: cfg.Auth.RefreshLifetime,
+		ResetLifetime:   cfg.Auth.ResetLifetime,
+	}
+
+	// =========================================================================
+	// Start Rate Limiting Support
+
+	log.Info("startup", "status", "initializing rate limiting support")
+
+	// The buckets of clients that stopped making requests are evicted so
+	// the store doesn't grow forever.
+	limits := ratelimit.NewStore()
+	limitsCtx, limitsCancel := context.WithCancel(context.Background())
+	defer limitsCancel()
+	go limits.Run(limitsCtx, cfg.RateLimit.EvictInterval, cfg.RateLimit.EvictIdle)
+
+	rateConfig := handlers.RateLimitConfig{
+		Store:   limits,
+		Default: ratelimit.Limit{Rate: cfg.RateLimit.Rate, Burst: cfg.RateLimit.Burst},
+		Feed:    ratelimit.Every(cfg.RateLimit.FeedInterval, cfg.RateLimit.FeedBurst),
+		Token:   ratelimit.Every(cfg.RateLimit.TokenInterval, cfg.RateLimit.TokenBurst),
+		IP:      ratelimit.Limit{Rate: cfg.RateLimit.IPRate, Burst: cfg.RateLimit.IPBurst},
+	}
+
+	// =========================================================================
+	// Start Headers Support
+
+	// Browsers are allowed to call the API from the configured origins. The
+	// responses are data only so the policy forbids loading anything.
+	headersConfig := handlers.HeadersConfig{
+		CORS: mid.CORSConfig{
+			AllowedOrigins: cfg.Headers.AllowedOrigins,
+			AllowedMethods: cfg.Headers.AllowedMethods,
+			AllowedHeaders: cfg.Headers.AllowedHeaders,
+			ExposedHeaders: []string{"Retry-After"},
+			MaxAge:         cfg.Headers.CORSMaxAge,
+		},
+		Secure: mid.SecureHeadersConfig{
+			HSTSMaxAge:            cfg.Headers.HSTSMaxAge,
+			ContentSecurityPolicy: cfg.Headers.CSP,
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "no-referrer",
+		},
+	}
+	if err := headersConfig.CORS.Validate(); err != nil {
+		return errors.Wrap(err, "validating cors config")
+	}
+
+	// =========================================================================
+	// Start Tracing Support
+
+	log.Info("startup", "status", "initializing tracing support")
+
+	// Requests are traced even without an exporter so the trace ids of the
+	// clients are used in the logs.
+	var tr *tracer.Tracer
+	var exporter tracer.Exporter
+	switch cfg.Trace.Exporter {
+	case "none":
+	case "stdout":
+		exporter = tracer.NewWriter(os.Stdout)
+	case "otlp":
+		exporter = tracer.NewOTLP(cfg.Trace.OTLPURL)
+	default:
+		return errors.Errorf("unknown trace exporter %q", cfg.Trace.Exporter)
+	}
+	if exporter != nil {
+		tr = tracer.New(tracer.Config{
+			Service:  "travel-api",
+			Exporter: exporter,
+			OnError: func(err error) {
+				log.Error("exporting spans", "error", err)
+			},
+		})
+	}
+
 	// =========================================================================
 	// Start Debug Service
 
 	// Not concerned with shutting this down when the application is shutdown.
 
-	log.Println("main: Initializing debugging support")
+	log.Info("startup", "status", "initializing debugging support")
 
 	// The Debug function returns a mux to listen and serve on for all the debug
 	// related endpoints. This include the standard library endpoints.
 
-	debugMux := handlers.DebugMux(build, gqlConfig)
+	// The same metrics are updated by the requests and published here.
+	metrics := metrics.New()
+
+	// The service is ready when the database can be reached and a key is
+	// available to sign tokens. The feed providers are added below.
+	checks := health.NewRegistry(cfg.Health.CacheTTL)
+	checks.Register(data.HealthCheck(gqlConfig.URL), health.Check{
+		Name:     "keystore",
+		Critical: true,
+		Checker: func(ctx context.Context) error {
+			kid, err := authn.ActiveKID()
+			if err != nil {
+				return err
+			}
+			_, err = ks.PrivateKey(kid)
+			return err
+		},
+	})
+
+	debugMux := handlers.DebugMux(build, log, checks, metrics)
 
 	// Start the service listening for debug requests.
 	// Not concerned with shutting this down with load shedding.
 	go func() {
-		log.Printf("main: Debug Listening %s", cfg.Web.DebugHost)
+		log.Info("startup", "status", "debug listening", "host", cfg.Web.DebugHost)
 		if err := http.ListenAndServe(cfg.Web.DebugHost, debugMux); err != nil {
-			log.Printf("main: Debug Listener closed : %v", err)
+			log.Error("debug listener closed", "error", err)
 		}
 	}()
 
 	// =========================================================================
 	// Start API Service
 
-	log.Println("main : Started : Initializing API support")
+	log.Info("startup", "status", "initializing API support")
+
+	// Construct the notifier for the changes detected while loading feeds.
+	notifier := notify.New()
+	if cfg.Notify.Log {
+		notifier.Register(notify.NewLog(log))
+	}
+	if cfg.Notify.WebhookURL != "" {
+		notifier.Register(notify.NewWebhook(cfg.Notify.WebhookURL))
+	}
+	if cfg.Notify.File != "" {
+		notifier.Register(notify.NewFile(cfg.Notify.File))
+	}
 
 	loaderConfig := loader.Config{
 		Filter: loader.Filter{
@@ -161,14 +441,26 @@ func run(log *log.Logger) error {
 			Advisory: cfg.URL.Advisory,
 			Weather:  cfg.URL.Weather,
 		},
+		Retention: loader.Retention{
+			Weather:  cfg.Retention.Weather,
+			Advisory: cfg.Retention.Advisory,
+		},
+		Alert: loader.Alert{
+			AdvisoryThreshold: cfg.Notify.AdvisoryThreshold,
+			Notifier:          notifier,
+		},
 	}
 
+	// Report the health of the feed providers used by feed uploads.
+	checks.Register(loader.HealthChecks(loaderConfig, cfg.Health.PingFeeds)...)
+
 	// Make a channel to listen for an interrupt or terminate signal from the OS.
 	// Use a buffered channel because the signal package requires it.
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
-	apiMux := handlers.APIMux(build, shutdown, log, metrics.New(), gqlConfig, loaderConfig)
+	apiMux := handlers.APIMux(build, shutdown, log, tr, metrics, authConfig, rateConfig, headersConfig, gqlConfig, loaderConfig)
+	apiMux.SetMaxBodyBytes(cfg.Web.MaxBodyBytes)
 
 	api := http.Server{
 		Addr:         cfg.Web.APIHost,
@@ -176,7 +468,7 @@ func run(log *log.Logger) error {
 		ReadTimeout:  cfg.Web.ReadTimeout,
 		WriteTimeout: cfg.Web.WriteTimeout,
 		IdleTimeout:  cfg.Web.IdleTimeout,
-		ErrorLog:     log,
+		ErrorLog:     log.StdLogger(logger.LevelError),
 	}
 
 	// Make a channel to listen for errors coming from the listener. Use a
@@ -185,7 +477,7 @@ func run(log *log.Logger) error {
 
 	// Start the service listening for requests.
 	go func() {
-		log.Printf("main: API listening on %s", api.Addr)
+		log.Info("startup", "status", "api listening", "host", api.Addr)
 		serverErrors <- api.ListenAndServe()
 	}()
 
@@ -198,16 +490,41 @@ func run(log *log.Logger) error {
 		return errors.Wrap(err, "server error")
 
 	case sig := <-shutdown:
-		log.Printf("main: %v : Start shutdown", sig)
+		log.Info("shutdown", "status", "shutdown started", "signal", sig.String())
+
+		// Refuse new work right away so the requests still running while
+		// the server shuts down can't start new tasks.
+		apiMux.Drain()
 
 		// Give outstanding requests a deadline for completion.
 		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
 		defer cancel()
 
 		// Asking listener to shutdown and shed load.
-		if err := api.Shutdown(ctx); err != nil {
+		serverErr := api.Shutdown(ctx)
+		if serverErr != nil {
 			api.Close()
-			return errors.Wrap(err, "could not stop server gracefully")
+		}
+
+		// Wait for the work started by requests, such as feed uploads, with
+		// what is left of the deadline. Work still running after that is
+		// cancelled.
+		for _, task := range apiMux.Shutdown(ctx) {
+			log.Warn("shutdown", "status", "task abandoned", "task", task.Name, "traceid", task.TraceID, "since", time.Since(task.Started))
+		}
+
+		if serverErr != nil {
+			return errors.Wrap(serverErr, "could not stop server gracefully")
+		}
+
+		// Export the spans of the requests and tasks that just completed.
+		// The flush has its own deadline since the shutdown deadline may
+		// already have been used up by abandoned tasks.
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), cfg.Trace.FlushTimeout)
+		defer flushCancel()
+
+		if err := tr.Shutdown(flushCtx); err != nil {
+			return errors.Wrap(err, "could not export remaining spans")
 		}
 	}
 
@@ -7,6 +7,8 @@ $.ajaxSetup({
     beforeSend: function (xhr) {
         if (AuthHeaderName != "") {
             xhr.setRequestHeader(AuthHeaderName, AuthToken);
+            // Dgraph forwards this header to the travel-api feed upload.
+            xhr.setRequestHeader("Authorization", "Bearer " + AuthToken);
         }
     }
 });
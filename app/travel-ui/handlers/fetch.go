@@ -4,20 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"io"
-	"log"
 	"net/http"
 
 	"github.com/AvraamMavridis/randomcolor"
 	"github.com/dgraph-io/travel/business/data"
 	"github.com/dgraph-io/travel/business/data/city"
 	"github.com/dgraph-io/travel/business/data/place"
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/dgraph-io/travel/foundation/web"
 	"github.com/dimfeld/httptreemux/v5"
 	"github.com/pkg/errors"
 )
 
 type fetchGroup struct {
-	log       *log.Logger
+	log       *logger.Logger
 	gqlConfig data.GraphQLConfig
 }
 
@@ -5,14 +5,16 @@ package handlers
 import (
 	"context"
 	"expvar"
-	"log"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"strings"
 
 	"github.com/dgraph-io/travel/business/data"
 	"github.com/dgraph-io/travel/business/sys/metrics"
 	"github.com/dgraph-io/travel/business/web/mid"
+	"github.com/dgraph-io/travel/foundation/health"
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/dgraph-io/travel/foundation/web"
 	"github.com/pkg/errors"
 )
@@ -39,22 +41,57 @@ func DebugStandardLibraryMux() *http.ServeMux {
 // debug application routes for the service. This bypassing the use of the
 // DefaultServerMux. Using the DefaultServerMux would be a security risk since
 // a dependency could inject a handler into our service without us knowing it.
-func DebugMux(build string, gqlConfig data.GraphQLConfig) http.Handler {
+func DebugMux(build string, log *logger.Logger, checks *health.Registry, metrics *metrics.Metrics) http.Handler {
 	mux := DebugStandardLibraryMux()
 
+	// Publish the metrics in the Prometheus text format next to the
+	// expvar output.
+	mux.Handle("/debug/metrics", metrics.Handler())
+
 	// Register the check endpoints.
 	cg := checkGroup{
-		build:     build,
-		gqlConfig: gqlConfig,
+		build:  build,
+		log:    log,
+		health: checks,
 	}
+	mux.HandleFunc("/debug/liveness", cg.liveness)
 	mux.HandleFunc("/debug/readiness", cg.readiness)
 
 	return mux
 }
 
+// HeadersConfig contains the CORS and security headers written on the
+// responses.
+type HeadersConfig struct {
+	CORS   mid.CORSConfig
+	Secure mid.SecureHeadersConfig
+}
+
+// ContentSecurityPolicy returns the policy allowing the index page to load
+// its scripts, the Google Maps API and to query the database from the
+// browser endpoint.
+func ContentSecurityPolicy(browserEndpoint string) string {
+	google := "https://*.googleapis.com https://*.gstatic.com"
+	policy := []string{
+		"default-src 'self'",
+		"script-src 'self' 'unsafe-inline' https://d3js.org https://ajax.googleapis.com https://polyfill.io " + google,
+		"style-src 'self' 'unsafe-inline' https://fonts.googleapis.com",
+		"font-src https://fonts.gstatic.com",
+		"img-src 'self' data: https://*.google.com " + google,
+		"connect-src 'self' " + browserEndpoint + " " + google,
+		"frame-src https://*.google.com",
+		"frame-ancestors 'none'",
+	}
+	return strings.Join(policy, "; ")
+}
+
 // UIMux constructs an http.Handler with all application routes defined.
-func UIMux(build string, shutdown chan os.Signal, log *log.Logger, metrics *metrics.Metrics, gqlConfig data.GraphQLConfig, browserEndpoint string, mapsKey string) (*web.App, error) {
-	app := web.NewApp(shutdown, mid.Logger(log), mid.Errors(log), mid.Metrics(metrics), mid.Panics(log))
+func UIMux(build string, shutdown chan os.Signal, log *logger.Logger, metrics *metrics.Metrics, headersConfig HeadersConfig, gqlConfig data.GraphQLConfig, browserEndpoint string, mapsKey string) (*web.App, error) {
+	app := web.NewApp(shutdown, nil, mid.Logger(log), mid.Errors(log), mid.Metrics(metrics), mid.Panics(log), mid.SecureHeaders(headersConfig.Secure), mid.CORS(headersConfig.CORS))
+
+	// Send OPTIONS requests, such as CORS preflight requests, through the
+	// middleware.
+	app.HandleOptions(options)
 
 	// Register the index page for the website.
 	ig, err := newIndex(gqlConfig, browserEndpoint, mapsKey)
@@ -81,3 +118,9 @@ func UIMux(build string, shutdown chan os.Signal, log *log.Logger, metrics *metr
 
 	return app, nil
 }
+
+// options answers OPTIONS requests. CORS preflight requests are answered by
+// the CORS middleware before reaching it.
+func options(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"expvar"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,6 +14,9 @@ import (
 	"github.com/dgraph-io/travel/app/travel-ui/handlers"
 	"github.com/dgraph-io/travel/business/data"
 	"github.com/dgraph-io/travel/business/sys/metrics"
+	"github.com/dgraph-io/travel/business/web/mid"
+	"github.com/dgraph-io/travel/foundation/health"
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/pkg/errors"
 )
 
@@ -22,15 +24,13 @@ import (
 var build = "develop"
 
 func main() {
-	log := log.New(os.Stdout, "UI : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
-
-	if err := run(log); err != nil {
-		log.Println("main: error:", err)
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
 	}
 }
 
-func run(log *log.Logger) error {
+func run() error {
 
 	// =========================================================================
 	// Configuration
@@ -53,6 +53,19 @@ func run(log *log.Logger) error {
 			CloudHeaderName string `conf:"default:X-Auth-Token"`
 			CloudToken      string
 		}
+		Headers struct {
+			AllowedOrigins []string      `conf:"help:origins allowed to make cross-origin requests"`
+			HSTSMaxAge     time.Duration `conf:"default:0s,help:max age of the HSTS header; only set it when served over TLS"`
+			CSP            string        `conf:"help:content security policy replacing the one built for the index page"`
+		}
+		Health struct {
+			CacheTTL time.Duration `conf:"default:5s,help:how long the result of the health checks is reused"`
+		}
+		Log struct {
+			Level   string `conf:"default:info,help:lowest level logged: debug info warn or error"`
+			Format  string `conf:"default:json,help:format of the log entries: json or console"`
+			Queries string `conf:"default:full,help:how much of each database query is logged: off summary or full"`
+		}
 		APIKeys struct {
 			// You need to generate a Google Key to support Places API and JS Maps.
 			// Once you have the key it's best to export it.
@@ -87,19 +100,38 @@ func run(log *log.Logger) error {
 		return errors.Wrap(err, "parsing config")
 	}
 
+	// =========================================================================
+	// Initialize Logging Support
+
+	level, err := logger.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return errors.Wrap(err, "parsing log level")
+	}
+	log, err := logger.New(os.Stdout, logger.Config{Service: "travel-ui", Level: level, Format: cfg.Log.Format})
+	if err != nil {
+		return errors.Wrap(err, "constructing logger")
+	}
+
+	// The stores redact sensitive values from the queries they log.
+	queryLog, err := data.ParseQueryLog(cfg.Log.Queries)
+	if err != nil {
+		return errors.Wrap(err, "parsing query log mode")
+	}
+	data.SetQueryLog(queryLog)
+
 	// =========================================================================
 	// App Starting
 
 	// Print the build version for our logs. Also expose it under /debug/vars.
 	expvar.NewString("build").Set(build)
-	log.Printf("main: Application initializing : version %q", build)
-	defer log.Println("main : Completed")
+	log.Info("starting service", "version", build)
+	defer log.Info("shutdown complete")
 
 	out, err := conf.String(&cfg)
 	if err != nil {
 		return errors.Wrap(err, "generating config for output")
 	}
-	log.Printf("main: Config:\n%v\n", out)
+	log.Info("startup", "config", out)
 
 	// =========================================================================
 	// Initialize GraphQL Support
@@ -118,34 +150,62 @@ func run(log *log.Logger) error {
 
 	// Not concerned with shutting this down when the application is shutdown.
 
-	log.Println("main: Initializing debugging support")
+	log.Info("startup", "status", "initializing debugging support")
 
 	// The Debug function returns a mux to listen and serve on for all the debug
 	// related endpoints. This include the standard library endpoints.
 
-	debugMux := handlers.DebugMux(build, gqlConfig)
+	// The same metrics are updated by the requests and published here.
+	metrics := metrics.New()
+
+	// The service is ready when the database can be reached.
+	checks := health.NewRegistry(cfg.Health.CacheTTL)
+	checks.Register(data.HealthCheck(gqlConfig.URL))
+
+	debugMux := handlers.DebugMux(build, log, checks, metrics)
 
 	// Start the service listening for debug requests.
 	// Not concerned with shutting this down with load shedding.
 	go func() {
-		log.Printf("main: Debug Listening %s", cfg.Web.DebugHost)
+		log.Info("startup", "status", "debug listening", "host", cfg.Web.DebugHost)
 		if err := http.ListenAndServe(cfg.Web.DebugHost, debugMux); err != nil {
-			log.Printf("main: Debug Listener closed : %v", err)
+			log.Error("debug listener closed", "error", err)
 		}
 	}()
 
 	// =========================================================================
 	// Start UI Service
 
-	log.Println("main: Initializing UI support")
+	log.Info("startup", "status", "initializing UI support")
 
 	// Make a channel to listen for an interrupt or terminate signal from the OS.
 	// Use a buffered channel because the signal package requires it.
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
+	csp := cfg.Headers.CSP
+	if csp == "" {
+		csp = handlers.ContentSecurityPolicy(cfg.Dgraph.BrowserURL)
+	}
+	headersConfig := handlers.HeadersConfig{
+		CORS: mid.CORSConfig{
+			AllowedOrigins: cfg.Headers.AllowedOrigins,
+			AllowedMethods: []string{http.MethodGet},
+			AllowedHeaders: []string{"Content-Type", "traceparent"},
+		},
+		Secure: mid.SecureHeadersConfig{
+			HSTSMaxAge:            cfg.Headers.HSTSMaxAge,
+			ContentSecurityPolicy: csp,
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "strict-origin-when-cross-origin",
+		},
+	}
+	if err := headersConfig.CORS.Validate(); err != nil {
+		return errors.Wrap(err, "validating cors config")
+	}
+
 	// Load the templates and bind the handlers.
-	uiMux, err := handlers.UIMux(build, shutdown, log, metrics.New(), gqlConfig, cfg.Dgraph.BrowserURL, cfg.APIKeys.MapsKey)
+	uiMux, err := handlers.UIMux(build, shutdown, log, metrics, headersConfig, gqlConfig, cfg.Dgraph.BrowserURL, cfg.APIKeys.MapsKey)
 	if err != nil {
 		return errors.Wrap(err, "unable to bind handlers")
 	}
@@ -164,7 +224,7 @@ func run(log *log.Logger) error {
 
 	// Start the service listening for requests.
 	go func() {
-		log.Printf("main: UI listening on %s", ui.Addr)
+		log.Info("startup", "status", "ui listening", "host", ui.Addr)
 		serverErrors <- ui.ListenAndServe()
 	}()
 
@@ -177,7 +237,7 @@ func run(log *log.Logger) error {
 		return errors.Wrap(err, "server error")
 
 	case sig := <-shutdown:
-		log.Printf("main: %v : Start shutdown", sig)
+		log.Info("shutdown", "status", "shutdown started", "signal", sig.String())
 
 		// Give outstanding requests a deadline for completion.
 		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
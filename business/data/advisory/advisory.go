@@ -4,10 +4,13 @@ package advisory
 import (
 	"context"
 	"fmt"
-	"log"
+	"strings"
+	"time"
 
 	"github.com/ardanlabs/graphql"
 	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/pkg/errors"
 )
 
@@ -18,21 +21,24 @@ var (
 
 // Store manages the set of API's for advisory access.
 type Store struct {
-	log *log.Logger
+	log *logger.Logger
 	gql *graphql.GraphQL
 }
 
 // NewStore constructs a advisory store for api access.
-func NewStore(log *log.Logger, gql *graphql.GraphQL) Store {
+func NewStore(log *logger.Logger, gql *graphql.GraphQL) Store {
 	return Store{
 		log: log,
 		gql: gql,
 	}
 }
 
-// Replace replaces an advisory in the database and connects it
-// to the specified city.
-func (s Store) Replace(ctx context.Context, traceID string, adv Advisory) (Advisory, error) {
+// Replace replaces all the advisories for the specified city with the
+// provided advisory, which becomes the latest advisory.
+func (s Store) Replace(ctx context.Context, traceID string, adv Advisory, now time.Time) (Advisory, error) {
+	ctx, span := tracer.Start(ctx, "advisory.Replace")
+	defer span.End()
+
 	if adv.ID != "" {
 		return Advisory{}, errors.New("advisory contains id")
 	}
@@ -40,39 +46,104 @@ func (s Store) Replace(ctx context.Context, traceID string, adv Advisory) (Advis
 		return Advisory{}, errors.New("cityid not provided")
 	}
 
-	if oldAdv, err := s.QueryByCity(ctx, traceID, adv.City.ID); err == nil {
-		if err := s.delete(ctx, traceID, oldAdv.ID); err != nil {
-			if err != ErrNotFound {
-				return Advisory{}, errors.Wrap(err, "deleting advisory from database")
-			}
+	history, err := s.QueryHistory(ctx, traceID, adv.City.ID)
+	if err != nil {
+		return Advisory{}, errors.Wrap(err, "querying advisory history")
+	}
+
+	if len(history) > 0 {
+		ids := make([]string, len(history))
+		for i, old := range history {
+			ids[i] = old.ID
+		}
+		if err := s.delete(ctx, traceID, ids); err != nil {
+			return Advisory{}, errors.Wrap(err, "deleting advisory from database")
+		}
+	}
+
+	return s.Append(ctx, traceID, adv, now)
+}
+
+// Append adds a new advisory for the specified city, recorded at the
+// specified time. The advisory becomes the latest advisory for the city and
+// all previous advisories are kept as history.
+func (s Store) Append(ctx context.Context, traceID string, adv Advisory, now time.Time) (Advisory, error) {
+	ctx, span := tracer.Start(ctx, "advisory.Append")
+	defer span.End()
+
+	if adv.ID != "" {
+		return Advisory{}, errors.New("advisory contains id")
+	}
+	if adv.City.ID == "" {
+		return Advisory{}, errors.New("cityid not provided")
+	}
+
+	adv.RecordedAt = now
+	adv, err := s.add(ctx, traceID, adv)
+	if err != nil {
+		return Advisory{}, err
+	}
+
+	if err := s.setLatest(ctx, traceID, adv.City.ID, adv.ID); err != nil {
+		return Advisory{}, err
+	}
+
+	return adv, nil
+}
+
+// Prune removes the advisories for the specified city that were recorded
+// before the specified time. The latest advisory is never removed. The number
+// of advisories removed is returned.
+func (s Store) Prune(ctx context.Context, traceID string, cityID string, before time.Time) (int, error) {
+	ctx, span := tracer.Start(ctx, "advisory.Prune")
+	defer span.End()
+
+	if cityID == "" {
+		return 0, errors.New("cityid not provided")
+	}
+
+	filter := fmt.Sprintf(`(filter: { recorded_at: { lt: %q } })`, before.UTC().Format(time.RFC3339))
+	history, err := s.queryHistoryIDs(ctx, traceID, cityID, filter)
+	if err != nil {
+		return 0, errors.Wrap(err, "querying advisory history")
+	}
+
+	var latestID string
+	if latest, err := s.QueryByCity(ctx, traceID, cityID); err == nil {
+		latestID = latest.ID
+	}
+
+	var ids []string
+	for _, id := range history {
+		if id != latestID {
+			ids = append(ids, id)
 		}
 	}
 
-	return s.add(ctx, traceID, adv)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if err := s.delete(ctx, traceID, ids); err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
 }
 
-// QueryByCity returns the specified advisory from the database by the city id.
+// QueryByCity returns the latest advisory from the database by the city id.
 func (s Store) QueryByCity(ctx context.Context, traceID string, cityID string) (Advisory, error) {
+	ctx, span := tracer.Start(ctx, "advisory.QueryByCity")
+	defer span.End()
+
 	query := fmt.Sprintf(`
 query {
 	getCity(id: %q) {
-		advisory {
-			id
-			city {
-				id
-			}
-			continent
-			country
-			country_code
-			last_updated
-			message
-			score
-			source
-		}
+		advisory %s
 	}
-}`, cityID)
+}`, cityID, fields)
 
-	s.log.Printf("%s: %s: %s", traceID, "advisory.QueryByID", data.Log(query))
+	data.LogQuery(s.log, traceID, "advisory.QueryByCity", query)
 
 	var result struct {
 		GetCity struct {
@@ -90,8 +161,51 @@ query {
 	return result.GetCity.Advisory, nil
 }
 
+// QueryHistory returns every advisory recorded for the specified city,
+// ordered from newest to oldest.
+func (s Store) QueryHistory(ctx context.Context, traceID string, cityID string) ([]Advisory, error) {
+	ctx, span := tracer.Start(ctx, "advisory.QueryHistory")
+	defer span.End()
+
+	query := fmt.Sprintf(`
+query {
+	getCity(id: %q) {
+		advisory_history(order: { desc: recorded_at }) %s
+	}
+}`, cityID, fields)
+
+	data.LogQuery(s.log, traceID, "advisory.QueryHistory", query)
+
+	var result struct {
+		GetCity struct {
+			History []Advisory `json:"advisory_history"`
+		} `json:"getCity"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return nil, errors.Wrap(err, "query failed")
+	}
+
+	return result.GetCity.History, nil
+}
+
 // =============================================================================
 
+// fields is the set of advisory fields returned by queries.
+const fields = `{
+			id
+			city {
+				id
+			}
+			continent
+			country
+			country_code
+			last_updated
+			message
+			recorded_at
+			score
+			source
+		}`
+
 func (s Store) add(ctx context.Context, traceID string, adv Advisory) (Advisory, error) {
 	var result id
 	mutation := fmt.Sprintf(`
@@ -105,18 +219,19 @@ func (s Store) add(ctx context.Context, traceID string, adv Advisory) (Advisory,
 			country_code: %q
 			last_updated: %q
 			message: %q
+			recorded_at: %q
 			score: %f
 			source: %q
 		}])
 		%s
 	}`, adv.City.ID, adv.Continent, adv.Country, adv.CountryCode,
-		adv.LastUpdated, adv.Message, adv.Score, adv.Source,
-		result.document())
+		adv.LastUpdated, adv.Message, adv.RecordedAt.UTC().Format(time.RFC3339),
+		adv.Score, adv.Source, result.document())
 
-	s.log.Printf("%s: %s: %s", traceID, "advisory.Add", data.Log(mutation))
+	data.LogQuery(s.log, traceID, "advisory.Add", mutation)
 
 	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
-		return Advisory{}, errors.Wrap(err, "failed to add place")
+		return Advisory{}, errors.Wrap(err, "failed to add advisory")
 	}
 
 	if len(result.Resp.Entities) != 1 {
@@ -127,21 +242,88 @@ func (s Store) add(ctx context.Context, traceID string, adv Advisory) (Advisory,
 	return adv, nil
 }
 
-func (s Store) delete(ctx context.Context, traceID string, advID string) error {
+func (s Store) setLatest(ctx context.Context, traceID string, cityID string, advID string) error {
 	var result result
 	mutation := fmt.Sprintf(`
 	mutation {
-		resp: deleteAdvisory(filter: { id: [%q] })
+		resp: updateCity(input: {
+			filter: {
+				id: [%q]
+			},
+			set: {
+				advisory: {
+					id: %q
+				}
+			}
+		})
 		%s
-	}`, advID, result.document())
+	}`, cityID, advID, result.document())
 
-	s.log.Printf("%s: %s: %s", traceID, "advisory.Delete", data.Log(mutation))
+	data.LogQuery(s.log, traceID, "advisory.SetLatest", mutation)
 
 	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
-		return errors.Wrap(err, "failed to delete advisory")
+		return errors.Wrap(err, "failed to set latest advisory")
 	}
 
 	if result.Resp.NumUids != 1 {
+		msg := fmt.Sprintf("failed to set latest advisory: NumUids: %d  Msg: %s", result.Resp.NumUids, result.Resp.Msg)
+		return errors.New(msg)
+	}
+
+	return nil
+}
+
+func (s Store) queryHistoryIDs(ctx context.Context, traceID string, cityID string, filter string) ([]string, error) {
+	query := fmt.Sprintf(`
+query {
+	getCity(id: %q) {
+		advisory_history%s {
+			id
+		}
+	}
+}`, cityID, filter)
+
+	data.LogQuery(s.log, traceID, "advisory.QueryHistoryIDs", query)
+
+	var result struct {
+		GetCity struct {
+			History []struct {
+				ID string `json:"id"`
+			} `json:"advisory_history"`
+		} `json:"getCity"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return nil, errors.Wrap(err, "query failed")
+	}
+
+	ids := make([]string, len(result.GetCity.History))
+	for i, adv := range result.GetCity.History {
+		ids[i] = adv.ID
+	}
+
+	return ids, nil
+}
+
+func (s Store) delete(ctx context.Context, traceID string, advIDs []string) error {
+	quoted := make([]string, len(advIDs))
+	for i, id := range advIDs {
+		quoted[i] = fmt.Sprintf("%q", id)
+	}
+
+	var result result
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: deleteAdvisory(filter: { id: [%s] })
+		%s
+	}`, strings.Join(quoted, ", "), result.document())
+
+	data.LogQuery(s.log, traceID, "advisory.Delete", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return errors.Wrap(err, "failed to delete advisory")
+	}
+
+	if result.Resp.NumUids != len(advIDs) {
 		msg := fmt.Sprintf("failed to delete advisory: NumUids: %d  Msg: %s", result.Resp.NumUids, result.Resp.Msg)
 		return errors.New(msg)
 	}
@@ -1,16 +1,19 @@
 package advisory
 
+import "time"
+
 // Advisory contains the travel advisory result captured for a city.
 type Advisory struct {
-	ID          string  `json:"id,omitempty"`
-	City        City    `json:"city"`
-	Country     string  `json:"country"`
-	CountryCode string  `json:"country_code"`
-	Continent   string  `json:"continent"`
-	Score       float64 `json:"score"`
-	LastUpdated string  `json:"last_updated"`
-	Message     string  `json:"message"`
-	Source      string  `json:"source"`
+	ID          string    `json:"id,omitempty"`
+	City        City      `json:"city"`
+	Country     string    `json:"country"`
+	CountryCode string    `json:"country_code"`
+	Continent   string    `json:"continent"`
+	Score       float64   `json:"score"`
+	LastUpdated string    `json:"last_updated"`
+	Message     string    `json:"message"`
+	Source      string    `json:"source"`
+	RecordedAt  time.Time `json:"recorded_at"`
 }
 
 // City is used to capture the city id in relationships.
@@ -0,0 +1,216 @@
+// Package apikey provides support for managing the api keys used by machine
+// clients in the database.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/graphql"
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/tracer"
+	"github.com/pkg/errors"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotExists = errors.New("api key does not exist")
+	ErrNotFound  = errors.New("api key not found")
+	ErrExpired   = errors.New("api key has expired")
+)
+
+// prefix identifies the api keys issued by this service.
+const prefix = "tk_"
+
+// Store manages the set of API's for api key access.
+type Store struct {
+	log *logger.Logger
+	gql *graphql.GraphQL
+}
+
+// NewStore constructs an api key store for api access.
+func NewStore(log *logger.Logger, gql *graphql.GraphQL) Store {
+	return Store{
+		log: log,
+		gql: gql,
+	}
+}
+
+// Add generates a new api key with the specified scopes. The key is
+// returned to be handed to the client and only its hash is stored, so it
+// can't be retrieved again.
+func (s Store) Add(ctx context.Context, traceID string, nak NewAPIKey, now time.Time) (string, APIKey, error) {
+	ctx, span := tracer.Start(ctx, "apikey.Add")
+	defer span.End()
+
+	if nak.Name == "" || len(nak.Scopes) == 0 || nak.Lifetime <= 0 {
+		return "", APIKey{}, errors.New("name, scopes and lifetime are required")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", APIKey{}, errors.Wrap(err, "generating api key")
+	}
+	key := prefix + base64.RawURLEncoding.EncodeToString(b)
+
+	ak := APIKey{
+		KeyHash:     hash(key),
+		Name:        nak.Name,
+		Scopes:      nak.Scopes,
+		ExpiresAt:   now.Add(nak.Lifetime),
+		DateCreated: now,
+	}
+
+	ak, err := s.add(ctx, traceID, ak)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+
+	return key, ak, nil
+}
+
+// Delete revokes an api key by removing it from the database by its ID.
+func (s Store) Delete(ctx context.Context, traceID string, keyID string) error {
+	ctx, span := tracer.Start(ctx, "apikey.Delete")
+	defer span.End()
+
+	if keyID == "" {
+		return errors.New("missing api key id")
+	}
+
+	var result result
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: deleteApiKey(filter: { id: [%q] })
+		%s
+	}`, keyID, result.document())
+
+	data.LogQuery(s.log, traceID, "apikey.Delete", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return errors.Wrap(err, "failed to delete api key")
+	}
+
+	if result.Resp.NumUids != 1 {
+		return ErrNotExists
+	}
+
+	return nil
+}
+
+// Authenticate finds the api key and verifies it hasn't expired.
+func (s Store) Authenticate(ctx context.Context, traceID string, key string, now time.Time) (APIKey, error) {
+	ctx, span := tracer.Start(ctx, "apikey.Authenticate")
+	defer span.End()
+
+	if !strings.HasPrefix(key, prefix) {
+		return APIKey{}, ErrNotFound
+	}
+
+	query := fmt.Sprintf(`
+query {
+	getApiKey(key_hash: %q) %s
+}`, hash(key), fields)
+
+	data.LogQuery(s.log, traceID, "apikey.Authenticate", query)
+
+	var result struct {
+		GetApiKey APIKey `json:"getApiKey"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return APIKey{}, errors.Wrap(err, "query failed")
+	}
+
+	if result.GetApiKey.ID == "" {
+		return APIKey{}, ErrNotFound
+	}
+
+	if now.After(result.GetApiKey.ExpiresAt) {
+		return APIKey{}, ErrExpired
+	}
+
+	return result.GetApiKey, nil
+}
+
+// QueryAll returns every api key ordered by name.
+func (s Store) QueryAll(ctx context.Context, traceID string) ([]APIKey, error) {
+	ctx, span := tracer.Start(ctx, "apikey.QueryAll")
+	defer span.End()
+
+	query := fmt.Sprintf(`
+query {
+	queryApiKey(order: { asc: name }) %s
+}`, fields)
+
+	data.LogQuery(s.log, traceID, "apikey.QueryAll", query)
+
+	var result struct {
+		QueryApiKey []APIKey `json:"queryApiKey"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return nil, errors.Wrap(err, "query failed")
+	}
+
+	return result.QueryApiKey, nil
+}
+
+// =============================================================================
+
+// fields is the set of api key fields returned by queries. The hash is left
+// out since it's never needed by the callers.
+const fields = `{
+		id
+		name
+		scopes
+		expires_at
+		date_created
+	}`
+
+// hash returns the value stored in the database for an api key.
+func hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s Store) add(ctx context.Context, traceID string, ak APIKey) (APIKey, error) {
+	scopes := make([]string, len(ak.Scopes))
+	for i, scope := range ak.Scopes {
+		scopes[i] = fmt.Sprintf("%q", scope)
+	}
+
+	var result id
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: addApiKey(input: [{
+			key_hash: %q
+			name: %q
+			scopes: [%s]
+			expires_at: %q
+			date_created: %q
+		}])
+		%s
+	}`, ak.KeyHash, ak.Name, strings.Join(scopes, ", "),
+		ak.ExpiresAt.UTC().Format(time.RFC3339),
+		ak.DateCreated.UTC().Format(time.RFC3339),
+		result.document())
+
+	data.LogQuery(s.log, traceID, "apikey.Add", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return APIKey{}, errors.Wrap(err, "failed to add api key")
+	}
+
+	if len(result.Resp.Entities) != 1 {
+		return APIKey{}, errors.New("api key id not returned")
+	}
+
+	ak.ID = result.Resp.Entities[0].ID
+	return ak, nil
+}
@@ -0,0 +1,53 @@
+package apikey
+
+import "time"
+
+// APIKey represents a key machine clients use to call the api. Only the hash
+// of the key is stored.
+type APIKey struct {
+	ID          string    `json:"id"`
+	KeyHash     string    `json:"key_hash,omitempty"`
+	Name        string    `json:"name"`
+	Scopes      []string  `json:"scopes"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// NewAPIKey contains information needed to create a new APIKey.
+type NewAPIKey struct {
+	Name     string        `json:"name" validate:"required"`
+	Scopes   []string      `json:"scopes" validate:"required"`
+	Lifetime time.Duration `json:"lifetime" validate:"required"`
+}
+
+// =============================================================================
+
+type id struct {
+	Resp struct {
+		Entities []struct {
+			ID string `json:"id"`
+		} `json:"entities"`
+	} `json:"resp"`
+}
+
+func (id) document() string {
+	return `{
+		entities: apiKey {
+			id
+		}
+	}`
+}
+
+type result struct {
+	Resp struct {
+		Msg     string
+		NumUids int
+	} `json:"resp"`
+}
+
+func (result) document() string {
+	return `{
+		msg,
+		numUids,
+	}`
+}
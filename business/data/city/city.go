@@ -4,26 +4,29 @@ package city
 import (
 	"context"
 	"fmt"
-	"log"
+	"strings"
 
 	"github.com/ardanlabs/graphql"
 	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/pkg/errors"
 )
 
 // Set of error variables for CRUD operations.
 var (
-	ErrNotFound = errors.New("city not found")
+	ErrNotExists = errors.New("city does not exist")
+	ErrNotFound  = errors.New("city not found")
 )
 
 // Store manages the set of API's for city access.
 type Store struct {
-	log *log.Logger
+	log *logger.Logger
 	gql *graphql.GraphQL
 }
 
 // NewStore constructs a city store for api access.
-func NewStore(log *log.Logger, gql *graphql.GraphQL) Store {
+func NewStore(log *logger.Logger, gql *graphql.GraphQL) Store {
 	return Store{
 		log: log,
 		gql: gql,
@@ -34,6 +37,9 @@ func NewStore(log *log.Logger, gql *graphql.GraphQL) Store {
 // If the city already exists in the database, the function will return an City
 // value with the existing id.
 func (s Store) Upsert(ctx context.Context, traceID string, cty City) (City, error) {
+	ctx, span := tracer.Start(ctx, "city.Upsert")
+	defer span.End()
+
 	if cty.ID != "" {
 		return City{}, errors.New("city contains id")
 	}
@@ -41,8 +47,85 @@ func (s Store) Upsert(ctx context.Context, traceID string, cty City) (City, erro
 	return s.upsert(ctx, traceID, cty)
 }
 
+// Update replaces the name and coordinates of a city in the database by its
+// ID. If the city doesn't already exist, this function will fail.
+func (s Store) Update(ctx context.Context, traceID string, cty City) error {
+	ctx, span := tracer.Start(ctx, "city.Update")
+	defer span.End()
+
+	if cty.ID == "" {
+		return errors.New("city missing id")
+	}
+
+	if _, err := s.QueryByID(ctx, traceID, cty.ID); err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return ErrNotExists
+		}
+		return errors.Wrap(err, "querying city")
+	}
+
+	return s.update(ctx, traceID, cty)
+}
+
+// Delete removes a city from the database by its ID. The weather, advisory
+// and places connected to the city are removed with it. If the city doesn't
+// already exist, this function will fail.
+func (s Store) Delete(ctx context.Context, traceID string, cityID string) error {
+	ctx, span := tracer.Start(ctx, "city.Delete")
+	defer span.End()
+
+	if cityID == "" {
+		return errors.New("missing city id")
+	}
+
+	deps, err := s.queryDependents(ctx, traceID, cityID)
+	if err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return ErrNotExists
+		}
+		return errors.Wrap(err, "querying city dependents")
+	}
+
+	return s.delete(ctx, traceID, deps)
+}
+
+// QueryAll returns a page of cities from the database ordered by name.
+// The page number starts at 1.
+func (s Store) QueryAll(ctx context.Context, traceID string, pageNumber int, rowsPerPage int) ([]City, error) {
+	ctx, span := tracer.Start(ctx, "city.QueryAll")
+	defer span.End()
+
+	if pageNumber < 1 || rowsPerPage < 1 {
+		return nil, errors.New("invalid page number or rows per page")
+	}
+
+	query := fmt.Sprintf(`
+query {
+	queryCity(order: { asc: name }, first: %d, offset: %d) {
+		id
+		name
+		lat
+		lng
+	}
+}`, rowsPerPage, (pageNumber-1)*rowsPerPage)
+
+	data.LogQuery(s.log, traceID, "city.QueryAll", query)
+
+	var result struct {
+		QueryCity []City `json:"queryCity"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return nil, errors.Wrap(err, "query failed")
+	}
+
+	return result.QueryCity, nil
+}
+
 // QueryByID returns the specified city from the database by the city id.
 func (s Store) QueryByID(ctx context.Context, traceID string, cityID string) (City, error) {
+	ctx, span := tracer.Start(ctx, "city.QueryByID")
+	defer span.End()
+
 	query := fmt.Sprintf(`
 query {
 	getCity(id: %q) {
@@ -53,7 +136,7 @@ query {
 	}
 }`, cityID)
 
-	s.log.Printf("%s: %s: %s", traceID, "city.QueryByID", data.Log(query))
+	data.LogQuery(s.log, traceID, "city.QueryByID", query)
 
 	var result struct {
 		GetCity City `json:"getCity"`
@@ -71,6 +154,9 @@ query {
 
 // QueryByName returns the specified city from the database by the city name.
 func (s Store) QueryByName(ctx context.Context, traceID string, name string) (City, error) {
+	ctx, span := tracer.Start(ctx, "city.QueryByName")
+	defer span.End()
+
 	query := fmt.Sprintf(`
 query {
 	queryCity(filter: { name: { eq: %q } }) {
@@ -81,7 +167,7 @@ query {
 	}
 }`, name)
 
-	s.log.Printf("%s: %s: %s", traceID, "city.QueryByName", data.Log(query))
+	data.LogQuery(s.log, traceID, "city.QueryByName", query)
 
 	var result struct {
 		QueryCity []struct {
@@ -101,6 +187,9 @@ query {
 
 // QueryNames returns the list of city names currently loaded in the database.
 func (s Store) QueryNames(ctx context.Context, traceID string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "city.QueryNames")
+	defer span.End()
+
 	query := `
 	query {
 		queryCity(filter: { }) {
@@ -108,7 +197,7 @@ func (s Store) QueryNames(ctx context.Context, traceID string) ([]string, error)
 		}
 	}`
 
-	s.log.Printf("%s: %s: %s", traceID, "city.QueryNames", data.Log(query))
+	data.LogQuery(s.log, traceID, "city.QueryNames", query)
 
 	var result struct {
 		QueryCity []struct {
@@ -145,7 +234,7 @@ func (s Store) upsert(ctx context.Context, traceID string, cty City) (City, erro
 		%s
 	}`, cty.Name, cty.Lat, cty.Lng, result.document())
 
-	s.log.Printf("%s: %s: %s", traceID, "city.Upsert", data.Log(mutation))
+	data.LogQuery(s.log, traceID, "city.Upsert", mutation)
 
 	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
 		return City{}, errors.Wrap(err, "failed to upsert city")
@@ -158,3 +247,104 @@ func (s Store) upsert(ctx context.Context, traceID string, cty City) (City, erro
 	cty.ID = result.Resp.Entities[0].ID
 	return cty, nil
 }
+
+func (s Store) update(ctx context.Context, traceID string, cty City) error {
+	var result result
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: updateCity(input: {
+			filter: {
+				id: [%q]
+			},
+			set: {
+				name: %q
+				lat: %f
+				lng: %f
+			}
+		})
+		%s
+	}`, cty.ID, cty.Name, cty.Lat, cty.Lng, result.document())
+
+	data.LogQuery(s.log, traceID, "city.Update", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return errors.Wrap(err, "failed to update city")
+	}
+
+	if result.Resp.NumUids != 1 {
+		msg := fmt.Sprintf("failed to update city: NumUids: %d  Msg: %s", result.Resp.NumUids, result.Resp.Msg)
+		return errors.New(msg)
+	}
+
+	return nil
+}
+
+func (s Store) queryDependents(ctx context.Context, traceID string, cityID string) (dependents, error) {
+	query := fmt.Sprintf(`
+query {
+	getCity(id: %q) {
+		%s
+	}
+}`, cityID, dependents{}.document())
+
+	data.LogQuery(s.log, traceID, "city.QueryDependents", query)
+
+	var result struct {
+		GetCity dependents `json:"getCity"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return dependents{}, errors.Wrap(err, "query failed")
+	}
+
+	if result.GetCity.ID == "" {
+		return dependents{}, ErrNotFound
+	}
+
+	return result.GetCity, nil
+}
+
+func (s Store) delete(ctx context.Context, traceID string, deps dependents) error {
+	var result result
+
+	// Every dependent node is removed in the same request, before the
+	// city, so no orphaned nodes are left behind if the city is removed.
+	var b strings.Builder
+	if ids := deps.placeIDs(); len(ids) > 0 {
+		fmt.Fprintf(&b, "places: deletePlace(filter: { id: [%s] }) { numUids }\n", quoteIDs(ids))
+	}
+	if ids := deps.advisoryIDs(); len(ids) > 0 {
+		fmt.Fprintf(&b, "advisory: deleteAdvisory(filter: { id: [%s] }) { numUids }\n", quoteIDs(ids))
+	}
+	if ids := deps.weatherIDs(); len(ids) > 0 {
+		fmt.Fprintf(&b, "weather: deleteWeather(filter: { id: [%s] }) { numUids }\n", quoteIDs(ids))
+	}
+
+	mutation := fmt.Sprintf(`
+	mutation {
+		%s
+		resp: deleteCity(filter: { id: [%q] })
+		%s
+	}`, b.String(), deps.ID, result.document())
+
+	data.LogQuery(s.log, traceID, "city.Delete", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return errors.Wrap(err, "failed to delete city")
+	}
+
+	if result.Resp.NumUids != 1 {
+		msg := fmt.Sprintf("failed to delete city: NumUids: %d  Msg: %s", result.Resp.NumUids, result.Resp.Msg)
+		return errors.New(msg)
+	}
+
+	return nil
+}
+
+// quoteIDs formats a list of ids for use inside a GraphQL list.
+func quoteIDs(ids []string) string {
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = fmt.Sprintf("%q", id)
+	}
+	return strings.Join(quoted, ", ")
+}
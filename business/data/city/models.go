@@ -25,3 +25,61 @@ func (id) document() string {
 		}
 	}`
 }
+
+type result struct {
+	Resp struct {
+		Msg     string
+		NumUids int
+	} `json:"resp"`
+}
+
+func (result) document() string {
+	return `{
+		msg,
+		numUids,
+	}`
+}
+
+type node struct {
+	ID string `json:"id"`
+}
+
+type dependents struct {
+	ID       string `json:"id"`
+	Places   []node `json:"places"`
+	Advisory []node `json:"advisory_history"`
+	Weather  []node `json:"weather_history"`
+}
+
+func (dependents) document() string {
+	return `id
+		places {
+			id
+		}
+		advisory_history {
+			id
+		}
+		weather_history {
+			id
+		}`
+}
+
+func (d dependents) placeIDs() []string {
+	return nodeIDs(d.Places)
+}
+
+func (d dependents) advisoryIDs() []string {
+	return nodeIDs(d.Advisory)
+}
+
+func (d dependents) weatherIDs() []string {
+	return nodeIDs(d.Weather)
+}
+
+func nodeIDs(nodes []node) []string {
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	return ids
+}
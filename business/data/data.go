@@ -11,35 +11,90 @@ import (
 	"time"
 
 	"github.com/ardanlabs/graphql"
+	"github.com/dgraph-io/travel/business/sys/metrics"
+	"github.com/dgraph-io/travel/foundation/health"
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/pkg/errors"
 )
 
 // GraphQLConfig represents comfiguration needed to support managing, mutating,
-// and querying the database.
+// and querying the database. When AuthTokenSource is set, it's called for
+// every request to get the token sent in place of the AuthToken, so tokens
+// can be replaced before they expire. When ForwardAuth is set, the token of
+// the caller found in the request context is sent instead so the database
+// applies its rules for that caller.
 type GraphQLConfig struct {
 	URL             string
 	AuthHeaderName  string
 	AuthToken       string
+	AuthTokenSource func() (string, error)
 	CloudHeaderName string
 	CloudToken      string
+	ForwardAuth     bool
+}
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// authKey is used to store/retrieve the token of the caller from a context.
+const authKey ctxKey = 1
+
+// WithAuthToken returns a new context carrying the token of the caller. The
+// token is forwarded to the database by clients configured with ForwardAuth.
+func WithAuthToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, authKey, token)
+}
+
+// AuthToken returns the token of the caller carried by the context.
+func AuthToken(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(authKey).(string)
+	return token, ok && token != ""
 }
 
 // NewGraphQL constructs a graphql value for use to access the databse.
 func NewGraphQL(gqlConfig GraphQLConfig) *graphql.GraphQL {
+	var transport http.RoundTripper = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			DualStack: true,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	// Record the calls made and continue the trace of the request in the
+	// database.
+	transport = recordMetrics{
+		base: transport,
+	}
+	transport = traceContext{
+		base: transport,
+	}
+
+	if gqlConfig.ForwardAuth {
+		transport = forwardAuth{
+			header: gqlConfig.AuthHeaderName,
+			base:   transport,
+		}
+	}
+
+	// The token of the source is set before the token of the caller is
+	// forwarded so the caller still takes precedence.
+	if gqlConfig.AuthTokenSource != nil {
+		transport = sourceAuth{
+			header: gqlConfig.AuthHeaderName,
+			source: gqlConfig.AuthTokenSource,
+			base:   transport,
+		}
+	}
+
 	client := http.Client{
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-				DualStack: true,
-			}).DialContext,
-			ForceAttemptHTTP2:     true,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		},
+		Transport: transport,
 	}
 
 	graphql := graphql.New(gqlConfig.URL,
@@ -51,11 +106,76 @@ func NewGraphQL(gqlConfig GraphQLConfig) *graphql.GraphQL {
 	return graphql
 }
 
-// Log removes line feeds and tabs for better logging.
-func Log(query string) string {
-	query = strings.Replace(query, "\t", "", -1)
-	query = strings.Replace(query, "\n", " ", -1)
-	return query
+// forwardAuth replaces the auth header of a request with the token of the
+// caller found in the request context.
+type forwardAuth struct {
+	header string
+	base   http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (f forwardAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	if token, ok := AuthToken(req.Context()); ok {
+		req = req.Clone(req.Context())
+		req.Header.Set(f.header, token)
+	}
+	return f.base.RoundTrip(req)
+}
+
+// sourceAuth sets the auth header of a request to the token returned by the
+// source. The request fails when no token is available rather than being
+// sent without one.
+type sourceAuth struct {
+	header string
+	source func() (string, error)
+	base   http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (s sourceAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := s.source()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting auth token")
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set(s.header, token)
+
+	return s.base.RoundTrip(req)
+}
+
+// traceContext adds the trace context of the current span to a request.
+type traceContext struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t traceContext) RoundTrip(req *http.Request) (*http.Response, error) {
+	if span := tracer.SpanFromContext(req.Context()); span != nil {
+		req = req.Clone(req.Context())
+		tracer.Inject(req.Context(), req.Header)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// recordMetrics records the calls made to the database in the metrics. A
+// server error counts as a failed call.
+type recordMetrics struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (m recordMetrics) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := m.base.RoundTrip(req)
+	if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+		metrics.AddDgraphCall(time.Since(start), errors.New(resp.Status))
+		return resp, err
+	}
+	metrics.AddDgraphCall(time.Since(start), err)
+
+	return resp, err
 }
 
 // Validate checks if the DB is ready to receive requests. It will attempt
@@ -93,6 +213,21 @@ func Validate(ctx context.Context, url string, retryInterval time.Duration) erro
 	}
 }
 
+// HealthCheck returns the check reporting whether the database is ready.
+// The service can't handle requests without it so the check is critical.
+func HealthCheck(url string) health.Check {
+	f := func(ctx context.Context) error {
+		return Validate(ctx, url, 100*time.Millisecond)
+	}
+
+	return health.Check{
+		Name:     "dgraph",
+		Critical: true,
+		Timeout:  time.Second,
+		Checker:  f,
+	}
+}
+
 // checkDB attempts to validate if the database is ready.
 func checkDB(ctx context.Context, url string) error {
 	ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
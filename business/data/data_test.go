@@ -2,23 +2,28 @@ package data_test
 
 import (
 	"context"
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
-	"log"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/ardanlabs/graphql"
 	"github.com/dgraph-io/travel/business/data"
 	"github.com/dgraph-io/travel/business/data/advisory"
+	"github.com/dgraph-io/travel/business/data/apikey"
 	"github.com/dgraph-io/travel/business/data/city"
 	"github.com/dgraph-io/travel/business/data/place"
+	"github.com/dgraph-io/travel/business/data/rating"
 	"github.com/dgraph-io/travel/business/data/schema"
 	"github.com/dgraph-io/travel/business/data/tests"
+	"github.com/dgraph-io/travel/business/data/token"
 	"github.com/dgraph-io/travel/business/data/user"
 	"github.com/dgraph-io/travel/business/data/weather"
 	"github.com/dgraph-io/travel/business/sys/auth"
 	"github.com/dgraph-io/travel/foundation/keystore"
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/crypto/bcrypt"
@@ -26,7 +31,7 @@ import (
 
 type TestConfig struct {
 	traceID string
-	log     *log.Logger
+	log     *logger.Logger
 	url     string
 	schema  schema.Config
 }
@@ -57,9 +62,14 @@ func TestData(t *testing.T) {
 	t.Run("schema", addSchema(tc))
 	t.Run("user", addUser(tc))
 	t.Run("city", upsertCity(tc))
+	t.Run("citycrud", crudCity(tc))
 	t.Run("place", addPlace(tc))
 	t.Run("advisory", replaceAdvisory(tc))
 	t.Run("weather", replaceWeather(tc))
+	t.Run("weatherhistory", appendWeather(tc))
+	t.Run("rating", rateUser(tc))
+	t.Run("token", manageTokens(tc))
+	t.Run("apikey", manageAPIKeys(tc))
 	t.Run("auth", performAuth())
 }
 
@@ -243,6 +253,16 @@ func addUser(tc TestConfig) func(t *testing.T) {
 				}
 				t.Logf("\t%s\tTest %d:\tShould get back the same password hash.", tests.Success, testID)
 
+				if _, err := store.Authenticate(ctx, traceID, newUser.Email, newUser.Password, user.Lockout{}, now); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to authenticate the user: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to authenticate the user.", tests.Success, testID)
+
+				if _, err := store.Authenticate(ctx, traceID, newUser.Email, "wrong", user.Lockout{}, now); err != user.ErrAuthenticationFailure {
+					t.Fatalf("\t%s\tTest %d:\tShould not be able to authenticate with a bad password: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould not be able to authenticate with a bad password.", tests.Success, testID)
+
 				userByEmail, err := store.QueryByEmail(ctx, traceID, addedUser.Email)
 				if err != nil {
 					t.Fatalf("\t%s\tTest %d:\tShould be able to query for the user by email: %v", tests.Failed, testID, err)
@@ -254,12 +274,64 @@ func addUser(tc TestConfig) func(t *testing.T) {
 				}
 				t.Logf("\t%s\tTest %d:\tShould get back the same user by email.", tests.Success, testID)
 
+				lockout := user.Lockout{Attempts: 2, Duration: time.Hour}
+				for i := 0; i < lockout.Attempts; i++ {
+					if _, err := store.Authenticate(ctx, traceID, newUser.Email, "wrong", lockout, now); err != user.ErrAuthenticationFailure {
+						t.Fatalf("\t%s\tTest %d:\tShould count the failed logins: %v", tests.Failed, testID, err)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould count the failed logins.", tests.Success, testID)
+
+				if _, err := store.Authenticate(ctx, traceID, newUser.Email, newUser.Password, lockout, now); err != user.ErrAccountLocked {
+					t.Fatalf("\t%s\tTest %d:\tShould lock the account after too many failed logins: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould lock the account after too many failed logins.", tests.Success, testID)
+
+				if _, err := store.Authenticate(ctx, traceID, newUser.Email, newUser.Password, lockout, now.Add(2*time.Hour)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould unlock the account after the lockout: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould unlock the account after the lockout.", tests.Success, testID)
+
 				_, err = store.Add(ctx, traceID, newUser, now)
 				if err == nil {
 					t.Fatalf("\t%s\tTest %d:\tShould not be able to add the same user twice.", tests.Failed, testID)
 				}
 				t.Logf("\t%s\tTest %d:\tShould not be able to add the same user twice: %v", tests.Success, testID, err)
 
+				name := "Jacob Walker"
+				uu := user.UpdateUser{
+					Name: &name,
+				}
+				updatedUser, err := store.Update(ctx, traceID, addedUser.ID, uu, now.Add(time.Hour))
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to update the user: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to update the user.", tests.Success, testID)
+
+				retUser, err = store.QueryByID(ctx, traceID, addedUser.ID)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to query for the updated user: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to query for the updated user.", tests.Success, testID)
+
+				if diff := cmp.Diff(updatedUser, retUser); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get back the updated user. Diff:\n%s", tests.Failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back the updated user.", tests.Success, testID)
+
+				users, err := store.QueryAll(ctx, traceID, 1, 10)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to query for a page of users: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to query for a page of users.", tests.Success, testID)
+
+				if len(users) != 1 {
+					t.Logf("\t\tTest %d:\tgot: %v", testID, len(users))
+					t.Logf("\t\tTest %d:\texp: %v", testID, 1)
+					t.Fatalf("\t%s\tTest %d:\tShould get back one user.", tests.Failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back one user.", tests.Success, testID)
+
 				err = store.Delete(ctx, traceID, addedUser.ID)
 				if err != nil {
 					t.Logf("\t%s\tTest %d:\tShould be able to delete the user: %v.", tests.Failed, testID, err)
@@ -352,6 +424,84 @@ func upsertCity(tc TestConfig) func(t *testing.T) {
 	return tf
 }
 
+// crudCity validates a city can be listed, updated and deleted along with
+// the nodes that depend on it.
+func crudCity(tc TestConfig) func(t *testing.T) {
+	tf := func(t *testing.T) {
+		t.Log("Given the need to be able to validate managing a city.")
+		{
+			testID := 0
+			t.Logf("\tTest %d:\tWhen handling a city for Sydney.", testID)
+			{
+				ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+				defer cancel()
+
+				newCity := city.City{
+					Name: "sydney",
+					Lat:  -33.865143,
+					Lng:  151.209900,
+				}
+				gql, addedCity := seedCity(t, ctx, testID, tc, newCity)
+				store := city.NewStore(tc.log, gql)
+
+				cities, err := store.QueryAll(ctx, tc.traceID, 1, 10)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to query for a page of cities: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to query for a page of cities.", tests.Success, testID)
+
+				if diff := cmp.Diff([]city.City{addedCity}, cities); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get back the page of cities. Diff:\n%s", tests.Failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back the page of cities.", tests.Success, testID)
+
+				addedCity.Name = "sydney harbour"
+				if err := store.Update(ctx, tc.traceID, addedCity); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to update the city: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to update the city.", tests.Success, testID)
+
+				retCity, err := store.QueryByID(ctx, tc.traceID, addedCity.ID)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to query for the city: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to query for the city.", tests.Success, testID)
+
+				if diff := cmp.Diff(addedCity, retCity); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get back the updated city. Diff:\n%s", tests.Failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back the updated city.", tests.Success, testID)
+
+				newWeather := weather.Weather{
+					City:     weather.City{ID: addedCity.ID},
+					CityName: "sydney",
+					Desc:     "going to be a great day",
+				}
+				if _, err := weather.NewStore(tc.log, gql).Replace(ctx, tc.traceID, newWeather, time.Now()); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to add weather to the city: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to add weather to the city.", tests.Success, testID)
+
+				if err := store.Delete(ctx, tc.traceID, addedCity.ID); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to delete the city: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to delete the city.", tests.Success, testID)
+
+				if _, err := store.QueryByID(ctx, tc.traceID, addedCity.ID); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould not be able to query for the city.", tests.Failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould not be able to query for the city.", tests.Success, testID)
+
+				if _, err := weather.NewStore(tc.log, gql).QueryByCity(ctx, tc.traceID, addedCity.ID); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould not be able to query for the weather.", tests.Failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould not be able to query for the weather.", tests.Success, testID)
+			}
+		}
+	}
+	return tf
+}
+
 // addPlace validates a place can be added to the database.
 func addPlace(tc TestConfig) func(t *testing.T) {
 	tf := func(t *testing.T) {
@@ -491,7 +641,9 @@ func replaceAdvisory(tc TestConfig) func(t *testing.T) {
 					Source:      "friendly neighborhood community engineers",
 				}
 
-				addedAdvisory, err := store.Replace(ctx, tc.traceID, newAdvisory)
+				now := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+				addedAdvisory, err := store.Replace(ctx, tc.traceID, newAdvisory, now)
 				if err != nil {
 					t.Fatalf("\t%s\tTest %d:\tShould be able to replace an advisory in Dgraph: %v", tests.Failed, testID, err)
 				}
@@ -510,7 +662,7 @@ func replaceAdvisory(tc TestConfig) func(t *testing.T) {
 
 				addedAdvisory.ID = ""
 				addedAdvisory.Score = 6
-				addedAdvisory, err = store.Replace(ctx, tc.traceID, addedAdvisory)
+				addedAdvisory, err = store.Replace(ctx, tc.traceID, addedAdvisory, now.Add(time.Hour))
 				if err != nil {
 					t.Fatalf("\t%s\tTest %d:\tShould be able to replace an advisory twice in Dgraph: %v", tests.Failed, testID, err)
 				}
@@ -526,6 +678,25 @@ func replaceAdvisory(tc TestConfig) func(t *testing.T) {
 					t.Fatalf("\t%s\tTest %d:\tShould get back the same advisory. Diff:\n%s", tests.Failed, testID, diff)
 				}
 				t.Logf("\t%s\tTest %d:\tShould get back the same advisory.", tests.Success, testID)
+
+				addedAdvisory.ID = ""
+				addedAdvisory.Score = 2
+				appendedAdvisory, err := store.Append(ctx, tc.traceID, addedAdvisory, now.Add(2*time.Hour))
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to append an advisory in Dgraph: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to append an advisory in Dgraph.", tests.Success, testID)
+
+				history, err := store.QueryHistory(ctx, tc.traceID, addedCity.ID)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to query the advisory history: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to query the advisory history.", tests.Success, testID)
+
+				if diff := cmp.Diff([]advisory.Advisory{appendedAdvisory, retAdvisory}, history); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get back the advisory history. Diff:\n%s", tests.Failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back the advisory history.", tests.Success, testID)
 			}
 		}
 	}
@@ -568,7 +739,9 @@ func replaceWeather(tc TestConfig) func(t *testing.T) {
 					Sunset:        10009945,
 				}
 
-				addedWeather, err := store.Replace(ctx, tc.traceID, newWeather)
+				now := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+				addedWeather, err := store.Replace(ctx, tc.traceID, newWeather, now)
 				if err != nil {
 					t.Fatalf("\t%s\tTest %d:\tShould be able to replace the weather in Dgraph: %v", tests.Failed, testID, err)
 				}
@@ -587,7 +760,7 @@ func replaceWeather(tc TestConfig) func(t *testing.T) {
 
 				addedWeather.ID = ""
 				addedWeather.Desc = "test replace"
-				addedWeather, err = store.Replace(ctx, tc.traceID, addedWeather)
+				addedWeather, err = store.Replace(ctx, tc.traceID, addedWeather, now.Add(time.Hour))
 				if err != nil {
 					t.Fatalf("\t%s\tTest %d:\tShould be able to replace the weather twice in Dgraph: %v", tests.Failed, testID, err)
 				}
@@ -609,6 +782,352 @@ func replaceWeather(tc TestConfig) func(t *testing.T) {
 	return tf
 }
 
+// appendWeather validates weather snapshots are kept as history and can be
+// queried and pruned by time.
+func appendWeather(tc TestConfig) func(t *testing.T) {
+	tf := func(t *testing.T) {
+		t.Log("Given the need to be able to validate keeping weather history.")
+		{
+			testID := 0
+			t.Logf("\tTest %d:\tWhen handling weather snapshots for sydney.", testID)
+			{
+				ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+				defer cancel()
+
+				newCity := city.City{
+					Name: "sydney",
+					Lat:  -33.865143,
+					Lng:  151.209900,
+				}
+				gql, addedCity := seedCity(t, ctx, testID, tc, newCity)
+				store := weather.NewStore(tc.log, gql)
+
+				start := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+				var snapshots []weather.Weather
+				for i := 0; i < 3; i++ {
+					newWeather := weather.Weather{
+						City:     weather.City{ID: addedCity.ID},
+						CityName: "Sydney",
+						Desc:     fmt.Sprintf("day %d", i),
+						Temp:     float64(90 + i),
+					}
+
+					addedWeather, err := store.Append(ctx, tc.traceID, newWeather, start.Add(time.Duration(i)*24*time.Hour))
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to append weather in Dgraph: %v", tests.Failed, testID, err)
+					}
+					snapshots = append(snapshots, addedWeather)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to append weather in Dgraph.", tests.Success, testID)
+
+				latest, err := store.QueryByCity(ctx, tc.traceID, addedCity.ID)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to query for the latest weather: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to query for the latest weather.", tests.Success, testID)
+
+				if diff := cmp.Diff(snapshots[2], latest); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get back the latest weather. Diff:\n%s", tests.Failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back the latest weather.", tests.Success, testID)
+
+				history, err := store.QueryRange(ctx, tc.traceID, addedCity.ID, start, start.Add(24*time.Hour))
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to query a range of weather: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to query a range of weather.", tests.Success, testID)
+
+				if diff := cmp.Diff(snapshots[:2], history); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get back the range of weather. Diff:\n%s", tests.Failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back the range of weather.", tests.Success, testID)
+
+				pruned, err := store.Prune(ctx, tc.traceID, addedCity.ID, start.Add(72*time.Hour))
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to prune weather: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to prune weather.", tests.Success, testID)
+
+				if pruned != 2 {
+					t.Logf("\t\tTest %d:\tgot: %v", testID, pruned)
+					t.Logf("\t\tTest %d:\texp: %v", testID, 2)
+					t.Fatalf("\t%s\tTest %d:\tShould prune all but the latest weather.", tests.Failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould prune all but the latest weather.", tests.Success, testID)
+			}
+		}
+	}
+	return tf
+}
+
+// rateUser validates a user can rate the places they visited.
+func rateUser(tc TestConfig) func(t *testing.T) {
+	tf := func(t *testing.T) {
+		t.Log("Given the need to be able to validate rating places.")
+		{
+			testID := 0
+			t.Logf("\tTest %d:\tWhen handling a user rating a place in sydney.", testID)
+			{
+				ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+				defer cancel()
+
+				newCity := city.City{
+					Name: "sydney",
+					Lat:  -33.865143,
+					Lng:  151.209900,
+				}
+				gql, addedCity := seedCity(t, ctx, testID, tc, newCity)
+
+				newPlace := place.Place{
+					PlaceID:  "12345",
+					Category: "test",
+					City:     place.City{ID: addedCity.ID},
+					CityName: "sydney",
+					Name:     "Bill's SPAM shack",
+				}
+				if _, err := place.NewStore(tc.log, gql).Upsert(ctx, tc.traceID, newPlace); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to add a place: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to add a place.", tests.Success, testID)
+
+				newUser := user.NewUser{
+					Name:            "Bill Kennedy",
+					Email:           "bill@ardanlabs.com",
+					Role:            "USER",
+					Password:        "gophers",
+					PasswordConfirm: "gophers",
+				}
+				if _, err := user.NewStore(tc.log, gql).Add(ctx, tc.traceID, newUser, time.Now()); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to add a user: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to add a user.", tests.Success, testID)
+
+				store := rating.NewStore(tc.log, gql)
+
+				nr := rating.NewRating{
+					PlaceID: newPlace.PlaceID,
+					Stars:   4,
+				}
+				if err := store.Add(ctx, tc.traceID, newUser.Email, nr); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to rate the place: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to rate the place.", tests.Success, testID)
+
+				nr.Stars = 2
+				if err := store.Update(ctx, tc.traceID, newUser.Email, nr); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to update the rating: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to update the rating.", tests.Success, testID)
+
+				ratings, err := store.QueryByUser(ctx, tc.traceID, newUser.Email)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to query the user ratings: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to query the user ratings.", tests.Success, testID)
+
+				exp := []rating.Rating{{PlaceID: newPlace.PlaceID, Name: newPlace.Name, Stars: 2}}
+				if diff := cmp.Diff(exp, ratings); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get back the user ratings. Diff:\n%s", tests.Failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back the user ratings.", tests.Success, testID)
+
+				avg, err := store.QueryAverage(ctx, tc.traceID, newPlace.PlaceID)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to query the average rating: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to query the average rating.", tests.Success, testID)
+
+				if avg.Stars != 2 || avg.NumberOfRatings != 1 {
+					t.Logf("\t\tTest %d:\tgot: %v", testID, avg)
+					t.Fatalf("\t%s\tTest %d:\tShould get back the average rating.", tests.Failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back the average rating.", tests.Success, testID)
+
+				if err := store.Remove(ctx, tc.traceID, newUser.Email, newPlace.PlaceID); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to remove the rating: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to remove the rating.", tests.Success, testID)
+
+				if _, err := store.QueryByUserPlace(ctx, tc.traceID, newUser.Email, newPlace.PlaceID); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould not be able to query the removed rating.", tests.Failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould not be able to query the removed rating.", tests.Success, testID)
+
+				missing := rating.NewRating{
+					PlaceID: "missing",
+					Stars:   4,
+				}
+				if err := store.Add(ctx, tc.traceID, newUser.Email, missing); err != rating.ErrNotExists {
+					t.Fatalf("\t%s\tTest %d:\tShould not be able to rate a place that doesn't exist: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould not be able to rate a place that doesn't exist.", tests.Success, testID)
+			}
+		}
+	}
+	return tf
+}
+
+// manageTokens validates refresh tokens can be rotated, access tokens can be
+// revoked and password reset tokens can only be used once.
+func manageTokens(tc TestConfig) func(t *testing.T) {
+	tf := func(t *testing.T) {
+		t.Log("Given the need to be able to manage refresh tokens and revocations.")
+		{
+			testID := 0
+			t.Logf("\tTest %d:\tWhen handling the tokens for a single user.", testID)
+			{
+				ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+				defer cancel()
+
+				gql := waitReady(t, ctx, testID, tc)
+				store := token.NewStore(tc.log, gql)
+				now := time.Now().Truncate(time.Second)
+				const userID = "0x01"
+
+				refreshToken, err := store.AddRefresh(ctx, tc.traceID, userID, time.Hour, now)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to add a refresh token: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to add a refresh token.", tests.Success, testID)
+
+				newToken, ref, err := store.Rotate(ctx, tc.traceID, refreshToken, time.Hour, now)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to rotate the refresh token: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to rotate the refresh token.", tests.Success, testID)
+
+				if ref.UserID != userID {
+					t.Logf("\t\tTest %d:\tgot: %v", testID, ref.UserID)
+					t.Logf("\t\tTest %d:\texp: %v", testID, userID)
+					t.Fatalf("\t%s\tTest %d:\tShould get back the user of the refresh token.", tests.Failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back the user of the refresh token.", tests.Success, testID)
+
+				if _, _, err := store.Rotate(ctx, tc.traceID, refreshToken, time.Hour, now); err != token.ErrNotFound {
+					t.Fatalf("\t%s\tTest %d:\tShould not be able to reuse a rotated refresh token: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould not be able to reuse a rotated refresh token.", tests.Success, testID)
+
+				if err := store.Revoke(ctx, "00000000-0000-0000-0000-000000000000", "1234", now.Add(time.Hour)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to revoke a token: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to revoke a token.", tests.Success, testID)
+
+				revoked, err := store.IsRevoked(ctx, "00000000-0000-0000-0000-000000000000", "1234", userID, now)
+				if err != nil || !revoked {
+					t.Fatalf("\t%s\tTest %d:\tShould see the token as revoked: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould see the token as revoked.", tests.Success, testID)
+
+				if err := store.RevokeUser(ctx, tc.traceID, userID, now, now.Add(time.Hour)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the user tokens: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to revoke the user tokens.", tests.Success, testID)
+
+				if _, err := store.QueryRefresh(ctx, tc.traceID, newToken); err != token.ErrNotFound {
+					t.Fatalf("\t%s\tTest %d:\tShould have removed the user refresh tokens: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould have removed the user refresh tokens.", tests.Success, testID)
+
+				revoked, err = store.IsRevoked(ctx, "00000000-0000-0000-0000-000000000000", "5678", userID, now.Add(-time.Minute))
+				if err != nil || !revoked {
+					t.Fatalf("\t%s\tTest %d:\tShould see earlier user tokens as revoked: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould see earlier user tokens as revoked.", tests.Success, testID)
+
+				revoked, err = store.IsRevoked(ctx, "00000000-0000-0000-0000-000000000000", "5678", userID, now.Add(time.Minute))
+				if err != nil || revoked {
+					t.Fatalf("\t%s\tTest %d:\tShould not see later user tokens as revoked: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould not see later user tokens as revoked.", tests.Success, testID)
+
+				resetToken, err := store.AddReset(ctx, tc.traceID, userID, time.Hour, now)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to add a reset token: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to add a reset token.", tests.Success, testID)
+
+				rst, err := store.ConsumeReset(ctx, tc.traceID, resetToken, now)
+				if err != nil || rst.UserID != userID {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to consume the reset token: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to consume the reset token.", tests.Success, testID)
+
+				if _, err := store.ConsumeReset(ctx, tc.traceID, resetToken, now); err != token.ErrNotFound {
+					t.Fatalf("\t%s\tTest %d:\tShould not be able to reuse a reset token: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould not be able to reuse a reset token.", tests.Success, testID)
+			}
+		}
+	}
+	return tf
+}
+
+// manageAPIKeys validates api keys can be added, authenticated, listed and
+// revoked.
+func manageAPIKeys(tc TestConfig) func(t *testing.T) {
+	tf := func(t *testing.T) {
+		t.Log("Given the need to be able to manage api keys.")
+		{
+			testID := 0
+			t.Logf("\tTest %d:\tWhen handling a single api key.", testID)
+			{
+				ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+				defer cancel()
+
+				gql := waitReady(t, ctx, testID, tc)
+				store := apikey.NewStore(tc.log, gql)
+				now := time.Now().Truncate(time.Second)
+
+				nak := apikey.NewAPIKey{
+					Name:     "feed loader",
+					Scopes:   []string{auth.ScopeFeedUpload},
+					Lifetime: time.Hour,
+				}
+				key, ak, err := store.Add(ctx, tc.traceID, nak, now)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to add an api key: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to add an api key.", tests.Success, testID)
+
+				authed, err := store.Authenticate(ctx, tc.traceID, key, now)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to authenticate the api key: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to authenticate the api key.", tests.Success, testID)
+
+				if diff := cmp.Diff(nak.Scopes, authed.Scopes); diff != "" {
+					t.Fatalf("\t%s\tTest %d:\tShould get back the scopes of the api key. Diff:\n%s", tests.Failed, testID, diff)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get back the scopes of the api key.", tests.Success, testID)
+
+				if _, err := store.Authenticate(ctx, tc.traceID, key, now.Add(2*time.Hour)); err != apikey.ErrExpired {
+					t.Fatalf("\t%s\tTest %d:\tShould not be able to authenticate an expired api key: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould not be able to authenticate an expired api key.", tests.Success, testID)
+
+				keys, err := store.QueryAll(ctx, tc.traceID)
+				if err != nil || len(keys) != 1 {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to list the api keys: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to list the api keys.", tests.Success, testID)
+
+				if err := store.Delete(ctx, tc.traceID, ak.ID); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the api key: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to revoke the api key.", tests.Success, testID)
+
+				if _, err := store.Authenticate(ctx, tc.traceID, key, now); err != apikey.ErrNotFound {
+					t.Fatalf("\t%s\tTest %d:\tShould not be able to authenticate a revoked api key: %v", tests.Failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould not be able to authenticate a revoked api key.", tests.Success, testID)
+			}
+		}
+	}
+	return tf
+}
+
 func performAuth() func(t *testing.T) {
 	tf := func(t *testing.T) {
 		t.Log("Given the need to be able to authenticate and authorize access.")
@@ -623,7 +1142,7 @@ func performAuth() func(t *testing.T) {
 				}
 				t.Logf("\t%s\tTest %d:\tShould be able to create a private key.", tests.Success, testID)
 
-				a, err := auth.New("RS256", keystore.NewMap(map[string]*rsa.PrivateKey{keyID: privateKey}))
+				a, err := auth.New("RS256", keystore.NewMap(map[string]crypto.Signer{keyID: privateKey}), nil)
 				if err != nil {
 					t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", tests.Failed, testID, err)
 				}
@@ -647,7 +1166,7 @@ func performAuth() func(t *testing.T) {
 				}
 				t.Logf("\t%s\tTest %d:\tShould be able to generate a JWT.", tests.Success, testID)
 
-				parsedClaims, err := a.ValidateToken(token)
+				parsedClaims, err := a.ValidateToken(context.Background(), "00000000-0000-0000-0000-000000000000", token)
 				if err != nil {
 					t.Fatalf("\t%s\tTest %d:\tShould be able to parse the claims: %v", tests.Failed, testID, err)
 				}
@@ -0,0 +1,81 @@
+package data_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/tests"
+)
+
+// TestForwardAuth validates the token of the caller is sent to the database
+// in place of the configured token or the token of the source.
+func TestForwardAuth(t *testing.T) {
+	received := make(chan string, 1)
+	f := func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Travel-Auth")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {}}`))
+	}
+	server := httptest.NewServer(http.HandlerFunc(f))
+	t.Cleanup(server.Close)
+
+	gqlConfig := data.GraphQLConfig{
+		URL:            server.URL,
+		AuthHeaderName: "X-Travel-Auth",
+		AuthToken:      "service",
+	}
+
+	source := func() (string, error) { return "minted", nil }
+
+	tt := []struct {
+		name    string
+		forward bool
+		source  func() (string, error)
+		token   string
+		exp     string
+	}{
+		{"noforward", false, nil, "caller", "service"},
+		{"nocaller", true, nil, "", "service"},
+		{"caller", true, nil, "caller", "caller"},
+		{"source", false, source, "", "minted"},
+		{"sourcenocaller", true, source, "", "minted"},
+		{"sourcecaller", true, source, "caller", "caller"},
+	}
+
+	t.Log("Given the need to forward the token of the caller to the database.")
+	{
+		for testID, test := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen handling %s.", testID, test.name)
+				{
+					cfg := gqlConfig
+					cfg.ForwardAuth = test.forward
+					cfg.AuthTokenSource = test.source
+					gql := data.NewGraphQL(cfg)
+
+					ctx := context.Background()
+					if test.token != "" {
+						ctx = data.WithAuthToken(ctx, test.token)
+					}
+
+					var result struct{}
+					if err := gql.Execute(ctx, `query { queryUser { id } }`, &result); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to execute the query: %v", tests.Failed, testID, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould be able to execute the query.", tests.Success, testID)
+
+					if got := <-received; got != test.exp {
+						t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+						t.Logf("\t\tTest %d:\texp: %v", testID, test.exp)
+						t.Fatalf("\t%s\tTest %d:\tShould send the expected token.", tests.Failed, testID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould send the expected token.", tests.Success, testID)
+				}
+			}
+			t.Run(test.name, tf)
+		}
+	}
+}
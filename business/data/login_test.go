@@ -0,0 +1,82 @@
+package data_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/tests"
+	"github.com/dgraph-io/travel/business/data/user"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestFailedLoginRace validates a failed login is counted on top of the
+// failed logins recorded at the same time.
+func TestFailedLoginRace(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("gophers"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Should be able to hash the password: %v", err)
+	}
+	usr := `{"id": "0x1", "email": "bill@ardanlabs.com", "role": "USER", "password_hash": %q, "failed_logins": %d}`
+
+	updates := make(chan string, 2)
+	f := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case bytes.Contains(body, []byte("queryUser")):
+			fmt.Fprintf(w, `{"data": {"queryUser": [`+usr+`]}}`, hash, 1)
+		case bytes.Contains(body, []byte("getUser")):
+			fmt.Fprintf(w, `{"data": {"getUser": `+usr+`}}`, hash, 2)
+		case bytes.Contains(body, []byte("updateUser")):
+			updates <- string(body)
+			numUids := 1
+			if len(updates) == 1 {
+				numUids = 0
+			}
+			fmt.Fprintf(w, `{"data": {"resp": {"msg": "Updated", "numUids": %d}}}`, numUids)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(f))
+	t.Cleanup(server.Close)
+
+	log, err := logger.New(io.Discard, logger.Config{Service: "TEST"})
+	if err != nil {
+		t.Fatalf("Should be able to construct a logger: %v", err)
+	}
+
+	store := user.NewStore(log, data.NewGraphQL(data.GraphQLConfig{URL: server.URL}))
+	lockout := user.Lockout{Attempts: 5, Duration: time.Hour}
+
+	t.Log("Given the need to count failed logins made at the same time.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen another login changes the count first.", testID)
+		{
+			if _, err := store.Authenticate(context.Background(), "traceid", "bill@ardanlabs.com", "wrong", lockout, time.Now()); err != user.ErrAuthenticationFailure {
+				t.Fatalf("\t%s\tTest %d:\tShould fail the login: %v", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould fail the login.", tests.Success, testID)
+
+			first, second := <-updates, <-updates
+			if !bytes.Contains([]byte(first), []byte("failed_logins: { eq: 1 }")) {
+				t.Logf("\t\tTest %d:\tgot: %v", testID, first)
+				t.Fatalf("\t%s\tTest %d:\tShould only update the count it read.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould only update the count it read.", tests.Success, testID)
+
+			if !bytes.Contains([]byte(second), []byte("failed_logins: { eq: 2 }")) || !bytes.Contains([]byte(second), []byte("failed_logins: 3")) {
+				t.Logf("\t\tTest %d:\tgot: %v", testID, second)
+				t.Fatalf("\t%s\tTest %d:\tShould count the login on top of the new count.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould count the login on top of the new count.", tests.Success, testID)
+		}
+	}
+}
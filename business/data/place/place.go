@@ -4,11 +4,12 @@ package place
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/ardanlabs/graphql"
 	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/pkg/errors"
 )
 
@@ -19,12 +20,12 @@ var (
 
 // Store manages the set of API's for place access.
 type Store struct {
-	log *log.Logger
+	log *logger.Logger
 	gql *graphql.GraphQL
 }
 
 // NewStore constructs a place store for api access.
-func NewStore(log *log.Logger, gql *graphql.GraphQL) Store {
+func NewStore(log *logger.Logger, gql *graphql.GraphQL) Store {
 	return Store{
 		log: log,
 		gql: gql,
@@ -35,6 +36,9 @@ func NewStore(log *log.Logger, gql *graphql.GraphQL) Store {
 // If the place already exists in the database, the function will return an Place
 // value with the existing id.
 func (s Store) Upsert(ctx context.Context, traceID string, plc Place) (Place, error) {
+	ctx, span := tracer.Start(ctx, "place.Upsert")
+	defer span.End()
+
 	if plc.ID != "" {
 		return Place{}, errors.New("place contains id")
 	}
@@ -53,6 +57,9 @@ func (s Store) Upsert(ctx context.Context, traceID string, plc Place) (Place, er
 
 // QueryByID returns the specified place from the database by the place id.
 func (s Store) QueryByID(ctx context.Context, traceID string, placeID string) (Place, error) {
+	ctx, span := tracer.Start(ctx, "place.QueryByID")
+	defer span.End()
+
 	query := fmt.Sprintf(`
 query {
 	getPlace(id: %q) {
@@ -75,7 +82,7 @@ query {
 	}
 }`, placeID)
 
-	s.log.Printf("%s: %s: %s", traceID, "place.QueryByID", data.Log(query))
+	data.LogQuery(s.log, traceID, "place.QueryByID", query)
 
 	var result struct {
 		GetPlace struct {
@@ -95,6 +102,9 @@ query {
 
 // QueryByName returns the specified place from the database by name.
 func (s Store) QueryByName(ctx context.Context, traceID string, name string) (Place, error) {
+	ctx, span := tracer.Start(ctx, "place.QueryByName")
+	defer span.End()
+
 	query := fmt.Sprintf(`
 query {
 	queryPlace(filter: { name: { alloftext: %q } }) {
@@ -117,7 +127,7 @@ query {
 	}
 }`, name)
 
-	s.log.Printf("%s: %s: %s", traceID, "place.QueryByName", data.Log(query))
+	data.LogQuery(s.log, traceID, "place.QueryByName", query)
 
 	var result struct {
 		QueryPlace []Place `json:"queryPlace"`
@@ -136,6 +146,9 @@ query {
 // QueryByCategory returns the collection of places from the database
 // by the cagtegory name.
 func (s Store) QueryByCategory(ctx context.Context, traceID string, category string) ([]Place, error) {
+	ctx, span := tracer.Start(ctx, "place.QueryByCategory")
+	defer span.End()
+
 	query := fmt.Sprintf(`
 query {
 	queryPlace(filter: { category: { eq: %q } }) {
@@ -158,7 +171,7 @@ query {
 	}
 }`, category)
 
-	s.log.Printf("%s: %s: %s", traceID, "place.QueryByCategory", data.Log(query))
+	data.LogQuery(s.log, traceID, "place.QueryByCategory", query)
 
 	var result struct {
 		QueryPlace []Place `json:"queryPlace"`
@@ -176,6 +189,9 @@ query {
 
 // QueryByCity returns the collection of places from the database by the city id.
 func (s Store) QueryByCity(ctx context.Context, traceID string, cityID string) ([]Place, error) {
+	ctx, span := tracer.Start(ctx, "place.QueryByCity")
+	defer span.End()
+
 	query := fmt.Sprintf(`
 query {
 	getCity(id: %q) {
@@ -200,7 +216,7 @@ query {
 	}
 }`, cityID)
 
-	s.log.Printf("%s: %s: %s", traceID, "place.QueryByCity", data.Log(query))
+	data.LogQuery(s.log, traceID, "place.QueryByCity", query)
 
 	var result struct {
 		GetCity struct {
@@ -243,7 +259,7 @@ func (s Store) upsert(ctx context.Context, traceID string, plc Place) (Place, er
 		plc.NumberOfRatings, plc.PlaceID, plc.PhotoReferenceID,
 		result.document())
 
-	s.log.Printf("%s: %s: %s", traceID, "place.Upsert", data.Log(mutation))
+	data.LogQuery(s.log, traceID, "place.Upsert", mutation)
 
 	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
 		return Place{}, errors.Wrap(err, "failed to upsert place")
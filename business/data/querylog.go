@@ -0,0 +1,106 @@
+package data
+
+import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/pkg/errors"
+)
+
+// QueryLog represents how much of each GraphQL document the stores write to
+// the logs.
+type QueryLog int32
+
+// Set of query logging modes. Summary writes the operation and the root
+// field being queried or mutated. Full writes the whole document once it
+// has been redacted.
+const (
+	QueryLogOff QueryLog = iota
+	QueryLogSummary
+	QueryLogFull
+)
+
+// ParseQueryLog returns the query logging mode for the specified name.
+func ParseQueryLog(name string) (QueryLog, error) {
+	switch strings.ToLower(name) {
+	case "off":
+		return QueryLogOff, nil
+	case "summary":
+		return QueryLogSummary, nil
+	case "full":
+		return QueryLogFull, nil
+	}
+	return QueryLogOff, errors.Errorf("unknown query log mode %q", name)
+}
+
+// queryLog holds the mode used by every store. It's set once at startup.
+var queryLog = int32(QueryLogFull)
+
+// SetQueryLog sets how much of each GraphQL document the stores log.
+func SetQueryLog(mode QueryLog) {
+	atomic.StoreInt32(&queryLog, int32(mode))
+}
+
+// LogQuery writes the GraphQL document executed by the specified store
+// function according to the query logging mode. Sensitive values are
+// redacted before anything is written. The document is always written
+// under the query field, whatever the kind of operation.
+func LogQuery(log *logger.Logger, traceID string, fn string, query string) {
+	switch QueryLog(atomic.LoadInt32(&queryLog)) {
+	case QueryLogSummary:
+		op, field := summary(query)
+		log.Info(op, "traceid", traceID, "func", fn, "field", field)
+	case QueryLogFull:
+		op, _ := summary(query)
+		log.Info(op, "traceid", traceID, "func", fn, "query", Redact(Log(query)))
+	}
+}
+
+// Log removes line feeds and tabs for better logging.
+func Log(query string) string {
+	query = strings.Replace(query, "\t", "", -1)
+	query = strings.Replace(query, "\n", " ", -1)
+	return query
+}
+
+// =============================================================================
+
+// redacted replaces the sensitive values in the logs.
+const redacted = `"***"`
+
+var (
+	// sensitiveField matches the string value of a sensitive field, directly
+	// or inside a filter such as { email: { eq: "..." } }.
+	sensitiveField = regexp.MustCompile(`\b(password_hash|password|token_hash|key_hash|token|email)(\s*:\s*(?:\{\s*(?:eq|in)\s*:\s*\[?\s*)?)"(?:[^"\\]|\\.)*"`)
+
+	// emailAddress matches email addresses found anywhere else in the
+	// document, such as in a name.
+	emailAddress = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+	// rootField matches the operation and the first field it selects,
+	// skipping an alias like resp: addUser.
+	rootField = regexp.MustCompile(`^\s*(query|mutation)?\s*\{\s*(?:\w+\s*:\s*)?(\w+)`)
+)
+
+// Redact masks the values of password hashes, tokens and email addresses
+// in the GraphQL document.
+func Redact(query string) string {
+	query = sensitiveField.ReplaceAllString(query, "${1}${2}"+redacted)
+	return emailAddress.ReplaceAllString(query, "***")
+}
+
+// summary returns the kind of operation and the root field it selects.
+func summary(query string) (string, string) {
+	m := rootField.FindStringSubmatch(query)
+	if m == nil {
+		return "query", ""
+	}
+
+	op := m[1]
+	if op == "" {
+		op = "query"
+	}
+	return op, m[2]
+}
@@ -0,0 +1,142 @@
+package data_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/tests"
+	"github.com/dgraph-io/travel/business/data/token"
+	"github.com/dgraph-io/travel/business/data/user"
+	"github.com/dgraph-io/travel/foundation/logger"
+)
+
+// TestQueryLog validates the queries logged by the stores never contain
+// passwords, password hashes, tokens or email addresses.
+func TestQueryLog(t *testing.T) {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if bytes.Contains(body, []byte("queryUser")) {
+			w.Write([]byte(`{"data": {"queryUser": []}}`))
+			return
+		}
+		w.Write([]byte(`{"data": {"resp": {"entities": [{"id": "0x1"}]}}}`))
+	}
+	server := httptest.NewServer(http.HandlerFunc(f))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { data.SetQueryLog(data.QueryLogFull) })
+
+	gql := data.NewGraphQL(data.GraphQLConfig{URL: server.URL})
+
+	tt := []struct {
+		name string
+		mode data.QueryLog
+		exp  string
+	}{
+		{"off", data.QueryLogOff, ""},
+		{"summary", data.QueryLogSummary, `"field":"addUser"`},
+		{"full", data.QueryLogFull, `"query":" mutation { resp: addUser(input: [{`},
+	}
+
+	t.Log("Given the need to keep secrets out of the logs.")
+	{
+		for testID, test := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen logging queries in %s mode.", testID, test.name)
+				{
+					var buf bytes.Buffer
+					log, err := logger.New(&buf, logger.Config{Service: "TEST"})
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to construct a logger: %v", tests.Failed, testID, err)
+					}
+					data.SetQueryLog(test.mode)
+
+					ctx := context.Background()
+					now := time.Now()
+
+					nu := user.NewUser{
+						Name:            "Bill Kennedy",
+						Email:           "bill@ardanlabs.com",
+						Role:            "ADMIN",
+						Password:        "gophers123",
+						PasswordConfirm: "gophers123",
+					}
+					if _, err := user.NewStore(log, gql).Add(ctx, "traceid", nu, now); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to add a user: %v", tests.Failed, testID, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould be able to add a user.", tests.Success, testID)
+
+					tkn, err := token.NewStore(log, gql).AddRefresh(ctx, "traceid", "0x1", time.Hour, now)
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to add a refresh token: %v", tests.Failed, testID, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould be able to add a refresh token.", tests.Success, testID)
+
+					sum := sha256.Sum256([]byte(tkn))
+					secrets := []string{nu.Email, nu.Password, "$2a$", tkn, hex.EncodeToString(sum[:])}
+
+					got := buf.String()
+					for _, secret := range secrets {
+						if strings.Contains(got, secret) {
+							t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+							t.Fatalf("\t%s\tTest %d:\tShould not log the secret %q.", tests.Failed, testID, secret)
+						}
+					}
+					t.Logf("\t%s\tTest %d:\tShould not log any secret.", tests.Success, testID)
+
+					if test.exp == "" && got != "" || !strings.Contains(got, test.exp) {
+						t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+						t.Logf("\t\tTest %d:\texp: %v", testID, test.exp)
+						t.Fatalf("\t%s\tTest %d:\tShould log the queries for the mode.", tests.Failed, testID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould log the queries for the mode.", tests.Success, testID)
+				}
+			}
+			t.Run(test.name, tf)
+		}
+	}
+}
+
+// TestRedact validates sensitive values are masked in GraphQL documents.
+func TestRedact(t *testing.T) {
+	tt := []struct {
+		name  string
+		query string
+		exp   string
+	}{
+		{"field", `addUser(input: [{ name: "Bill" password_hash: "$2a$10$abc" }])`, `addUser(input: [{ name: "Bill" password_hash: "***" }])`},
+		{"filter", `queryUser(filter: { email: { eq: "bill@ardanlabs.com" } })`, `queryUser(filter: { email: { eq: "***" } })`},
+		{"argument", `getRefreshToken(token_hash: "5e884898da28") { id }`, `getRefreshToken(token_hash: "***") { id }`},
+		{"escaped", `addApiKey(input: [{ key_hash: "a\"b" name: "ci" }])`, `addApiKey(input: [{ key_hash: "***" name: "ci" }])`},
+		{"address", `addUser(input: [{ name: "jill@ardanlabs.com" }])`, `addUser(input: [{ name: "***" }])`},
+		{"selection", `queryUser { id email password_hash }`, `queryUser { id email password_hash }`},
+	}
+
+	t.Log("Given the need to redact sensitive values from queries.")
+	{
+		for testID, test := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen handling a %s.", testID, test.name)
+				{
+					got := data.Redact(test.query)
+					if got != test.exp {
+						t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+						t.Logf("\t\tTest %d:\texp: %v", testID, test.exp)
+						t.Fatalf("\t%s\tTest %d:\tShould redact the sensitive values.", tests.Failed, testID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould redact the sensitive values.", tests.Success, testID)
+				}
+			}
+			t.Run(test.name, tf)
+		}
+	}
+}
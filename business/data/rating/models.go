@@ -0,0 +1,56 @@
+package rating
+
+import "github.com/dgraph-io/travel/business/sys/validate"
+
+// Rating represents the stars a user gave to a place they visited.
+type Rating struct {
+	PlaceID string `json:"place_id"`
+	Name    string `json:"name"`
+	Stars   int    `json:"stars"`
+}
+
+// NewRating contains information needed to rate a place.
+type NewRating struct {
+	PlaceID string `json:"place_id" validate:"required"`
+	Stars   int    `json:"stars" validate:"required,min=1,max=5"`
+}
+
+// Validate checks the fields of the new rating against their tags.
+func (nr NewRating) Validate() error {
+	return validate.Check(nr)
+}
+
+// UpdateRating contains information needed to change the rating of a place.
+// The place is identified by the caller.
+type UpdateRating struct {
+	Stars int `json:"stars" validate:"required,min=1,max=5"`
+}
+
+// Validate checks the fields of the rating update against their tags.
+func (ur UpdateRating) Validate() error {
+	return validate.Check(ur)
+}
+
+// Average represents the community rating for a place.
+type Average struct {
+	PlaceID         string  `json:"place_id"`
+	Name            string  `json:"name"`
+	Stars           float64 `json:"avg_stars"`
+	NumberOfRatings int     `json:"no_ratings"`
+}
+
+// =============================================================================
+
+type result struct {
+	Resp struct {
+		Msg     string
+		NumUids int
+	} `json:"resp"`
+}
+
+func (result) document() string {
+	return `{
+		msg,
+		numUids,
+	}`
+}
@@ -0,0 +1,253 @@
+// Package rating provides support for managing user ratings of places
+// in the database.
+package rating
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ardanlabs/graphql"
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/sys/validate"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/tracer"
+	"github.com/pkg/errors"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound  = errors.New("rating not found")
+	ErrNotExists = errors.New("user or place does not exist")
+)
+
+// Store manages the set of API's for rating access.
+type Store struct {
+	log *logger.Logger
+	gql *graphql.GraphQL
+}
+
+// NewStore constructs a rating store for api access.
+func NewStore(log *logger.Logger, gql *graphql.GraphQL) Store {
+	return Store{
+		log: log,
+		gql: gql,
+	}
+}
+
+// Add records the user's rating for a place. The place is added to the
+// places the user has visited. If the user or place doesn't exist, this
+// function will fail.
+func (s Store) Add(ctx context.Context, traceID string, email string, nr NewRating) error {
+	ctx, span := tracer.Start(ctx, "rating.Add")
+	defer span.End()
+
+	if err := validate.Check(nr); err != nil {
+		return errors.Wrap(err, "validating data")
+	}
+
+	return s.rate(ctx, traceID, email, nr)
+}
+
+// Update changes the user's existing rating for a place. If the user has
+// not rated the place, this function will fail.
+func (s Store) Update(ctx context.Context, traceID string, email string, nr NewRating) error {
+	ctx, span := tracer.Start(ctx, "rating.Update")
+	defer span.End()
+
+	if err := validate.Check(nr); err != nil {
+		return errors.Wrap(err, "validating data")
+	}
+
+	if _, err := s.QueryByUserPlace(ctx, traceID, email, nr.PlaceID); err != nil {
+		return err
+	}
+
+	return s.rate(ctx, traceID, email, nr)
+}
+
+// Remove removes the user's rating for a place. The place is no longer
+// one of the places the user has visited.
+func (s Store) Remove(ctx context.Context, traceID string, email string, placeID string) error {
+	ctx, span := tracer.Start(ctx, "rating.Remove")
+	defer span.End()
+
+	if _, err := s.QueryByUserPlace(ctx, traceID, email, placeID); err != nil {
+		return err
+	}
+
+	var result result
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: updateUser(input: {
+			filter: {
+				email: { eq: %q }
+			},
+			remove: {
+				visited: [{ place_id: %q }]
+			}
+		})
+		%s
+	}`, email, placeID, result.document())
+
+	data.LogQuery(s.log, traceID, "rating.Remove", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return errors.Wrap(err, "failed to remove rating")
+	}
+
+	if result.Resp.NumUids != 1 {
+		msg := fmt.Sprintf("failed to remove rating: NumUids: %d  Msg: %s", result.Resp.NumUids, result.Resp.Msg)
+		return errors.New(msg)
+	}
+
+	return nil
+}
+
+// QueryByUser returns the places the user has visited with their stars.
+func (s Store) QueryByUser(ctx context.Context, traceID string, email string) ([]Rating, error) {
+	ctx, span := tracer.Start(ctx, "rating.QueryByUser")
+	defer span.End()
+
+	query := fmt.Sprintf(`
+query {
+	queryUserRatings(email: %q) {
+		place_id
+		name
+		stars
+	}
+}`, email)
+
+	data.LogQuery(s.log, traceID, "rating.QueryByUser", query)
+
+	var result struct {
+		QueryUserRatings []Rating `json:"queryUserRatings"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return nil, errors.Wrap(err, "query failed")
+	}
+
+	return result.QueryUserRatings, nil
+}
+
+// QueryByUserPlace returns the user's rating for the specified place.
+func (s Store) QueryByUserPlace(ctx context.Context, traceID string, email string, placeID string) (Rating, error) {
+	ctx, span := tracer.Start(ctx, "rating.QueryByUserPlace")
+	defer span.End()
+
+	ratings, err := s.QueryByUser(ctx, traceID, email)
+	if err != nil {
+		return Rating{}, err
+	}
+
+	for _, rtg := range ratings {
+		if rtg.PlaceID == placeID {
+			return rtg, nil
+		}
+	}
+
+	return Rating{}, ErrNotFound
+}
+
+// QueryAverage returns the average community rating for the specified place.
+func (s Store) QueryAverage(ctx context.Context, traceID string, placeID string) (Average, error) {
+	ctx, span := tracer.Start(ctx, "rating.QueryAverage")
+	defer span.End()
+
+	query := fmt.Sprintf(`
+query {
+	queryPlaceRatings(placeId: %q) {
+		place_id
+		name
+		stars
+	}
+}`, placeID)
+
+	data.LogQuery(s.log, traceID, "rating.QueryAverage", query)
+
+	var result struct {
+		QueryPlaceRatings []Rating `json:"queryPlaceRatings"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return Average{}, errors.Wrap(err, "query failed")
+	}
+
+	if len(result.QueryPlaceRatings) == 0 {
+		return Average{}, ErrNotFound
+	}
+
+	avg := Average{
+		PlaceID:         placeID,
+		Name:            result.QueryPlaceRatings[0].Name,
+		NumberOfRatings: len(result.QueryPlaceRatings),
+	}
+
+	var total int
+	for _, rtg := range result.QueryPlaceRatings {
+		total += rtg.Stars
+	}
+	avg.Stars = float64(total) / float64(avg.NumberOfRatings)
+
+	return avg, nil
+}
+
+// =============================================================================
+
+// rate stores the rating of the user for the place. The upsert storing it
+// silently does nothing when the user or place is missing so they are
+// looked up first.
+func (s Store) rate(ctx context.Context, traceID string, email string, nr NewRating) error {
+	if err := s.exists(ctx, traceID, email, nr.PlaceID); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+query {
+	addUserRating(email: %q, placeId: %q, stars: %d) {
+		data {
+			code
+			message
+		}
+	}
+}`, email, nr.PlaceID, nr.Stars)
+
+	data.LogQuery(s.log, traceID, "rating.Rate", query)
+
+	if err := s.gql.Execute(ctx, query, nil); err != nil {
+		return errors.Wrap(err, "failed to rate place")
+	}
+
+	return nil
+}
+
+// exists checks the user and place exist.
+func (s Store) exists(ctx context.Context, traceID string, email string, placeID string) error {
+	query := fmt.Sprintf(`
+query {
+	queryUser(filter: { email: { eq: %q } }) {
+		id
+	}
+	getPlace(place_id: %q) {
+		id
+	}
+}`, email, placeID)
+
+	data.LogQuery(s.log, traceID, "rating.Exists", query)
+
+	var result struct {
+		QueryUser []struct {
+			ID string `json:"id"`
+		} `json:"queryUser"`
+		GetPlace struct {
+			ID string `json:"id"`
+		} `json:"getPlace"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return errors.Wrap(err, "query failed")
+	}
+
+	if len(result.QueryUser) != 1 || result.GetPlace.ID == "" {
+		return ErrNotExists
+	}
+
+	return nil
+}
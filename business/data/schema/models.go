@@ -1,11 +1,18 @@
 package schema
 
+import "github.com/dgraph-io/travel/business/sys/validate"
+
 // UploadFeedRequest is the data required to make a feed/upload request.
 type UploadFeedRequest struct {
-	CountryCode string  `json:"countrycode"`
-	CityName    string  `json:"cityname"`
-	Lat         float64 `json:"lat"`
-	Lng         float64 `json:"lng"`
+	CountryCode string  `json:"countrycode" validate:"required"`
+	CityName    string  `json:"cityname" validate:"required"`
+	Lat         float64 `json:"lat" validate:"min=-90,max=90"`
+	Lng         float64 `json:"lng" validate:"min=-180,max=180"`
+}
+
+// Validate checks the fields of the request against their tags.
+func (ufr UploadFeedRequest) Validate() error {
+	return validate.Check(ufr)
 }
 
 // UploadFeedResponse is the response from the feed/upload request.
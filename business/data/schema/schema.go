@@ -35,9 +35,12 @@ type CustomFunctions struct {
 	UploadFeedURL string
 }
 
-// Config contains information required for the schema document.
+// Config contains information required for the schema document. When a
+// JWKURL is provided, Dgraph fetches the keys to verify tokens from that url
+// instead of using the embedded public key.
 type Config struct {
 	CustomFunctions
+	JWKURL string
 }
 
 // Schema provides support for schema operations against the database.
@@ -63,6 +66,7 @@ func New(graphql *graphql.GraphQL, config Config) (*Schema, error) {
 	vars := map[string]interface{}{
 		"UploadFeedURL": config.CustomFunctions.UploadFeedURL,
 		"PublicKey":     publicKey,
+		"JWKURL":        config.JWKURL,
 	}
 	if err := tmpl.Execute(&document, vars); err != nil {
 		return nil, errors.Wrap(err, "executing template")
@@ -5,11 +5,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"testing"
 
 	"github.com/dgraph-io/travel/foundation/docker"
+	"github.com/dgraph-io/travel/foundation/logger"
 )
 
 // Success and failure markers.
@@ -26,7 +26,7 @@ const (
 
 // NewUnit creates a test value with necessary application state to run
 // database tests. It will return the host to use to connect to the database.
-func NewUnit(t *testing.T) (*log.Logger, string, func()) {
+func NewUnit(t *testing.T) (*logger.Logger, string, func()) {
 	r, w, _ := os.Pipe()
 	old := os.Stdout
 	os.Stdout = w
@@ -50,7 +50,10 @@ func NewUnit(t *testing.T) (*log.Logger, string, func()) {
 	}
 
 	url := fmt.Sprintf("http://%s", c.Host)
-	log := log.New(os.Stdout, "TEST : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
+	log, err := logger.New(os.Stdout, logger.Config{Service: "TEST", Level: logger.LevelDebug, Format: logger.FormatConsole})
+	if err != nil {
+		t.Fatalf("creating logger: %v", err)
+	}
 
 	return log, url, teardown
 }
@@ -0,0 +1,63 @@
+package token
+
+import "time"
+
+// Refresh represents a refresh token issued to a user. Only the hash of the
+// token is stored so a copy of the database can't be used to mint tokens.
+type Refresh struct {
+	ID          string    `json:"id"`
+	TokenHash   string    `json:"token_hash"`
+	UserID      string    `json:"user_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// Reset represents a token mailed to a user to reset a forgotten password.
+// Like refresh tokens, only the hash of the token is stored.
+type Reset struct {
+	ID        string    `json:"id"`
+	TokenHash string    `json:"token_hash"`
+	UserID    string    `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Revocation represents a revoked access token or the revocation of every
+// access token issued to a user before a point in time.
+type Revocation struct {
+	ID        string    `json:"id"`
+	Key       string    `json:"key"`
+	RevokedAt time.Time `json:"revoked_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// =============================================================================
+
+type id struct {
+	Resp struct {
+		Entities []struct {
+			ID string `json:"id"`
+		} `json:"entities"`
+	} `json:"resp"`
+}
+
+func (id) document(entity string) string {
+	return `{
+		entities: ` + entity + ` {
+			id
+		}
+	}`
+}
+
+type result struct {
+	Resp struct {
+		Msg     string
+		NumUids int
+	} `json:"resp"`
+}
+
+func (result) document() string {
+	return `{
+		msg,
+		numUids,
+	}`
+}
@@ -0,0 +1,550 @@
+// Package token provides support for managing refresh tokens, password reset
+// tokens and access token revocations in the database.
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/graphql"
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/tracer"
+	"github.com/pkg/errors"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound = errors.New("token not found")
+	ErrExpired  = errors.New("token has expired")
+)
+
+// Store manages the set of API's for token access.
+type Store struct {
+	log *logger.Logger
+	gql *graphql.GraphQL
+}
+
+// NewStore constructs a token store for api access.
+func NewStore(log *logger.Logger, gql *graphql.GraphQL) Store {
+	return Store{
+		log: log,
+		gql: gql,
+	}
+}
+
+// AddRefresh generates a new refresh token for the specified user which
+// expires after the specified lifetime. The token is returned to be handed
+// to the user and only its hash is stored.
+func (s Store) AddRefresh(ctx context.Context, traceID string, userID string, lifetime time.Duration, now time.Time) (string, error) {
+	ctx, span := tracer.Start(ctx, "token.AddRefresh")
+	defer span.End()
+
+	if userID == "" {
+		return "", errors.New("userid not provided")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generating refresh token")
+	}
+	tkn := base64.RawURLEncoding.EncodeToString(b)
+
+	ref := Refresh{
+		TokenHash:   hash(tkn),
+		UserID:      userID,
+		ExpiresAt:   now.Add(lifetime),
+		DateCreated: now,
+	}
+
+	if err := s.addRefresh(ctx, traceID, ref); err != nil {
+		return "", err
+	}
+
+	return tkn, nil
+}
+
+// Rotate exchanges a refresh token for a new one. The provided refresh token
+// can't be used again. When the token is used twice at the same time, every
+// refresh token of the user is deleted and ErrNotFound is returned. The new token is returned with the stored refresh
+// information of the provided token so the caller knows who it belongs to.
+func (s Store) Rotate(ctx context.Context, traceID string, tkn string, lifetime time.Duration, now time.Time) (string, Refresh, error) {
+	ctx, span := tracer.Start(ctx, "token.Rotate")
+	defer span.End()
+
+	ref, err := s.QueryRefresh(ctx, traceID, tkn)
+	if err != nil {
+		return "", Refresh{}, err
+	}
+
+	// Only one caller can delete the token. A caller that finds it deleted
+	// is replaying it, so every refresh token of the user is deleted since
+	// the token may have been stolen.
+	n, err := s.deleteRefresh(ctx, traceID, fmt.Sprintf(`{ token_hash: { eq: %q } }`, ref.TokenHash))
+	if err != nil {
+		return "", Refresh{}, err
+	}
+	if n != 1 {
+		if _, err := s.deleteRefresh(ctx, traceID, fmt.Sprintf(`{ user_id: { eq: %q } }`, ref.UserID)); err != nil {
+			return "", Refresh{}, errors.Wrap(err, "revoking reused token")
+		}
+		return "", Refresh{}, ErrNotFound
+	}
+
+	if now.After(ref.ExpiresAt) {
+		return "", Refresh{}, ErrExpired
+	}
+
+	newTkn, err := s.AddRefresh(ctx, traceID, ref.UserID, lifetime, now)
+	if err != nil {
+		return "", Refresh{}, err
+	}
+
+	return newTkn, ref, nil
+}
+
+// DeleteRefresh removes the specified refresh token from the database.
+func (s Store) DeleteRefresh(ctx context.Context, traceID string, tkn string) error {
+	ctx, span := tracer.Start(ctx, "token.DeleteRefresh")
+	defer span.End()
+
+	n, err := s.deleteRefresh(ctx, traceID, fmt.Sprintf(`{ token_hash: { eq: %q } }`, hash(tkn)))
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// QueryRefresh returns the stored information for the specified refresh token.
+func (s Store) QueryRefresh(ctx context.Context, traceID string, tkn string) (Refresh, error) {
+	ctx, span := tracer.Start(ctx, "token.QueryRefresh")
+	defer span.End()
+
+	query := fmt.Sprintf(`
+query {
+	getRefreshToken(token_hash: %q) {
+		id
+		token_hash
+		user_id
+		expires_at
+		date_created
+	}
+}`, hash(tkn))
+
+	data.LogQuery(s.log, traceID, "token.QueryRefresh", query)
+
+	var result struct {
+		GetRefreshToken Refresh `json:"getRefreshToken"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return Refresh{}, errors.Wrap(err, "query failed")
+	}
+
+	if result.GetRefreshToken.ID == "" {
+		return Refresh{}, ErrNotFound
+	}
+
+	return result.GetRefreshToken, nil
+}
+
+// AddReset generates a new password reset token for the specified user which
+// expires after the specified lifetime. Any reset token previously issued to
+// the user can no longer be used.
+func (s Store) AddReset(ctx context.Context, traceID string, userID string, lifetime time.Duration, now time.Time) (string, error) {
+	ctx, span := tracer.Start(ctx, "token.AddReset")
+	defer span.End()
+
+	if userID == "" {
+		return "", errors.New("userid not provided")
+	}
+
+	if _, err := s.deleteReset(ctx, traceID, fmt.Sprintf(`{ user_id: { eq: %q } }`, userID)); err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generating reset token")
+	}
+	tkn := base64.RawURLEncoding.EncodeToString(b)
+
+	rst := Reset{
+		TokenHash: hash(tkn),
+		UserID:    userID,
+		ExpiresAt: now.Add(lifetime),
+	}
+
+	var result id
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: addPasswordReset(input: [{
+			token_hash: %q
+			user_id: %q
+			expires_at: %q
+		}])
+		%s
+	}`, rst.TokenHash, rst.UserID,
+		rst.ExpiresAt.UTC().Format(time.RFC3339),
+		result.document("passwordReset"))
+
+	data.LogQuery(s.log, traceID, "token.AddReset", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return "", errors.Wrap(err, "failed to add reset token")
+	}
+
+	if len(result.Resp.Entities) != 1 {
+		return "", errors.New("reset token id not returned")
+	}
+
+	return tkn, nil
+}
+
+// ConsumeReset exchanges a password reset token for the stored reset
+// information so the caller knows whose password to reset. The token can't
+// be used again.
+func (s Store) ConsumeReset(ctx context.Context, traceID string, tkn string, now time.Time) (Reset, error) {
+	ctx, span := tracer.Start(ctx, "token.ConsumeReset")
+	defer span.End()
+
+	query := fmt.Sprintf(`
+query {
+	getPasswordReset(token_hash: %q) {
+		id
+		token_hash
+		user_id
+		expires_at
+	}
+}`, hash(tkn))
+
+	data.LogQuery(s.log, traceID, "token.ConsumeReset", query)
+
+	var result struct {
+		GetPasswordReset Reset `json:"getPasswordReset"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return Reset{}, errors.Wrap(err, "query failed")
+	}
+
+	rst := result.GetPasswordReset
+	if rst.ID == "" {
+		return Reset{}, ErrNotFound
+	}
+
+	// Only one caller can delete the token so it can't be used twice at the
+	// same time.
+	n, err := s.deleteReset(ctx, traceID, fmt.Sprintf(`{ token_hash: { eq: %q } }`, rst.TokenHash))
+	if err != nil {
+		return Reset{}, err
+	}
+	if n != 1 {
+		return Reset{}, ErrNotFound
+	}
+
+	if now.After(rst.ExpiresAt) {
+		return Reset{}, ErrExpired
+	}
+
+	return rst, nil
+}
+
+// Revoke implements the auth.RevocationList interface. It revokes the access
+// token with the specified id until the token expires.
+func (s Store) Revoke(ctx context.Context, traceID string, jti string, expiresAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "token.Revoke")
+	defer span.End()
+
+	if jti == "" {
+		return errors.New("token id not provided")
+	}
+
+	rev := Revocation{
+		Key:       "jti:" + jti,
+		RevokedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+
+	return s.revoke(ctx, traceID, rev)
+}
+
+// RevokeSubject implements the auth.RevocationList interface. It revokes every
+// access token issued to the subject at or before the specified time. The
+// revocation is kept until the specified expiration.
+func (s Store) RevokeSubject(ctx context.Context, traceID string, subject string, before time.Time, expiresAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "token.RevokeSubject")
+	defer span.End()
+
+	if subject == "" {
+		return errors.New("subject not provided")
+	}
+
+	rev := Revocation{
+		Key:       "sub:" + subject,
+		RevokedAt: before,
+		ExpiresAt: expiresAt,
+	}
+
+	return s.revoke(ctx, traceID, rev)
+}
+
+// DeleteUserRefresh removes all of the refresh tokens for the specified user.
+func (s Store) DeleteUserRefresh(ctx context.Context, traceID string, userID string) error {
+	ctx, span := tracer.Start(ctx, "token.DeleteUserRefresh")
+	defer span.End()
+
+	if userID == "" {
+		return errors.New("userid not provided")
+	}
+
+	if _, err := s.deleteRefresh(ctx, traceID, fmt.Sprintf(`{ user_id: { eq: %q } }`, userID)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeUser removes all of the refresh tokens for the specified user and
+// revokes every access token issued to the user at or before the specified
+// time. The revocation is kept until the specified expiration.
+func (s Store) RevokeUser(ctx context.Context, traceID string, userID string, before time.Time, expiresAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "token.RevokeUser")
+	defer span.End()
+
+	if err := s.DeleteUserRefresh(ctx, traceID, userID); err != nil {
+		return err
+	}
+
+	rev := Revocation{
+		Key:       "sub:" + userID,
+		RevokedAt: before,
+		ExpiresAt: expiresAt,
+	}
+
+	return s.revoke(ctx, traceID, rev)
+}
+
+// IsRevoked implements the auth.RevocationList interface. It reports if the
+// access token with the specified id was revoked or if every token issued to
+// the subject at or before the specified time was revoked.
+func (s Store) IsRevoked(ctx context.Context, traceID string, jti string, subject string, issuedAt time.Time) (bool, error) {
+	ctx, span := tracer.Start(ctx, "token.IsRevoked")
+	defer span.End()
+
+	keys := []string{fmt.Sprintf("%q", sentinelKey), fmt.Sprintf("%q", "sub:"+subject)}
+	if jti != "" {
+		keys = append(keys, fmt.Sprintf("%q", "jti:"+jti))
+	}
+
+	query := fmt.Sprintf(`
+query {
+	queryRevocation(filter: { key: { in: [%s] } }) {
+		key
+		revoked_at
+	}
+}`, strings.Join(keys, ", "))
+
+	data.LogQuery(s.log, traceID, "token.IsRevoked", query)
+
+	var result struct {
+		QueryRevocation []Revocation `json:"queryRevocation"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return false, errors.Wrap(err, "query failed")
+	}
+
+	// Revocations are only visible to admins so an empty result may just
+	// mean the query ran without those rights. The sentinel is always
+	// stored, so when it's missing the result can't be trusted unless the
+	// sentinel can be stored now, which also needs those rights.
+	var sentinel bool
+	for _, rev := range result.QueryRevocation {
+		if rev.Key == sentinelKey {
+			sentinel = true
+			break
+		}
+	}
+	if !sentinel {
+		if err := s.addSentinel(ctx, traceID); err != nil {
+			return false, errors.Wrap(err, "revocations can't be trusted")
+		}
+	}
+
+	for _, rev := range result.QueryRevocation {
+		if rev.Key == sentinelKey {
+			continue
+		}
+		if strings.HasPrefix(rev.Key, "jti:") {
+			return true, nil
+		}
+		if !issuedAt.After(rev.RevokedAt) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Prune removes the revocations, refresh and reset tokens that expired before the
+// specified time since they can no longer be used.
+func (s Store) Prune(ctx context.Context, traceID string, now time.Time) error {
+	ctx, span := tracer.Start(ctx, "token.Prune")
+	defer span.End()
+
+	before := now.UTC().Format(time.RFC3339)
+
+	if _, err := s.deleteRefresh(ctx, traceID, fmt.Sprintf(`{ expires_at: { lt: %q } }`, before)); err != nil {
+		return err
+	}
+
+	if _, err := s.deleteReset(ctx, traceID, fmt.Sprintf(`{ expires_at: { lt: %q } }`, before)); err != nil {
+		return err
+	}
+
+	var result result
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: deleteRevocation(filter: { expires_at: { lt: %q } })
+		%s
+	}`, before, result.document())
+
+	data.LogQuery(s.log, traceID, "token.Prune", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return errors.Wrap(err, "failed to prune revocations")
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// sentinelKey is the key of the revocation that is always stored so lookups
+// can tell an empty revocation list from one they aren't allowed to read.
+const sentinelKey = "sentinel:revocations"
+
+// addSentinel stores the sentinel revocation. It never expires so it isn't
+// pruned.
+func (s Store) addSentinel(ctx context.Context, traceID string) error {
+	rev := Revocation{
+		Key:       sentinelKey,
+		RevokedAt: time.Now(),
+		ExpiresAt: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	return s.revoke(ctx, traceID, rev)
+}
+
+// hash returns the value stored in the database for a refresh token.
+func hash(tkn string) string {
+	sum := sha256.Sum256([]byte(tkn))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s Store) addRefresh(ctx context.Context, traceID string, ref Refresh) error {
+	var result id
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: addRefreshToken(input: [{
+			token_hash: %q
+			user_id: %q
+			expires_at: %q
+			date_created: %q
+		}])
+		%s
+	}`, ref.TokenHash, ref.UserID,
+		ref.ExpiresAt.UTC().Format(time.RFC3339),
+		ref.DateCreated.UTC().Format(time.RFC3339),
+		result.document("refreshToken"))
+
+	data.LogQuery(s.log, traceID, "token.AddRefresh", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return errors.Wrap(err, "failed to add refresh token")
+	}
+
+	if len(result.Resp.Entities) != 1 {
+		return errors.New("refresh token id not returned")
+	}
+
+	return nil
+}
+
+// deleteRefresh removes the refresh tokens matching the filter and returns how
+// many were removed.
+func (s Store) deleteRefresh(ctx context.Context, traceID string, filter string) (int, error) {
+	var result result
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: deleteRefreshToken(filter: %s)
+		%s
+	}`, filter, result.document())
+
+	data.LogQuery(s.log, traceID, "token.DeleteRefresh", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return 0, errors.Wrap(err, "failed to delete refresh token")
+	}
+
+	return result.Resp.NumUids, nil
+}
+
+// deleteReset removes the reset tokens matching the filter and returns how
+// many were removed.
+func (s Store) deleteReset(ctx context.Context, traceID string, filter string) (int, error) {
+	var result result
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: deletePasswordReset(filter: %s)
+		%s
+	}`, filter, result.document())
+
+	data.LogQuery(s.log, traceID, "token.DeleteReset", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return 0, errors.Wrap(err, "failed to delete reset token")
+	}
+
+	return result.Resp.NumUids, nil
+}
+
+// revoke stores the revocation, replacing any existing revocation with the
+// same key in a single upsert so the key is never left without a revocation.
+// The time of the revocation keeps its fractional seconds so tokens issued
+// right after it aren't revoked.
+func (s Store) revoke(ctx context.Context, traceID string, rev Revocation) error {
+	var result id
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: addRevocation(input: [{
+			key: %q
+			revoked_at: %q
+			expires_at: %q
+		}], upsert: true)
+		%s
+	}`, rev.Key,
+		rev.RevokedAt.UTC().Format(time.RFC3339Nano),
+		rev.ExpiresAt.UTC().Format(time.RFC3339),
+		result.document("revocation"))
+
+	data.LogQuery(s.log, traceID, "token.Revoke", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return errors.Wrap(err, "failed to upsert revocation")
+	}
+
+	if len(result.Resp.Entities) != 1 {
+		return errors.New("revocation id not returned")
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package data_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/data/tests"
+	"github.com/dgraph-io/travel/business/data/token"
+	"github.com/dgraph-io/travel/foundation/logger"
+)
+
+// TestRevocationSentinel validates revocation lookups fail closed when the
+// revocation list can't be read.
+func TestRevocationSentinel(t *testing.T) {
+	tt := []struct {
+		name     string
+		query    string
+		canWrite bool
+		fail     bool
+	}{
+		{"sentinel", `{"data": {"queryRevocation": [{"key": "sentinel:revocations"}]}}`, false, false},
+		{"nosentinel", `{"data": {"queryRevocation": []}}`, true, false},
+		{"noadmin", `{"data": {"queryRevocation": []}}`, false, true},
+	}
+
+	log, err := logger.New(io.Discard, logger.Config{Service: "TEST"})
+	if err != nil {
+		t.Fatalf("Should be able to construct a logger: %v", err)
+	}
+
+	t.Log("Given the need to trust the revocation list.")
+	{
+		for testID, test := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen the revocation list is read in the %s case.", testID, test.name)
+				{
+					f := func(w http.ResponseWriter, r *http.Request) {
+						body, _ := io.ReadAll(r.Body)
+						w.Header().Set("Content-Type", "application/json")
+						switch {
+						case bytes.Contains(body, []byte("queryRevocation")):
+							w.Write([]byte(test.query))
+						case test.canWrite:
+							w.Write([]byte(`{"data": {"resp": {"msg": "Deleted", "numUids": 1, "entities": [{"id": "0x1"}]}}}`))
+						default:
+							w.Write([]byte(`{"errors": [{"message": "mutation failed because authorization failed"}]}`))
+						}
+					}
+					server := httptest.NewServer(http.HandlerFunc(f))
+					defer server.Close()
+
+					store := token.NewStore(log, data.NewGraphQL(data.GraphQLConfig{URL: server.URL}))
+
+					revoked, err := store.IsRevoked(context.Background(), "00000000-0000-0000-0000-000000000000", "1234", "0x1", time.Now())
+					if test.fail {
+						if err == nil {
+							t.Fatalf("\t%s\tTest %d:\tShould fail the lookup.", tests.Failed, testID)
+						}
+						t.Logf("\t%s\tTest %d:\tShould fail the lookup.", tests.Success, testID)
+						return
+					}
+
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to check the revocation: %v", tests.Failed, testID, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould be able to check the revocation.", tests.Success, testID)
+
+					if revoked {
+						t.Fatalf("\t%s\tTest %d:\tShould not report the token as revoked.", tests.Failed, testID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould not report the token as revoked.", tests.Success, testID)
+				}
+			}
+			t.Run(test.name, tf)
+		}
+	}
+}
+
+// TestRefreshReuse validates a refresh token used twice at the same time
+// revokes every refresh token of the user.
+func TestRefreshReuse(t *testing.T) {
+	deleted := make(chan string, 2)
+	f := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case bytes.Contains(body, []byte("getRefreshToken")):
+			w.Write([]byte(`{"data": {"getRefreshToken": {"id": "0x2", "token_hash": "hash", "user_id": "0x1", "expires_at": "2999-01-01T00:00:00Z"}}}`))
+		case bytes.Contains(body, []byte("deleteRefreshToken")):
+			deleted <- string(body)
+			w.Write([]byte(`{"data": {"resp": {"msg": "Deleted", "numUids": 0}}}`))
+		default:
+			w.Write([]byte(`{"data": {"resp": {"entities": [{"id": "0x3"}]}}}`))
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(f))
+	t.Cleanup(server.Close)
+
+	log, err := logger.New(io.Discard, logger.Config{Service: "TEST"})
+	if err != nil {
+		t.Fatalf("Should be able to construct a logger: %v", err)
+	}
+
+	store := token.NewStore(log, data.NewGraphQL(data.GraphQLConfig{URL: server.URL}))
+
+	t.Log("Given the need to detect refresh tokens being replayed.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the token was deleted by another caller.", testID)
+		{
+			if _, _, err := store.Rotate(context.Background(), "traceid", "token", time.Hour, time.Now()); err != token.ErrNotFound {
+				t.Fatalf("\t%s\tTest %d:\tShould not be able to rotate the token: %v", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not be able to rotate the token.", tests.Success, testID)
+
+			<-deleted
+			if got := <-deleted; !bytes.Contains([]byte(got), []byte(`user_id: { eq: \"0x1\" }`)) {
+				t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+				t.Fatalf("\t%s\tTest %d:\tShould delete every refresh token of the user.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould delete every refresh token of the user.", tests.Success, testID)
+		}
+	}
+}
+
+// TestRevokeUpsert validates a revocation replaces the previous one for the
+// same key in a single mutation, so the key is never left unrevoked.
+func TestRevokeUpsert(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	f := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"resp": {"entities": [{"id": "0x1"}]}}}`))
+	}
+	server := httptest.NewServer(http.HandlerFunc(f))
+	t.Cleanup(server.Close)
+
+	log, err := logger.New(io.Discard, logger.Config{Service: "TEST"})
+	if err != nil {
+		t.Fatalf("Should be able to construct a logger: %v", err)
+	}
+	store := token.NewStore(log, data.NewGraphQL(data.GraphQLConfig{URL: server.URL}))
+
+	t.Log("Given the need to replace the revocation of a subject.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen revoking a subject.", testID)
+		{
+			now := time.Now()
+			if err := store.RevokeSubject(context.Background(), "00000000-0000-0000-0000-000000000000", "0x1", now, now.Add(time.Hour)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the subject: %v", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to revoke the subject.", tests.Success, testID)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if len(bodies) != 1 || strings.Contains(bodies[0], "deleteRevocation") {
+				t.Fatalf("\t%s\tTest %d:\tShould send a single mutation without a delete: %v", tests.Failed, testID, bodies)
+			}
+			if !strings.Contains(bodies[0], "addRevocation") || !strings.Contains(bodies[0], "upsert: true") {
+				t.Fatalf("\t%s\tTest %d:\tShould upsert the revocation: %s", tests.Failed, testID, bodies[0])
+			}
+			t.Logf("\t%s\tTest %d:\tShould upsert the revocation in a single mutation.", tests.Success, testID)
+		}
+	}
+}
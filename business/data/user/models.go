@@ -1,6 +1,10 @@
 package user
 
-import "time"
+import (
+	"time"
+
+	"github.com/dgraph-io/travel/business/sys/validate"
+)
 
 // User represents someone with access to the system.
 type User struct {
@@ -8,18 +12,52 @@ type User struct {
 	Name         string    `json:"name"`
 	Email        string    `json:"email"`
 	Role         string    `json:"role"`
-	PasswordHash string    `json:"password_hash"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	FailedLogins int       `json:"failed_logins"`
+	LockedUntil  time.Time `json:"locked_until"`
 	DateCreated  time.Time `json:"date_created"`
 	DateUpdated  time.Time `json:"date_updated"`
 }
 
 // NewUser contains information needed to create a new User.
 type NewUser struct {
-	Name            string `json:"name"`
-	Email           string `json:"email"`
-	Role            string `json:"role"`
-	Password        string `json:"password"`
-	PasswordConfirm string `json:"password_confirm"`
+	Name            string `json:"name" validate:"required"`
+	Email           string `json:"email" validate:"required,email"`
+	Role            string `json:"role" validate:"required,oneof=ADMIN USER"`
+	Password        string `json:"password" validate:"required,password"`
+	PasswordConfirm string `json:"password_confirm" validate:"eqfield=Password"`
+}
+
+// Validate checks the fields of the new user against their tags.
+func (nu NewUser) Validate() error {
+	return validate.Check(nu)
+}
+
+// Lockout defines how many failed logins in a row lock an account and for how
+// long. Accounts are never locked when Attempts is zero.
+type Lockout struct {
+	Attempts int
+	Duration time.Duration
+}
+
+// UpdateUser defines what information may be provided to modify an existing
+// User. All fields are optional so clients can send just the fields they want
+// changed. It uses pointer fields so we can differentiate between a field that
+// was not provided and a field that was provided as explicitly blank. Users
+// changing their own password or email must provide their current password,
+// which isn't stored.
+type UpdateUser struct {
+	Name            *string `json:"name"`
+	Email           *string `json:"email" validate:"omitempty,email"`
+	Role            *string `json:"role" validate:"omitempty,oneof=ADMIN USER"`
+	Password        *string `json:"password" validate:"omitempty,password"`
+	PasswordConfirm *string `json:"password_confirm" validate:"required_with=Password,eqfield=Password"`
+	CurrentPassword *string `json:"current_password"`
+}
+
+// Validate checks the provided fields of the update against their tags.
+func (uu UpdateUser) Validate() error {
+	return validate.Check(uu)
 }
 
 // =============================================================================
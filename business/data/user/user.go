@@ -4,11 +4,12 @@ package user
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/ardanlabs/graphql"
 	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -18,16 +19,24 @@ var (
 	ErrNotExists = errors.New("user does not exist")
 	ErrExists    = errors.New("user exists")
 	ErrNotFound  = errors.New("user not found")
+
+	// ErrAuthenticationFailure occurs when a user attempts to authenticate but
+	// anything goes wrong.
+	ErrAuthenticationFailure = errors.New("authentication failed")
+
+	// ErrAccountLocked occurs when a user attempts to authenticate after too
+	// many failed logins in a row.
+	ErrAccountLocked = errors.New("account locked")
 )
 
 // Store manages the set of API's for user access.
 type Store struct {
-	log *log.Logger
+	log *logger.Logger
 	gql *graphql.GraphQL
 }
 
 // NewStore constructs a user store for api access.
-func NewStore(log *log.Logger, gql *graphql.GraphQL) Store {
+func NewStore(log *logger.Logger, gql *graphql.GraphQL) Store {
 	return Store{
 		log: log,
 		gql: gql,
@@ -38,6 +47,9 @@ func NewStore(log *log.Logger, gql *graphql.GraphQL) Store {
 // this function will fail but the found user is returned. If the user is
 // being added, the user with the id from the database is returned.
 func (s Store) Add(ctx context.Context, traceID string, nu NewUser, now time.Time) (User, error) {
+	ctx, span := tracer.Start(ctx, "user.Add")
+	defer span.End()
+
 	if usr, err := s.QueryByEmail(ctx, traceID, nu.Email); err == nil {
 		return usr, ErrExists
 	}
@@ -59,23 +71,62 @@ func (s Store) Add(ctx context.Context, traceID string, nu NewUser, now time.Tim
 	return s.add(ctx, traceID, usr)
 }
 
-// Update updates a user in the database by its ID. If the user doesn't
-// already exist, this function will fail.
-func (s Store) Update(ctx context.Context, traceID string, usr User) error {
-	if usr.ID == "" {
-		return errors.New("user missing id")
+// Update modifies data about a user in the database by its ID. Only the
+// fields provided are changed. If the user doesn't already exist, this
+// function will fail.
+func (s Store) Update(ctx context.Context, traceID string, userID string, uu UpdateUser, now time.Time) (User, error) {
+	ctx, span := tracer.Start(ctx, "user.Update")
+	defer span.End()
+
+	if userID == "" {
+		return User{}, errors.New("user missing id")
 	}
 
-	if _, err := s.QueryByID(ctx, traceID, usr.ID); err != nil {
-		return ErrNotExists
+	usr, err := s.QueryByID(ctx, traceID, userID)
+	if err != nil {
+		return User{}, ErrNotExists
+	}
+
+	if uu.Name != nil {
+		usr.Name = *uu.Name
+	}
+	if uu.Email != nil {
+		if other, err := s.QueryByEmail(ctx, traceID, *uu.Email); err == nil && other.ID != usr.ID {
+			return User{}, ErrExists
+		}
+		usr.Email = *uu.Email
+	}
+	if uu.Role != nil {
+		usr.Role = *uu.Role
+	}
+	if uu.Password != nil {
+		hash, err := bcrypt.GenerateFromPassword([]byte(*uu.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return User{}, errors.Wrap(err, "generating password hash")
+		}
+		usr.PasswordHash = string(hash)
+
+		// A new password unlocks the account.
+		usr.FailedLogins = 0
+		if usr.LockedUntil.After(now) {
+			usr.LockedUntil = now
+		}
+	}
+	usr.DateUpdated = now
+
+	if err := s.update(ctx, traceID, usr); err != nil {
+		return User{}, err
 	}
 
-	return s.update(ctx, traceID, usr)
+	return usr, nil
 }
 
 // Delete removes a user from the database by its ID. If the user doesn't
 // already exist, this function will fail.
 func (s Store) Delete(ctx context.Context, traceID string, userID string) error {
+	ctx, span := tracer.Start(ctx, "user.Delete")
+	defer span.End()
+
 	if userID == "" {
 		return errors.New("missing user id")
 	}
@@ -87,8 +138,128 @@ func (s Store) Delete(ctx context.Context, traceID string, userID string) error
 	return s.delete(ctx, traceID, userID)
 }
 
-// QueryByID returns the specified user from the database by the city id.
+// Authenticate finds a user by their email and verifies their password. On
+// success it returns the user. Any failure is reported as an
+// ErrAuthenticationFailure so the cause is not leaked to the caller. Failed
+// logins are counted and the account is locked for a time once the lockout
+// attempts are reached.
+func (s Store) Authenticate(ctx context.Context, traceID string, email string, password string, lockout Lockout, now time.Time) (User, error) {
+	ctx, span := tracer.Start(ctx, "user.Authenticate")
+	defer span.End()
+
+	usr, err := s.QueryByEmail(ctx, traceID, email)
+	if err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return User{}, ErrAuthenticationFailure
+		}
+		return User{}, errors.Wrap(err, "querying user")
+	}
+
+	if now.Before(usr.LockedUntil) {
+		return User{}, ErrAccountLocked
+	}
+
+	// Compare the provided password with the saved hash. Use the bcrypt
+	// comparison function so it is cryptographically secure.
+	if err := bcrypt.CompareHashAndPassword([]byte(usr.PasswordHash), []byte(password)); err != nil {
+		if lockout.Attempts > 0 {
+			if err := s.recordFailedLogin(ctx, traceID, usr, lockout, now); err != nil {
+				return User{}, errors.Wrap(err, "recording failed login")
+			}
+		}
+		return User{}, ErrAuthenticationFailure
+	}
+
+	// Another login failing at the same time keeps its count.
+	if usr.FailedLogins > 0 {
+		if _, err := s.updateLogins(ctx, traceID, usr, 0, usr.LockedUntil); err != nil {
+			return User{}, errors.Wrap(err, "resetting failed logins")
+		}
+		usr.FailedLogins = 0
+	}
+
+	return usr, nil
+}
+
+// maxLoginRetries is how many times a failed login is counted again when
+// other logins change the count at the same time.
+const maxLoginRetries = 5
+
+// recordFailedLogin counts the failed login and locks the account once the
+// lockout attempts are reached. The count is only changed when it still has
+// the value read so concurrent logins can't overwrite each other's count.
+func (s Store) recordFailedLogin(ctx context.Context, traceID string, usr User, lockout Lockout, now time.Time) error {
+	for i := 0; i < maxLoginRetries; i++ {
+		failedLogins := usr.FailedLogins + 1
+		lockedUntil := usr.LockedUntil
+		if failedLogins >= lockout.Attempts {
+			failedLogins = 0
+			lockedUntil = now.Add(lockout.Duration)
+		}
+
+		updated, err := s.updateLogins(ctx, traceID, usr, failedLogins, lockedUntil)
+		if err != nil {
+			return err
+		}
+		if updated {
+			return nil
+		}
+
+		if usr, err = s.QueryByID(ctx, traceID, usr.ID); err != nil {
+			return errors.Wrap(err, "querying user")
+		}
+
+		// Another login locked the account.
+		if now.Before(usr.LockedUntil) {
+			return nil
+		}
+	}
+
+	return errors.New("failed logins changed too often")
+}
+
+// QueryAll returns a page of users from the database ordered by name.
+// The page number starts at 1.
+func (s Store) QueryAll(ctx context.Context, traceID string, pageNumber int, rowsPerPage int) ([]User, error) {
+	ctx, span := tracer.Start(ctx, "user.QueryAll")
+	defer span.End()
+
+	if pageNumber < 1 || rowsPerPage < 1 {
+		return nil, errors.New("invalid page number or rows per page")
+	}
+
+	query := fmt.Sprintf(`
+query {
+	queryUser(order: { asc: name }, first: %d, offset: %d) {
+		id
+		name
+		email
+		role
+		password_hash
+		failed_logins
+		locked_until
+		date_created
+		date_updated
+	}
+}`, rowsPerPage, (pageNumber-1)*rowsPerPage)
+
+	data.LogQuery(s.log, traceID, "user.QueryAll", query)
+
+	var result struct {
+		QueryUser []User `json:"queryUser"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return nil, errors.Wrap(err, "query failed")
+	}
+
+	return result.QueryUser, nil
+}
+
+// QueryByID returns the specified user from the database by the user id.
 func (s Store) QueryByID(ctx context.Context, traceID string, userID string) (User, error) {
+	ctx, span := tracer.Start(ctx, "user.QueryByID")
+	defer span.End()
+
 	query := fmt.Sprintf(`
 query {
 	getUser(id: %q) {
@@ -97,12 +268,14 @@ query {
 		email
 		role
 		password_hash
+		failed_logins
+		locked_until
 		date_created
 		date_updated
 	}
 }`, userID)
 
-	s.log.Printf("%s: %s: %s", traceID, "user.QueryByID", data.Log(query))
+	data.LogQuery(s.log, traceID, "user.QueryByID", query)
 
 	var result struct {
 		GetUser User `json:"getUser"`
@@ -120,6 +293,9 @@ query {
 
 // QueryByEmail returns the specified user from the database by email.
 func (s Store) QueryByEmail(ctx context.Context, traceID string, email string) (User, error) {
+	ctx, span := tracer.Start(ctx, "user.QueryByEmail")
+	defer span.End()
+
 	query := fmt.Sprintf(`
 query {
 	queryUser(filter: { email: { eq: %q } }) {
@@ -128,12 +304,14 @@ query {
 		email
 		role
 		password_hash
+		failed_logins
+		locked_until
 		date_created
 		date_updated
 	}
 }`, email)
 
-	s.log.Printf("%s: %s: %s", traceID, "user.QueryByEmail", data.Log(query))
+	data.LogQuery(s.log, traceID, "user.QueryByEmail", query)
 
 	var result struct {
 		QueryUser []User `json:"queryUser"`
@@ -169,7 +347,7 @@ func (s Store) add(ctx context.Context, traceID string, usr User) (User, error)
 		usr.DateUpdated.UTC().Format(time.RFC3339),
 		result.document())
 
-	s.log.Printf("%s: %s: %s", traceID, "user.Add", data.Log(mutation))
+	data.LogQuery(s.log, traceID, "user.Add", mutation)
 
 	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
 		return User{}, errors.Wrap(err, "failed to add user")
@@ -184,6 +362,13 @@ func (s Store) add(ctx context.Context, traceID string, usr User) (User, error)
 }
 
 func (s Store) update(ctx context.Context, traceID string, usr User) error {
+
+	// The account has never been locked if there is no time.
+	var lockedUntil string
+	if !usr.LockedUntil.IsZero() {
+		lockedUntil = fmt.Sprintf("locked_until: %q", usr.LockedUntil.UTC().Format(time.RFC3339))
+	}
+
 	var result result
 	mutation := fmt.Sprintf(`
 	mutation {
@@ -196,19 +381,21 @@ func (s Store) update(ctx context.Context, traceID string, usr User) error {
 				email: %q
 				role: %s
 				password_hash: %q
+				failed_logins: %d
+				%s
 				date_created: %q
 				date_updated: %q
 			}
 		})
 		%s
-	}`, usr.ID, usr.Name, usr.Email, usr.Role, usr.PasswordHash,
+	}`, usr.ID, usr.Name, usr.Email, usr.Role, usr.PasswordHash, usr.FailedLogins, lockedUntil,
 		usr.DateCreated.UTC().Format(time.RFC3339),
 		usr.DateUpdated.UTC().Format(time.RFC3339),
 		result.document())
 
-	s.log.Printf("%s: %s: %s", traceID, "user.Update", data.Log(mutation))
+	data.LogQuery(s.log, traceID, "user.Update", mutation)
 
-	if err := s.gql.Execute(ctx, mutation, nil); err != nil {
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
 		return errors.Wrap(err, "failed to update user")
 	}
 
@@ -220,6 +407,45 @@ func (s Store) update(ctx context.Context, traceID string, usr User) error {
 	return nil
 }
 
+// updateLogins sets the failed logins and lock of the user when the failed
+// logins still have the value read. It reports whether the user was
+// updated. Users that never failed a login have no count stored.
+func (s Store) updateLogins(ctx context.Context, traceID string, usr User, failedLogins int, lockedUntil time.Time) (bool, error) {
+	current := fmt.Sprintf("failed_logins: { eq: %d }", usr.FailedLogins)
+	if usr.FailedLogins == 0 {
+		current = "not: { failed_logins: { gt: 0 } }"
+	}
+
+	var locked string
+	if !lockedUntil.IsZero() {
+		locked = fmt.Sprintf("locked_until: %q", lockedUntil.UTC().Format(time.RFC3339))
+	}
+
+	var result result
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: updateUser(input: {
+			filter: {
+				id: [%q]
+				%s
+			},
+			set: {
+				failed_logins: %d
+				%s
+			}
+		})
+		%s
+	}`, usr.ID, current, failedLogins, locked, result.document())
+
+	data.LogQuery(s.log, traceID, "user.UpdateLogins", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return false, errors.Wrap(err, "failed to update failed logins")
+	}
+
+	return result.Resp.NumUids == 1, nil
+}
+
 func (s Store) delete(ctx context.Context, traceID string, userID string) error {
 	var result result
 	mutation := fmt.Sprintf(`
@@ -228,13 +454,13 @@ func (s Store) delete(ctx context.Context, traceID string, userID string) error
 		%s
 	}`, userID, result.document())
 
-	s.log.Printf("%s: %s: %s", traceID, "user.Delete", data.Log(mutation))
+	data.LogQuery(s.log, traceID, "user.Delete", mutation)
 
-	if err := s.gql.Execute(ctx, mutation, nil); err != nil {
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
 		return errors.Wrap(err, "failed to delete user")
 	}
 
-	if result.Resp.NumUids != 0 {
+	if result.Resp.NumUids != 1 {
 		msg := fmt.Sprintf("failed to delete user: NumUids: %d  Msg: %s", result.Resp.NumUids, result.Resp.Msg)
 		return errors.New(msg)
 	}
@@ -1,22 +1,25 @@
 package weather
 
+import "time"
+
 // Weather contains the weather data points captured from the API.
 type Weather struct {
-	ID            string  `json:"id,omitempty"`
-	City          City    `json:"city"`
-	CityName      string  `json:"city_name"`
-	Visibility    string  `json:"visibility"`
-	Desc          string  `json:"description"`
-	Temp          float64 `json:"temp"`
-	FeelsLike     float64 `json:"feels_like"`
-	MinTemp       float64 `json:"temp_min"`
-	MaxTemp       float64 `json:"temp_max"`
-	Pressure      int     `json:"pressure"`
-	Humidity      int     `json:"humidity"`
-	WindSpeed     float64 `json:"wind_speed"`
-	WindDirection int     `json:"wind_direction"`
-	Sunrise       int     `json:"sunrise"`
-	Sunset        int     `json:"sunset"`
+	ID            string    `json:"id,omitempty"`
+	City          City      `json:"city"`
+	CityName      string    `json:"city_name"`
+	Visibility    string    `json:"visibility"`
+	Desc          string    `json:"description"`
+	Temp          float64   `json:"temp"`
+	FeelsLike     float64   `json:"feels_like"`
+	MinTemp       float64   `json:"temp_min"`
+	MaxTemp       float64   `json:"temp_max"`
+	Pressure      int       `json:"pressure"`
+	Humidity      int       `json:"humidity"`
+	WindSpeed     float64   `json:"wind_speed"`
+	WindDirection int       `json:"wind_direction"`
+	Sunrise       int       `json:"sunrise"`
+	Sunset        int       `json:"sunset"`
+	RecordedAt    time.Time `json:"recorded_at"`
 }
 
 // City is used to capture the city id in relationships.
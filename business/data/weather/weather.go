@@ -4,10 +4,13 @@ package weather
 import (
 	"context"
 	"fmt"
-	"log"
+	"strings"
+	"time"
 
 	"github.com/ardanlabs/graphql"
 	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/pkg/errors"
 )
 
@@ -18,21 +21,24 @@ var (
 
 // Store manages the set of API's for city access.
 type Store struct {
-	log *log.Logger
+	log *logger.Logger
 	gql *graphql.GraphQL
 }
 
 // NewStore constructs a weather store for api access.
-func NewStore(log *log.Logger, gql *graphql.GraphQL) Store {
+func NewStore(log *logger.Logger, gql *graphql.GraphQL) Store {
 	return Store{
 		log: log,
 		gql: gql,
 	}
 }
 
-// Replace replaces a weather in the database and connects it
-// to the specified city.
-func (s Store) Replace(ctx context.Context, traceID string, wth Weather) (Weather, error) {
+// Replace replaces all the weather snapshots for the specified city with
+// the provided weather, which becomes the latest snapshot.
+func (s Store) Replace(ctx context.Context, traceID string, wth Weather, now time.Time) (Weather, error) {
+	ctx, span := tracer.Start(ctx, "weather.Replace")
+	defer span.End()
+
 	if wth.ID != "" {
 		return Weather{}, errors.New("weather contains id")
 	}
@@ -40,23 +46,144 @@ func (s Store) Replace(ctx context.Context, traceID string, wth Weather) (Weathe
 		return Weather{}, errors.New("cityid not provided")
 	}
 
-	if oldWth, err := s.QueryByCity(ctx, traceID, wth.City.ID); err == nil {
-		if err := s.delete(ctx, traceID, oldWth.ID); err != nil {
-			if err != ErrNotFound {
-				return Weather{}, errors.Wrap(err, "deleting weather from database")
-			}
+	history, err := s.queryHistoryIDs(ctx, traceID, wth.City.ID, "")
+	if err != nil {
+		return Weather{}, errors.Wrap(err, "querying weather history")
+	}
+
+	if len(history) > 0 {
+		if _, err := s.delete(ctx, traceID, history); err != nil {
+			return Weather{}, errors.Wrap(err, "deleting weather from database")
+		}
+	}
+
+	return s.Append(ctx, traceID, wth, now)
+}
+
+// Append adds a new weather snapshot for the specified city, recorded at the
+// specified time. The snapshot becomes the latest weather for the city and
+// all previous snapshots are kept as history.
+func (s Store) Append(ctx context.Context, traceID string, wth Weather, now time.Time) (Weather, error) {
+	ctx, span := tracer.Start(ctx, "weather.Append")
+	defer span.End()
+
+	if wth.ID != "" {
+		return Weather{}, errors.New("weather contains id")
+	}
+	if wth.City.ID == "" {
+		return Weather{}, errors.New("cityid not provided")
+	}
+
+	wth.RecordedAt = now
+	wth, err := s.add(ctx, traceID, wth)
+	if err != nil {
+		return Weather{}, err
+	}
+
+	if err := s.setLatest(ctx, traceID, wth.City.ID, wth.ID); err != nil {
+		return Weather{}, err
+	}
+
+	return wth, nil
+}
+
+// Prune removes the weather snapshots for the specified city that were
+// recorded before the specified time. The latest snapshot is never removed.
+// The number of snapshots removed is returned.
+func (s Store) Prune(ctx context.Context, traceID string, cityID string, before time.Time) (int, error) {
+	ctx, span := tracer.Start(ctx, "weather.Prune")
+	defer span.End()
+
+	if cityID == "" {
+		return 0, errors.New("cityid not provided")
+	}
+
+	filter := fmt.Sprintf(`(filter: { recorded_at: { lt: %q } })`, before.UTC().Format(time.RFC3339))
+	history, err := s.queryHistoryIDs(ctx, traceID, cityID, filter)
+	if err != nil {
+		return 0, errors.Wrap(err, "querying weather history")
+	}
+
+	var latestID string
+	if latest, err := s.QueryByCity(ctx, traceID, cityID); err == nil {
+		latestID = latest.ID
+	}
+
+	var ids []string
+	for _, id := range history {
+		if id != latestID {
+			ids = append(ids, id)
 		}
 	}
 
-	return s.add(ctx, traceID, wth)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	return s.delete(ctx, traceID, ids)
 }
 
-// QueryByCity returns the specified weather from the database by the city id.
+// QueryByCity returns the latest weather from the database by the city id.
 func (s Store) QueryByCity(ctx context.Context, traceID string, cityID string) (Weather, error) {
+	ctx, span := tracer.Start(ctx, "weather.QueryByCity")
+	defer span.End()
+
 	query := fmt.Sprintf(`
 query {
 	getCity(id: %q) {
-		weather {
+		weather %s
+	}
+}`, cityID, fields)
+
+	data.LogQuery(s.log, traceID, "weather.QueryByCity", query)
+
+	var result struct {
+		GetCity struct {
+			Weather Weather `json:"weather"`
+		} `json:"getCity"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return Weather{}, errors.Wrap(err, "query failed")
+	}
+
+	if result.GetCity.Weather.ID == "" {
+		return Weather{}, ErrNotFound
+	}
+
+	return result.GetCity.Weather, nil
+}
+
+// QueryRange returns the weather snapshots for the specified city recorded
+// between the from and to times inclusive, ordered from oldest to newest.
+func (s Store) QueryRange(ctx context.Context, traceID string, cityID string, from time.Time, to time.Time) ([]Weather, error) {
+	ctx, span := tracer.Start(ctx, "weather.QueryRange")
+	defer span.End()
+
+	query := fmt.Sprintf(`
+query {
+	getCity(id: %q) {
+		weather_history(filter: { recorded_at: { between: { min: %q, max: %q } } }, order: { asc: recorded_at }) %s
+	}
+}`, cityID, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339), fields)
+
+	data.LogQuery(s.log, traceID, "weather.QueryRange", query)
+
+	var result struct {
+		GetCity struct {
+			History []Weather `json:"weather_history"`
+		} `json:"getCity"`
+	}
+	if err := s.gql.Execute(ctx, query, &result); err != nil {
+		return nil, errors.Wrap(err, "query failed")
+	}
+
+	return result.GetCity.History, nil
+}
+
+// =============================================================================
+
+// fields is the set of weather fields returned by queries.
+const fields = `{
 			id
 			city {
 				id
@@ -66,6 +193,7 @@ query {
 			feels_like
 			humidity
 			pressure
+			recorded_at
 			sunrise
 			sunset
 			temp
@@ -74,50 +202,64 @@ query {
 			visibility
 			wind_direction
 			wind_speed
+		}`
+
+func (s Store) queryHistoryIDs(ctx context.Context, traceID string, cityID string, filter string) ([]string, error) {
+	query := fmt.Sprintf(`
+query {
+	getCity(id: %q) {
+		weather_history%s {
+			id
 		}
 	}
-}`, cityID)
+}`, cityID, filter)
 
-	s.log.Printf("%s: %s: %s", traceID, "weather.QueryByID", data.Log(query))
+	data.LogQuery(s.log, traceID, "weather.QueryHistoryIDs", query)
 
 	var result struct {
 		GetCity struct {
-			Weather Weather `json:"weather"`
+			History []struct {
+				ID string `json:"id"`
+			} `json:"weather_history"`
 		} `json:"getCity"`
 	}
 	if err := s.gql.Execute(ctx, query, &result); err != nil {
-		return Weather{}, errors.Wrap(err, "query failed")
+		return nil, errors.Wrap(err, "query failed")
 	}
 
-	if result.GetCity.Weather.ID == "" {
-		return Weather{}, ErrNotFound
+	ids := make([]string, len(result.GetCity.History))
+	for i, wth := range result.GetCity.History {
+		ids[i] = wth.ID
 	}
 
-	return result.GetCity.Weather, nil
+	return ids, nil
 }
 
-// =============================================================================
+func (s Store) delete(ctx context.Context, traceID string, wthIDs []string) (int, error) {
+	quoted := make([]string, len(wthIDs))
+	for i, id := range wthIDs {
+		quoted[i] = fmt.Sprintf("%q", id)
+	}
 
-func (s Store) delete(ctx context.Context, traceID string, wthID string) error {
 	var result result
 	mutation := fmt.Sprintf(`
 	mutation {
-		resp: deleteWeather(filter: { id: [%q] })
+		resp: deleteWeather(filter: { id: [%s] })
 		%s
-	}`, wthID, result.document())
+	}`, strings.Join(quoted, ", "), result.document())
 
-	s.log.Printf("%s: %s: %s", traceID, "weather.Delete", data.Log(mutation))
+	data.LogQuery(s.log, traceID, "weather.Delete", mutation)
 
 	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
-		return errors.Wrap(err, "failed to delete weather")
+		return 0, errors.Wrap(err, "failed to delete weather")
 	}
 
-	if result.Resp.NumUids != 1 {
-		msg := fmt.Sprintf("failed to delete advisory: NumUids: %d  Msg: %s", result.Resp.NumUids, result.Resp.Msg)
-		return errors.New(msg)
+	if result.Resp.NumUids != len(wthIDs) {
+		msg := fmt.Sprintf("failed to delete weather: NumUids: %d  Msg: %s", result.Resp.NumUids, result.Resp.Msg)
+		return 0, errors.New(msg)
 	}
 
-	return nil
+	return result.Resp.NumUids, nil
 }
 
 func (s Store) add(ctx context.Context, traceID string, wth Weather) (Weather, error) {
@@ -133,6 +275,7 @@ func (s Store) add(ctx context.Context, traceID string, wth Weather) (Weather, e
 			feels_like: %f
 			humidity: %d
 			pressure: %d
+			recorded_at: %q
 			sunrise: %d
 			sunset: %d
 			temp: %f
@@ -144,20 +287,52 @@ func (s Store) add(ctx context.Context, traceID string, wth Weather) (Weather, e
 		}])
 		%s
 	}`, wth.City.ID, wth.CityName, wth.Desc, wth.FeelsLike, wth.Humidity,
-		wth.Pressure, wth.Sunrise, wth.Sunset, wth.Temp,
+		wth.Pressure, wth.RecordedAt.UTC().Format(time.RFC3339),
+		wth.Sunrise, wth.Sunset, wth.Temp,
 		wth.MinTemp, wth.MaxTemp, wth.Visibility, wth.WindDirection,
 		wth.WindSpeed, result.document())
 
-	s.log.Printf("%s: %s: %s", traceID, "weather.Add", data.Log(mutation))
+	data.LogQuery(s.log, traceID, "weather.Add", mutation)
 
 	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
 		return Weather{}, errors.Wrap(err, "failed to add weather")
 	}
 
 	if len(result.Resp.Entities) != 1 {
-		return Weather{}, errors.New("advisory id not returned")
+		return Weather{}, errors.New("weather id not returned")
 	}
 
 	wth.ID = result.Resp.Entities[0].ID
 	return wth, nil
 }
+
+func (s Store) setLatest(ctx context.Context, traceID string, cityID string, wthID string) error {
+	var result result
+	mutation := fmt.Sprintf(`
+	mutation {
+		resp: updateCity(input: {
+			filter: {
+				id: [%q]
+			},
+			set: {
+				weather: {
+					id: %q
+				}
+			}
+		})
+		%s
+	}`, cityID, wthID, result.document())
+
+	data.LogQuery(s.log, traceID, "weather.SetLatest", mutation)
+
+	if err := s.gql.Execute(ctx, mutation, &result); err != nil {
+		return errors.Wrap(err, "failed to set latest weather")
+	}
+
+	if result.Resp.NumUids != 1 {
+		msg := fmt.Sprintf("failed to set latest weather: NumUids: %d  Msg: %s", result.Resp.NumUids, result.Resp.Msg)
+		return errors.New(msg)
+	}
+
+	return nil
+}
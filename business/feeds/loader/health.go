@@ -0,0 +1,84 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/health"
+	"github.com/pkg/errors"
+)
+
+// placesURL is the endpoint of the Google Places API used by the maps
+// client.
+const placesURL = "https://maps.googleapis.com/maps/api/place/nearbysearch/json"
+
+// pingTimeout is how long a feed provider has to answer a ping.
+const pingTimeout = 2 * time.Second
+
+// HealthChecks returns the checks of the feed providers. The settings of
+// each provider are always checked. When ping is true the providers are also
+// sent a request without any search, which is cheap but still counts
+// against the quota of the API. A service can run without the feeds so the
+// checks aren't critical.
+func HealthChecks(cfg Config, ping bool) []health.Check {
+	feeds := []struct {
+		name string
+		url  string
+		key  string
+		keys bool
+	}{
+		{"advisory", cfg.URL.Advisory, "", false},
+		{"weather", cfg.URL.Weather, cfg.Keys.WeatherKey, true},
+		{"places", placesURL, cfg.Keys.MapKey, true},
+	}
+
+	checks := make([]health.Check, len(feeds))
+	for i, feed := range feeds {
+		feed := feed
+		f := func(ctx context.Context) error {
+			switch {
+			case feed.url == "":
+				return errors.New("url not configured")
+			case feed.keys && feed.key == "":
+				return errors.New("api key not configured")
+			}
+
+			if !ping {
+				return nil
+			}
+			return pingURL(ctx, feed.url)
+		}
+
+		checks[i] = health.Check{
+			Name:    "feed." + feed.name,
+			Timeout: pingTimeout,
+			Checker: f,
+		}
+	}
+
+	return checks
+}
+
+// pingURL makes sure the provider is reachable. Any response other than a
+// server error means the provider is up, since the request doesn't carry
+// the parameters of a real search.
+func pingURL(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrap(err, "new request")
+	}
+
+	var client http.Client
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "client do")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return errors.New(resp.Status)
+	}
+
+	return nil
+}
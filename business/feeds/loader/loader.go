@@ -4,7 +4,7 @@ package loader
 import (
 	"context"
 	"io"
-	"log"
+	"math"
 	"time"
 
 	"github.com/ardanlabs/graphql"
@@ -15,8 +15,12 @@ import (
 	"github.com/dgraph-io/travel/business/data/schema"
 	"github.com/dgraph-io/travel/business/data/weather"
 	advisoryfeed "github.com/dgraph-io/travel/business/feeds/advisory"
+	"github.com/dgraph-io/travel/business/feeds/notify"
 	placesfeed "github.com/dgraph-io/travel/business/feeds/places"
 	weatherfeed "github.com/dgraph-io/travel/business/feeds/weather"
+	"github.com/dgraph-io/travel/business/sys/metrics"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/pkg/errors"
 	"googlemaps.github.io/maps"
 )
@@ -32,9 +36,11 @@ type Search struct {
 
 // Config defines the set of mandatory settings.
 type Config struct {
-	Filter Filter
-	Keys   Keys
-	URL    URL
+	Filter    Filter
+	Keys      Keys
+	URL       URL
+	Retention Retention
+	Alert     Alert
 }
 
 // Filter represents search related refinements.
@@ -57,6 +63,20 @@ type URL struct {
 	Weather  string
 }
 
+// Retention represents how long historical data is kept. A zero value
+// keeps the history forever.
+type Retention struct {
+	Weather  time.Duration
+	Advisory time.Duration
+}
+
+// Alert represents the settings for detecting changes in the feed data.
+// Events are only delivered when a notifier is provided.
+type Alert struct {
+	AdvisoryThreshold float64
+	Notifier          *notify.Notifier
+}
+
 // UpdateSchema creates/updates the schema for the database.
 func UpdateSchema(gqlConfig data.GraphQLConfig, schemaConfig schema.Config) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -81,11 +101,16 @@ func UpdateSchema(gqlConfig data.GraphQLConfig, schemaConfig schema.Config) erro
 	return nil
 }
 
-// UpdateData retrieves and stores the feed data for this API.
-func UpdateData(log *log.Logger, gqlConfig data.GraphQLConfig, traceID string, config Config, search Search) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+// UpdateData retrieves and stores the feed data for this API. The context
+// carries the span of the work being traced.
+func UpdateData(ctx context.Context, log *logger.Logger, gqlConfig data.GraphQLConfig, traceID string, config Config, search Search) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
+	ctx, span := tracer.Start(ctx, "loader.UpdateData")
+	span.SetAttribute("city", search.CityName)
+	defer span.End()
+
 	gql := data.NewGraphQL(gqlConfig)
 	loader := newLoader(log, gql)
 
@@ -94,12 +119,12 @@ func UpdateData(log *log.Logger, gqlConfig data.GraphQLConfig, traceID string, c
 		return errors.Wrapf(err, "adding city")
 	}
 
-	if err := loader.replaceWeather(ctx, traceID, config.Keys.WeatherKey, config.URL.Weather, cty.ID, cty.Lat, cty.Lng); err != nil {
-		return errors.Wrapf(err, "replacing weather")
+	if err := loader.appendWeather(ctx, traceID, config.Keys.WeatherKey, config.URL.Weather, cty.ID, cty.Lat, cty.Lng, config.Retention.Weather); err != nil {
+		return errors.Wrapf(err, "appending weather")
 	}
 
-	if err := loader.replaceAdvisory(ctx, traceID, config.URL.Advisory, cty.ID, search.CountryCode); err != nil {
-		return errors.Wrapf(err, "replacing advisory")
+	if err := loader.appendAdvisory(ctx, traceID, config.URL.Advisory, cty, search.CountryCode, config.Retention.Advisory, config.Alert); err != nil {
+		return errors.Wrapf(err, "appending advisory")
 	}
 
 	if err := loader.upsertPlaces(ctx, traceID, config.Keys.MapKey, cty, config.Filter.Categories, config.Filter.Radius); err != nil {
@@ -117,12 +142,12 @@ type store struct {
 }
 
 type loader struct {
-	log   *log.Logger
+	log   *logger.Logger
 	gql   *graphql.GraphQL
 	store store
 }
 
-func newLoader(log *log.Logger, gql *graphql.GraphQL) loader {
+func newLoader(log *logger.Logger, gql *graphql.GraphQL) loader {
 	return loader{
 		log: log,
 		gql: gql,
@@ -147,45 +172,106 @@ func (l loader) upsertCity(ctx context.Context, traceID string, name string, lat
 		return city.City{}, errors.Wrapf(err, "adding city: %s", name)
 	}
 
-	log.Printf("feed: Work: Upserted City: ID: %s Name: %s Lat: %f Lng: %f", newCity.ID, name, lat, lng)
+	l.log.Info("upserted city", "traceid", traceID, "city_id", newCity.ID, "name", name, "lat", lat, "lng", lng)
 
 	return newCity, nil
 }
 
-// replaceWeather pulls weather information and updates it for the specified city.
-func (l loader) replaceWeather(ctx context.Context, traceID string, apiKey string, url string, cityID string, lat float64, lng float64) error {
-	feedData, err := weatherfeed.Search(ctx, apiKey, url, lat, lng)
+// appendWeather pulls weather information and adds a new snapshot for the
+// specified city. Snapshots older than the retention period are removed.
+func (l loader) appendWeather(ctx context.Context, traceID string, apiKey string, url string, cityID string, lat float64, lng float64, retention time.Duration) error {
+	feedData, err := searchWeather(ctx, apiKey, url, lat, lng)
 	if err != nil {
 		return errors.Wrap(err, "searching weather")
 	}
 
+	now := time.Now()
 	newWeather := marshalWeather(feedData, cityID)
-	newWeather, err = l.store.weather.Replace(ctx, traceID, newWeather)
+	newWeather, err = l.store.weather.Append(ctx, traceID, newWeather, now)
 	if err != nil {
 		return errors.Wrap(err, "storing weather")
 	}
 
-	log.Printf("feed: Work: Replaced Weather: ID: %s Desc: %s", newWeather.ID, newWeather.Desc)
+	l.log.Info("appended weather", "traceid", traceID, "weather_id", newWeather.ID, "desc", newWeather.Desc)
+
+	if retention > 0 {
+		pruned, err := l.store.weather.Prune(ctx, traceID, cityID, now.Add(-retention))
+		if err != nil {
+			return errors.Wrap(err, "pruning weather")
+		}
+
+		l.log.Info("pruned weather", "traceid", traceID, "city_id", cityID, "snapshots", pruned)
+	}
+
 	return nil
 }
 
-// replaceAdvisory pulls advisory information and updates it for the specified city.
-func (l loader) replaceAdvisory(ctx context.Context, traceID string, url string, cityID string, countryCode string) error {
-	feedData, err := advisoryfeed.Search(ctx, url, countryCode)
+// appendAdvisory pulls advisory information and adds it to the history for
+// the specified city. Advisories older than the retention period are removed.
+// A notification is delivered when the score changes by more than the alert
+// threshold.
+func (l loader) appendAdvisory(ctx context.Context, traceID string, url string, cty city.City, countryCode string, retention time.Duration, alert Alert) error {
+	feedData, err := searchAdvisory(ctx, url, countryCode)
 	if err != nil {
 		return errors.Wrap(err, "searching advisory")
 	}
 
-	newAdvisory := marshalAdvisory(feedData, cityID)
-	newAdvisory, err = l.store.advisory.Replace(ctx, traceID, newAdvisory)
+	oldAdvisory, errOld := l.store.advisory.QueryByCity(ctx, traceID, cty.ID)
+
+	now := time.Now()
+	newAdvisory := marshalAdvisory(feedData, cty.ID)
+	newAdvisory, err = l.store.advisory.Append(ctx, traceID, newAdvisory, now)
 	if err != nil {
-		return errors.Wrap(err, "replacing advisory")
+		return errors.Wrap(err, "appending advisory")
+	}
+
+	l.log.Info("appended advisory", "traceid", traceID, "advisory_id", newAdvisory.ID, "message", newAdvisory.Message)
+
+	if retention > 0 {
+		pruned, err := l.store.advisory.Prune(ctx, traceID, cty.ID, now.Add(-retention))
+		if err != nil {
+			return errors.Wrap(err, "pruning advisory")
+		}
+
+		l.log.Info("pruned advisory", "traceid", traceID, "city_id", cty.ID, "advisories", pruned)
+	}
+
+	if errOld == nil {
+		l.notifyAdvisory(ctx, traceID, cty, oldAdvisory, newAdvisory, alert)
 	}
 
-	log.Printf("feed: Work: Replaced Advisory: ID: %s Message: %s", newAdvisory.ID, newAdvisory.Message)
 	return nil
 }
 
+// notifyAdvisory delivers an event when the score of the new advisory has
+// moved by more than the alert threshold from the old advisory.
+func (l loader) notifyAdvisory(ctx context.Context, traceID string, cty city.City, oldAdvisory advisory.Advisory, newAdvisory advisory.Advisory, alert Alert) {
+	if alert.Notifier == nil {
+		return
+	}
+
+	if math.Abs(newAdvisory.Score-oldAdvisory.Score) <= alert.AdvisoryThreshold {
+		return
+	}
+
+	evt := notify.Event{
+		Type:        notify.TypeAdvisoryScoreChanged,
+		TraceID:     traceID,
+		CityID:      cty.ID,
+		CityName:    cty.Name,
+		CountryCode: newAdvisory.CountryCode,
+		OldScore:    oldAdvisory.Score,
+		NewScore:    newAdvisory.Score,
+		Message:     newAdvisory.Message,
+		Time:        newAdvisory.RecordedAt,
+	}
+
+	// A failed notification should not stop the rest of the data from loading.
+	if err := alert.Notifier.Notify(ctx, evt); err != nil {
+		l.log.Error("notify advisory", "traceid", traceID, "advisory_id", newAdvisory.ID, "error", err)
+	}
+}
+
 // upsertPlaces pulls place information and adds new places to the specified city.
 func (l loader) upsertPlaces(ctx context.Context, traceID string, apiKey string, cty city.City, categories []string, radius uint) error {
 	client, err := maps.NewClient(maps.WithAPIKey(apiKey))
@@ -201,11 +287,11 @@ func (l loader) upsertPlaces(ctx context.Context, traceID string, apiKey string,
 			Keyword: category,
 			Radius:  radius,
 		}
-		log.Printf("feed: Work: Search Places: filter: %v]", filter)
+		l.log.Info("search places", "traceid", traceID, "category", filter.Keyword, "radius", filter.Radius)
 
 		// Only store up to the first 20 places.
 		for i := 0; i < 1; i++ {
-			feedList, errRet := placesfeed.Search(ctx, client, &filter)
+			feedList, errRet := searchPlaces(ctx, client, &filter)
 			if errRet != nil && errRet != io.EOF {
 				return errors.Wrap(err, "searching places")
 			}
@@ -216,7 +302,7 @@ func (l loader) upsertPlaces(ctx context.Context, traceID string, apiKey string,
 					return errors.Wrapf(err, "adding place: %s", newPlace.Name)
 				}
 
-				log.Printf("feed: Work: Added Place: ID: %s Name: %s", newPlace.ID, newPlace.Name)
+				l.log.Info("added place", "traceid", traceID, "place_id", newPlace.ID, "name", newPlace.Name)
 			}
 
 			if errRet == io.EOF {
@@ -227,3 +313,50 @@ func (l loader) upsertPlaces(ctx context.Context, traceID string, apiKey string,
 
 	return nil
 }
+
+// =============================================================================
+
+// searchWeather requests the weather feed within its own span and records
+// the call in the metrics.
+func searchWeather(ctx context.Context, apiKey string, url string, lat float64, lng float64) (weatherfeed.Weather, error) {
+	ctx, span := tracer.Start(ctx, "feed.weather")
+	defer span.End()
+
+	start := time.Now()
+	feedData, err := weatherfeed.Search(ctx, apiKey, url, lat, lng)
+	metrics.AddFeedCall("weather", time.Since(start), err)
+	span.RecordError(err)
+	return feedData, err
+}
+
+// searchAdvisory requests the advisory feed within its own span and records
+// the call in the metrics.
+func searchAdvisory(ctx context.Context, url string, countryCode string) (advisoryfeed.Advisory, error) {
+	ctx, span := tracer.Start(ctx, "feed.advisory")
+	span.SetAttribute("country_code", countryCode)
+	defer span.End()
+
+	start := time.Now()
+	feedData, err := advisoryfeed.Search(ctx, url, countryCode)
+	metrics.AddFeedCall("advisory", time.Since(start), err)
+	span.RecordError(err)
+	return feedData, err
+}
+
+// searchPlaces requests a page of the places feed within its own span and
+// records the call in the metrics. Reaching the last page is not a failure.
+func searchPlaces(ctx context.Context, client *maps.Client, filter *placesfeed.Filter) ([]placesfeed.Place, error) {
+	ctx, span := tracer.Start(ctx, "feed.places")
+	span.SetAttribute("keyword", filter.Keyword)
+	defer span.End()
+
+	start := time.Now()
+	feedList, err := placesfeed.Search(ctx, client, filter)
+	if err == io.EOF {
+		metrics.AddFeedCall("places", time.Since(start), nil)
+		return feedList, err
+	}
+	metrics.AddFeedCall("places", time.Since(start), err)
+	span.RecordError(err)
+	return feedList, err
+}
@@ -0,0 +1,122 @@
+package loader
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/business/data/advisory"
+	"github.com/dgraph-io/travel/business/data/city"
+	"github.com/dgraph-io/travel/business/feeds/notify"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/pkg/errors"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// recordSink keeps every event it is sent.
+type recordSink struct {
+	events []notify.Event
+	err    error
+}
+
+func (s *recordSink) Send(ctx context.Context, evt notify.Event) error {
+	s.events = append(s.events, evt)
+	return s.err
+}
+
+// TestNotifyAdvisory validates events are only delivered when the score
+// moves by more than the alert threshold.
+func TestNotifyAdvisory(t *testing.T) {
+	log, err := logger.New(io.Discard, logger.Config{Service: "TEST"})
+	if err != nil {
+		t.Fatalf("Should be able to construct a logger: %v", err)
+	}
+	l := loader{log: log}
+
+	cty := city.City{ID: "0x01", Name: "sydney"}
+	recorded := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name      string
+		oldScore  float64
+		newScore  float64
+		threshold float64
+		notified  bool
+	}{
+		{"unchanged", 2.5, 2.5, 1.0, false},
+		{"below threshold", 2.5, 3.0, 1.0, false},
+		{"equal to threshold", 2.5, 3.5, 1.0, false},
+		{"above threshold", 2.5, 3.75, 1.0, true},
+		{"negative equal to threshold", 3.5, 2.5, 1.0, false},
+		{"negative above threshold", 3.75, 2.5, 1.0, true},
+		{"zero threshold", 2.5, 2.75, 0, true},
+	}
+
+	t.Log("Given the need to alert on advisory score changes.")
+	{
+		for testID, tst := range tt {
+			t.Logf("\tTest %d:\tWhen the score moves %v -> %v with threshold %v (%s).", testID, tst.oldScore, tst.newScore, tst.threshold, tst.name)
+			{
+				sink := recordSink{}
+				alert := Alert{
+					AdvisoryThreshold: tst.threshold,
+					Notifier:          notify.New(&sink),
+				}
+
+				oldAdv := advisory.Advisory{ID: "0x02", Score: tst.oldScore}
+				newAdv := advisory.Advisory{ID: "0x03", CountryCode: "AU", Message: "msg", Score: tst.newScore, RecordedAt: recorded}
+
+				l.notifyAdvisory(context.Background(), "traceid", cty, oldAdv, newAdv, alert)
+
+				if got := len(sink.events) == 1; got != tst.notified {
+					t.Fatalf("\t%s\tTest %d:\tShould notify %v : got %d events.", failed, testID, tst.notified, len(sink.events))
+				}
+				t.Logf("\t%s\tTest %d:\tShould notify %v.", success, testID, tst.notified)
+
+				if !tst.notified {
+					continue
+				}
+
+				evt := sink.events[0]
+				exp := notify.Event{
+					Type:        notify.TypeAdvisoryScoreChanged,
+					TraceID:     "traceid",
+					CityID:      cty.ID,
+					CityName:    cty.Name,
+					CountryCode: "AU",
+					OldScore:    tst.oldScore,
+					NewScore:    tst.newScore,
+					Message:     "msg",
+					Time:        recorded,
+				}
+				if evt != exp {
+					t.Fatalf("\t%s\tTest %d:\tShould deliver the expected event : got %+v exp %+v", failed, testID, evt, exp)
+				}
+				t.Logf("\t%s\tTest %d:\tShould deliver the expected event.", success, testID)
+			}
+		}
+
+		testID := len(tt)
+		t.Logf("\tTest %d:\tWhen no notifier is configured or the sink fails.", testID)
+		{
+			oldAdv := advisory.Advisory{Score: 1}
+			newAdv := advisory.Advisory{Score: 5}
+
+			l.notifyAdvisory(context.Background(), "traceid", cty, oldAdv, newAdv, Alert{AdvisoryThreshold: 1})
+			t.Logf("\t%s\tTest %d:\tShould skip the notification without a notifier.", success, testID)
+
+			sink := recordSink{err: errors.New("sink down")}
+			l.notifyAdvisory(context.Background(), "traceid", cty, oldAdv, newAdv, Alert{AdvisoryThreshold: 1, Notifier: notify.New(&sink)})
+			if len(sink.events) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould attempt delivery once : got %d.", failed, testID, len(sink.events))
+			}
+			t.Logf("\t%s\tTest %d:\tShould log and ignore a failed delivery.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package notify provides support for delivering change events detected
+// while loading feed data to a set of registered sinks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/pkg/errors"
+)
+
+// These constants represent the set of event types.
+const (
+	TypeAdvisoryScoreChanged = "advisory.score.changed"
+)
+
+// Event represents a change that was detected in the feed data for a city.
+type Event struct {
+	Type        string    `json:"type"`
+	TraceID     string    `json:"trace_id"`
+	CityID      string    `json:"city_id"`
+	CityName    string    `json:"city_name"`
+	CountryCode string    `json:"country_code"`
+	OldScore    float64   `json:"old_score"`
+	NewScore    float64   `json:"new_score"`
+	Message     string    `json:"message"`
+	Time        time.Time `json:"time"`
+}
+
+// Sink declares the behavior for delivering an event somewhere.
+type Sink interface {
+	Send(ctx context.Context, evt Event) error
+}
+
+// Notifier delivers events to every registered sink.
+type Notifier struct {
+	mu    sync.RWMutex
+	sinks []Sink
+}
+
+// New constructs a Notifier with an initial set of sinks.
+func New(sinks ...Sink) *Notifier {
+	return &Notifier{
+		sinks: sinks,
+	}
+}
+
+// Register adds a sink to the set of sinks receiving events.
+func (n *Notifier) Register(sink Sink) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.sinks = append(n.sinks, sink)
+}
+
+// Notify delivers the event to every registered sink. A failing sink does
+// not stop delivery to the others. All the failures are returned together.
+func (n *Notifier) Notify(ctx context.Context, evt Event) error {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	var failed []string
+	for _, sink := range n.sinks {
+		if err := sink.Send(ctx, evt); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("delivering event to %d sink(s): %v", len(failed), failed)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// LogSink writes events to a logger.
+type LogSink struct {
+	log *logger.Logger
+}
+
+// NewLog constructs a sink that writes events to the specified logger.
+func NewLog(log *logger.Logger) *LogSink {
+	return &LogSink{
+		log: log,
+	}
+}
+
+// Send implements the Sink interface.
+func (s *LogSink) Send(ctx context.Context, evt Event) error {
+	s.log.Info("notify", "traceid", evt.TraceID, "type", evt.Type, "city", evt.CityName, "country", evt.CountryCode, "old_score", evt.OldScore, "new_score", evt.NewScore)
+	return nil
+}
+
+// =============================================================================
+
+// WebhookSink posts events as JSON documents to a URL.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook constructs a sink that posts events to the specified url.
+func NewWebhook(url string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send implements the Sink interface.
+func (s *WebhookSink) Send(ctx context.Context, evt Event) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return errors.Wrap(err, "marshal event")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "new request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "client do")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// FileSink appends events as JSON lines to a file. This is useful for
+// local testing.
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile constructs a sink that appends events to the specified file.
+func NewFile(path string) *FileSink {
+	return &FileSink{
+		path: path,
+	}
+}
+
+// Send implements the Sink interface.
+func (s *FileSink) Send(ctx context.Context, evt Event) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return errors.Wrap(err, "marshal event")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "opening event file")
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, "writing event")
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/business/feeds/notify"
+	"github.com/google/go-cmp/cmp"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// TestNotify validates events are delivered to every registered sink.
+func TestNotify(t *testing.T) {
+	t.Log("Given the need to deliver advisory change events.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a webhook and a file sink.", testID)
+		{
+			received := make(chan notify.Event, 1)
+			f := func(w http.ResponseWriter, r *http.Request) {
+				var evt notify.Event
+				if err := json.NewDecoder(r.Body).Decode(&evt); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				received <- evt
+			}
+			server := httptest.NewServer(http.HandlerFunc(f))
+			t.Cleanup(server.Close)
+
+			file := filepath.Join(t.TempDir(), "events.json")
+			n := notify.New(notify.NewWebhook(server.URL))
+			n.Register(notify.NewFile(file))
+
+			evt := notify.Event{
+				Type:        notify.TypeAdvisoryScoreChanged,
+				TraceID:     "00000000-0000-0000-0000-000000000000",
+				CityID:      "0x01",
+				CityName:    "sydney",
+				CountryCode: "AU",
+				OldScore:    4.5,
+				NewScore:    2.0,
+				Time:        time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC),
+			}
+
+			if err := n.Notify(context.Background(), evt); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to notify the sinks: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to notify the sinks.", success, testID)
+
+			if diff := cmp.Diff(evt, <-received); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get back the event from the webhook. Diff:\n%s", failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get back the event from the webhook.", success, testID)
+
+			data, err := os.Open(file)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to open the event file: %v", failed, testID, err)
+			}
+			defer data.Close()
+
+			var got notify.Event
+			scanner := bufio.NewScanner(data)
+			if !scanner.Scan() {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read an event from the file.", failed, testID)
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to decode the event from the file: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to read an event from the file.", success, testID)
+
+			if diff := cmp.Diff(evt, got); diff != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get back the event from the file. Diff:\n%s", failed, testID, diff)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get back the event from the file.", success, testID)
+		}
+	}
+}
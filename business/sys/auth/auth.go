@@ -2,9 +2,12 @@
 package auth
 
 import (
-	"crypto/rsa"
+	"context"
+	"crypto"
+	"time"
 
 	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
@@ -14,15 +17,46 @@ const (
 	RoleUser  = "USER"
 )
 
+// These constants represent the set of scopes. A scope grants permission
+// for a single kind of action regardless of the role.
+const (
+	ScopeFeedUpload = "feed:upload"
+	ScopeCityWrite  = "city:write"
+	ScopeUserRead   = "user:read"
+	ScopeUserWrite  = "user:write"
+)
+
+// roleScopes is the set of scopes granted to each role by default.
+var roleScopes = map[string][]string{
+	RoleAdmin: {ScopeFeedUpload, ScopeCityWrite, ScopeUserRead, ScopeUserWrite},
+	RoleUser:  {},
+}
+
+// RoleScopes returns the scopes granted by default to the specified role.
+func RoleScopes(role string) []string {
+	scopes := make([]string, len(roleScopes[role]))
+	copy(scopes, roleScopes[role])
+	return scopes
+}
+
+// Set of error variables for token validation.
+var (
+	ErrForbidden = errors.New("attempted action is not allowed")
+	ErrRevoked   = errors.New("token has been revoked")
+)
+
 // ctxKey represents the type of value for the context key.
 type ctxKey int
 
 // Key is used to store/retrieve a Claims value from a context.Context.
 const Key ctxKey = 1
 
-// StandardClaims represents claims for the applications.
+// StandardClaims represents claims for the applications. The email lets the
+// database apply rules to the records owned by the user.
 type StandardClaims struct {
-	Role string `json:"ROLE"`
+	Role   string   `json:"ROLE"`
+	Scopes []string `json:"SCOPES,omitempty"`
+	Email  string   `json:"EMAIL,omitempty"`
 }
 
 // Claims represents the authorization claims transmitted via a JWT.
@@ -31,35 +65,93 @@ type Claims struct {
 	Auth StandardClaims
 }
 
-// Authorized returns true if the claim matches the provided role.
-func (c Claims) Authorized(role string) bool {
-	return c.Auth.Role == role
+// Authorized returns true if the claim matches at least one of the
+// provided roles.
+func (c Claims) Authorized(roles ...string) bool {
+	for _, role := range roles {
+		if c.Auth.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope returns true if the claims hold every one of the provided
+// scopes. Tokens issued without a list of scopes are granted the default
+// scopes of their role.
+func (c Claims) HasScope(scopes ...string) bool {
+	granted := c.Auth.Scopes
+	if granted == nil {
+		granted = roleScopes[c.Auth.Role]
+	}
+
+	for _, scope := range scopes {
+		var found bool
+		for _, g := range granted {
+			if g == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 // KeyLookup declares a method set of behavior for looking up
 // private and public keys for JWT use.
 type KeyLookup interface {
-	PrivateKey(kid string) (*rsa.PrivateKey, error)
-	PublicKey(kid string) (*rsa.PublicKey, error)
+	PrivateKey(kid string) (crypto.Signer, error)
+	PublicKey(kid string) (crypto.PublicKey, error)
+}
+
+// ActiveKeyLookup declares a method set of behavior for looking up the kid
+// of the key used to sign new tokens, when keys can be rotated.
+type ActiveKeyLookup interface {
+	ActiveKID() string
+}
+
+// RevocationList declares a method set of behavior for revoking tokens and
+// checking if a token has been revoked, either by its id (jti) or because
+// every token issued to the subject before a point in time was revoked.
+type RevocationList interface {
+	Revoke(ctx context.Context, traceID string, jti string, expiresAt time.Time) error
+	RevokeSubject(ctx context.Context, traceID string, subject string, before time.Time, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, traceID string, jti string, subject string, issuedAt time.Time) (bool, error)
 }
 
 // Auth is used to authenticate clients. It can generate a token for a
 // set of user claims and recreate the claims by parsing the token.
 type Auth struct {
-	algorithm string
-	keyLookup KeyLookup
-	method    jwt.SigningMethod
-	keyFunc   func(t *jwt.Token) (interface{}, error)
-	parser    *jwt.Parser
+	rsaAlgorithm string
+	keyLookup    KeyLookup
+	revocations  RevocationList
+	keyFunc      func(t *jwt.Token) (interface{}, error)
+	parser       *jwt.Parser
 }
 
-// New creates an Auth to support authentication/authorization.
-func New(algorithm string, keyLookup KeyLookup) (*Auth, error) {
-	method := jwt.GetSigningMethod(algorithm)
-	if method == nil {
+// New creates an Auth to support authentication/authorization. Each key signs
+// and verifies tokens with the algorithm of its type so the keystore can hold
+// different types of keys. RSA keys use the specified algorithm when it's an
+// RSA algorithm and RS256 otherwise. The revocation list is optional. When
+// nil, tokens are never considered revoked.
+func New(algorithm string, keyLookup KeyLookup, revocations RevocationList) (*Auth, error) {
+	if jwt.GetSigningMethod(algorithm) == nil {
 		return nil, errors.Errorf("unknown algorithm %v", algorithm)
 	}
 
+	rsaAlgorithm := "RS256"
+	switch jwt.GetSigningMethod(algorithm).(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		rsaAlgorithm = algorithm
+	}
+
+	// The algorithm used to sign the JWT must be validated to avoid a
+	// critical vulnerability:
+	// https://auth0.com/blog/critical-vulnerabilities-in-json-web-token-libraries/
+	// Only the algorithm of the key named by the kid is accepted.
 	keyFunc := func(t *jwt.Token) (interface{}, error) {
 		kid, ok := t.Header["kid"]
 		if !ok {
@@ -69,35 +161,61 @@ func New(algorithm string, keyLookup KeyLookup) (*Auth, error) {
 		if !ok {
 			return nil, errors.New("user token key id (kid) must be string")
 		}
-		return keyLookup.PublicKey(kidID)
+
+		publicKey, err := keyLookup.PublicKey(kidID)
+		if err != nil {
+			return nil, err
+		}
+
+		alg, err := keyAlgorithm(publicKey, rsaAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != alg {
+			return nil, errors.Errorf("token signed with %s but key %s uses %s", t.Method.Alg(), kidID, alg)
+		}
+
+		return publicKey, nil
 	}
 
-	// Create the token parser to use. The algorithm used to sign the JWT must be
-	// validated to avoid a critical vulnerability:
-	// https://auth0.com/blog/critical-vulnerabilities-in-json-web-token-libraries/
-	parser := jwt.NewParser(jwt.WithValidMethods([]string{algorithm}), jwt.WithAudience("student"))
+	// Create the token parser to use. It accepts the algorithms of the key
+	// types we support, the key function then checks the algorithm matches
+	// the key.
+	parser := jwt.NewParser(jwt.WithValidMethods(algorithms(rsaAlgorithm)), jwt.WithAudience("student"))
 
 	a := Auth{
-		algorithm: algorithm,
-		keyLookup: keyLookup,
-		method:    method,
-		keyFunc:   keyFunc,
-		parser:    parser,
+		rsaAlgorithm: rsaAlgorithm,
+		keyLookup:    keyLookup,
+		revocations:  revocations,
+		keyFunc:      keyFunc,
+		parser:       parser,
 	}
 
 	return &a, nil
 }
 
-// GenerateToken generates a signed JWT token string representing the user Claims.
+// GenerateToken generates a signed JWT token string representing the user
+// Claims. The token is signed with the algorithm of the key. A unique token
+// id (jti) is assigned when the claims don't have one so the token can be
+// revoked.
 func (a *Auth) GenerateToken(kid string, claims Claims) (string, error) {
-	token := jwt.NewWithClaims(a.method, claims)
-	token.Header["kid"] = kid
+	if claims.ID == "" {
+		claims.ID = uuid.New().String()
+	}
 
 	privateKey, err := a.keyLookup.PrivateKey(kid)
 	if err != nil {
 		return "", errors.New("kid lookup failed")
 	}
 
+	alg, err := keyAlgorithm(privateKey.Public(), a.rsaAlgorithm)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(alg), claims)
+	token.Header["kid"] = kid
+
 	str, err := token.SignedString(privateKey)
 	if err != nil {
 		return "", errors.Wrap(err, "signing token")
@@ -106,9 +224,26 @@ func (a *Auth) GenerateToken(kid string, claims Claims) (string, error) {
 	return str, nil
 }
 
+// ActiveKID returns the kid of the key that should be used to sign new
+// tokens. The key lookup provided to New must implement the ActiveKeyLookup
+// interface.
+func (a *Auth) ActiveKID() (string, error) {
+	lookup, ok := a.keyLookup.(ActiveKeyLookup)
+	if !ok {
+		return "", errors.New("key lookup has no active key")
+	}
+
+	kid := lookup.ActiveKID()
+	if kid == "" {
+		return "", errors.New("no active key")
+	}
+
+	return kid, nil
+}
+
 // ValidateToken recreates the Claims that were used to generate a token. It
-// verifies that the token was signed using our key.
-func (a *Auth) ValidateToken(tokenStr string) (Claims, error) {
+// verifies that the token was signed using our key and has not been revoked.
+func (a *Auth) ValidateToken(ctx context.Context, traceID string, tokenStr string) (Claims, error) {
 	var claims Claims
 	token, err := a.parser.ParseWithClaims(tokenStr, &claims, a.keyFunc)
 	if err != nil {
@@ -119,5 +254,57 @@ func (a *Auth) ValidateToken(tokenStr string) (Claims, error) {
 		return Claims{}, errors.New("invalid token")
 	}
 
+	if a.revocations != nil {
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
+		}
+
+		revoked, err := a.revocations.IsRevoked(ctx, traceID, claims.ID, claims.Subject, issuedAt)
+		if err != nil {
+			return Claims{}, errors.Wrap(err, "checking revocation")
+		}
+		if revoked {
+			return Claims{}, ErrRevoked
+		}
+	}
+
 	return claims, nil
 }
+
+// Revoke revokes the token the claims were parsed from. The token can't be
+// validated again even though it hasn't expired.
+func (a *Auth) Revoke(ctx context.Context, traceID string, claims Claims) error {
+	if a.revocations == nil {
+		return errors.New("revocation list not configured")
+	}
+	if claims.ID == "" {
+		return errors.New("token has no id")
+	}
+
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	return a.revocations.Revoke(ctx, traceID, claims.ID, expiresAt)
+}
+
+// RevokeSubject revokes every token issued to the subject at or before the
+// specified time. The revocation is kept until the specified expiration,
+// which should be no earlier than the expiration of the last token issued.
+func (a *Auth) RevokeSubject(ctx context.Context, traceID string, subject string, before time.Time, expiresAt time.Time) error {
+	if a.revocations == nil {
+		return errors.New("revocation list not configured")
+	}
+
+	return a.revocations.RevokeSubject(ctx, traceID, subject, before, expiresAt)
+}
+
+// IssuedAfter returns the time a new token must be issued at so it isn't
+// revoked by a call to RevokeSubject with the specified time. The iat claim
+// loses sub-second precision when it is encoded, so the token is issued at
+// the next whole second which is always after the revocation.
+func IssuedAfter(before time.Time) time.Time {
+	return before.Truncate(time.Second).Add(time.Second)
+}
@@ -1,12 +1,21 @@
 package auth_test
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
 	"testing"
 	"time"
 
 	"github.com/dgraph-io/travel/business/sys/auth"
+	"github.com/dgraph-io/travel/foundation/keystore"
 	"github.com/dgrijalva/jwt-go/v4"
 )
 
@@ -29,7 +38,7 @@ func TestAuth(t *testing.T) {
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to create a private key.", success, testID)
 
-			a, err := auth.New("RS256", &keyStore{pk: privateKey})
+			a, err := auth.New("RS256", &keyStore{pk: privateKey}, nil)
 			if err != nil {
 				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
 			}
@@ -53,7 +62,7 @@ func TestAuth(t *testing.T) {
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to generate a JWT.", success, testID)
 
-			parsedClaims, err := a.ValidateToken(token)
+			parsedClaims, err := a.ValidateToken(context.Background(), "00000000-0000-0000-0000-000000000000", token)
 			if err != nil {
 				t.Fatalf("\t%s\tTest %d:\tShould be able to parse the claims: %v", failed, testID, err)
 			}
@@ -69,16 +78,525 @@ func TestAuth(t *testing.T) {
 	}
 }
 
+func TestScopes(t *testing.T) {
+	type tableTest struct {
+		name   string
+		claims auth.StandardClaims
+		scopes []string
+		exp    bool
+	}
+
+	tt := []tableTest{
+		{"granted", auth.StandardClaims{Role: auth.RoleUser, Scopes: []string{auth.ScopeCityWrite}}, []string{auth.ScopeCityWrite}, true},
+		{"missing", auth.StandardClaims{Role: auth.RoleUser, Scopes: []string{auth.ScopeCityWrite}}, []string{auth.ScopeFeedUpload}, false},
+		{"partial", auth.StandardClaims{Role: auth.RoleUser, Scopes: []string{auth.ScopeUserRead}}, []string{auth.ScopeUserRead, auth.ScopeUserWrite}, false},
+		{"admin role", auth.StandardClaims{Role: auth.RoleAdmin}, []string{auth.ScopeFeedUpload, auth.ScopeUserWrite}, true},
+		{"user role", auth.StandardClaims{Role: auth.RoleUser}, []string{auth.ScopeUserRead}, false},
+		{"explicit empty", auth.StandardClaims{Role: auth.RoleAdmin, Scopes: []string{}}, []string{auth.ScopeUserRead}, false},
+	}
+
+	t.Log("Given the need to check the scopes granted to a user.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen handling %s scopes.", testID, test.name)
+			{
+				claims := auth.Claims{Auth: test.claims}
+				if got := claims.HasScope(test.scopes...); got != test.exp {
+					t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+					t.Logf("\t\tTest %d:\texp: %v", testID, test.exp)
+					t.Fatalf("\t%s\tTest %d:\tShould get the expected result.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould get the expected result.", success, testID)
+			}
+		}
+	}
+}
+
+func TestRevocations(t *testing.T) {
+	t.Log("Given the need to be able to revoke issued tokens.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a revoked token.", testID)
+		{
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create a private key.", success, testID)
+
+			revocations := auth.NewRevocations()
+			a, err := auth.New("RS256", &keyStore{pk: privateKey}, revocations)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create an authenticator.", success, testID)
+
+			claims := auth.Claims{
+				StandardClaims: jwt.StandardClaims{
+					Issuer:    "travel project",
+					Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+					ExpiresAt: jwt.At(time.Now().Add(time.Hour)),
+					IssuedAt:  jwt.At(time.Now().Add(-time.Minute)),
+				},
+				Auth: auth.StandardClaims{
+					Role: auth.RoleUser,
+				},
+			}
+
+			token, err := a.GenerateToken("", claims)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to generate a JWT.", success, testID)
+
+			parsedClaims, err := a.ValidateToken(context.Background(), "00000000-0000-0000-0000-000000000000", token)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to parse the claims: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to parse the claims.", success, testID)
+
+			if parsedClaims.ID == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould have a token id assigned.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have a token id assigned.", success, testID)
+
+			if err := a.Revoke(context.Background(), "00000000-0000-0000-0000-000000000000", parsedClaims); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the token: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to revoke the token.", success, testID)
+
+			if _, err := a.ValidateToken(context.Background(), "00000000-0000-0000-0000-000000000000", token); err != auth.ErrRevoked {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the revoked token: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the revoked token.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen handling a revoked subject.", testID)
+		{
+			revocations := auth.NewRevocations()
+			const subject = "5cf37266-3473-4006-984f-9325122678b7"
+			now := time.Now()
+
+			if err := revocations.RevokeSubject(context.Background(), "00000000-0000-0000-0000-000000000000", subject, now, now.Add(time.Hour)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the subject: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to revoke the subject.", success, testID)
+
+			revoked, err := revocations.IsRevoked(context.Background(), "00000000-0000-0000-0000-000000000000", "1", subject, now.Add(-time.Minute))
+			if err != nil || !revoked {
+				t.Fatalf("\t%s\tTest %d:\tShould revoke tokens issued before the revocation: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould revoke tokens issued before the revocation.", success, testID)
+
+			revoked, err = revocations.IsRevoked(context.Background(), "00000000-0000-0000-0000-000000000000", "2", subject, now.Add(time.Minute))
+			if err != nil || revoked {
+				t.Fatalf("\t%s\tTest %d:\tShould not revoke tokens issued after the revocation: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not revoke tokens issued after the revocation.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen issuing a token right after revoking its subject.", testID)
+		{
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+
+			revocations := auth.NewRevocations()
+			a, err := auth.New("RS256", &keyStore{pk: privateKey}, revocations)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			// The iat claim loses precision when encoded, so try many
+			// revocation times spread across a second.
+			for i := 0; i < 200; i++ {
+				subject := fmt.Sprintf("subject-%d", i)
+				now := time.Now().Add(time.Duration(i) * 4999 * time.Microsecond)
+
+				if err := a.RevokeSubject(context.Background(), "00000000-0000-0000-0000-000000000000", subject, now, now.Add(time.Hour)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the subject: %v", failed, testID, err)
+				}
+
+				newToken := func(issuedAt time.Time) string {
+					claims := auth.Claims{
+						StandardClaims: jwt.StandardClaims{
+							Issuer:    "travel project",
+							Subject:   subject,
+							ExpiresAt: jwt.At(issuedAt.Add(time.Hour)),
+							IssuedAt:  jwt.At(issuedAt),
+						},
+						Auth: auth.StandardClaims{
+							Role: auth.RoleUser,
+						},
+					}
+					token, err := a.GenerateToken("", claims)
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+					}
+					return token
+				}
+
+				if _, err := a.ValidateToken(context.Background(), "00000000-0000-0000-0000-000000000000", newToken(auth.IssuedAfter(now))); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould accept the token issued after the revocation at %s: %v", failed, testID, now.Format(time.RFC3339Nano), err)
+				}
+
+				if _, err := a.ValidateToken(context.Background(), "00000000-0000-0000-0000-000000000000", newToken(now.Add(-time.Millisecond))); err != auth.ErrRevoked {
+					t.Fatalf("\t%s\tTest %d:\tShould reject the token issued before the revocation at %s: %v", failed, testID, now.Format(time.RFC3339Nano), err)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept the token issued after the revocation.", success, testID)
+			t.Logf("\t%s\tTest %d:\tShould reject the token issued before the revocation.", success, testID)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	t.Log("Given the need to publish the public keys used to verify tokens.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a keystore with a single key.", testID)
+		{
+			const keyID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create a private key.", success, testID)
+
+			a, err := auth.New("RS256", keystore.NewMap(map[string]crypto.Signer{keyID: privateKey}), nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create an authenticator.", success, testID)
+
+			jwks, err := a.JWKS()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to build the key set: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to build the key set.", success, testID)
+
+			if len(jwks.Keys) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould have a single key: got %d", failed, testID, len(jwks.Keys))
+			}
+			t.Logf("\t%s\tTest %d:\tShould have a single key.", success, testID)
+
+			jwk := jwks.Keys[0]
+			if jwk.KeyID != keyID || jwk.Algorithm != "RS256" || jwk.Use != "sig" || jwk.KeyType != "RSA" {
+				t.Logf("\t\tTest %d:\tgot: %+v", testID, jwk)
+				t.Fatalf("\t%s\tTest %d:\tShould have the expected key fields.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have the expected key fields.", success, testID)
+
+			n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to decode the modulus: %v", failed, testID, err)
+			}
+			if new(big.Int).SetBytes(n).Cmp(privateKey.PublicKey.N) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould publish the modulus of the public key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould publish the modulus of the public key.", success, testID)
+		}
+	}
+}
+
+func TestAlgorithms(t *testing.T) {
+	type tableTest struct {
+		algorithm string
+		keyType   string
+		generate  func() (crypto.Signer, error)
+	}
+
+	tt := []tableTest{
+		{"RS256", "RSA", func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 2048) }},
+		{"ES256", "EC", func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) }},
+		{"ES384", "EC", func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P384(), rand.Reader) }},
+		{"EdDSA", "OKP", func() (crypto.Signer, error) {
+			_, pk, err := ed25519.GenerateKey(rand.Reader)
+			return pk, err
+		}},
+	}
+
+	t.Log("Given the need to sign and verify tokens with different algorithms.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen handling the %s algorithm.", testID, test.algorithm)
+			{
+				const keyID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+				privateKey, err := test.generate()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to create a private key.", success, testID)
+
+				a, err := auth.New(test.algorithm, keystore.NewMap(map[string]crypto.Signer{keyID: privateKey}), nil)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to create an authenticator.", success, testID)
+
+				claims := auth.Claims{
+					StandardClaims: jwt.StandardClaims{
+						Issuer:    "travel project",
+						Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+						ExpiresAt: jwt.At(time.Now().Add(time.Hour)),
+						IssuedAt:  jwt.Now(),
+					},
+					Auth: auth.StandardClaims{
+						Role: auth.RoleUser,
+					},
+				}
+
+				token, err := a.GenerateToken(keyID, claims)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to generate a JWT.", success, testID)
+
+				parsedClaims, err := a.ValidateToken(context.Background(), "00000000-0000-0000-0000-000000000000", token)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to parse the claims: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to parse the claims.", success, testID)
+
+				if exp, got := claims.Subject, parsedClaims.Subject; exp != got {
+					t.Logf("\t\tTest %d:\texp: %v", testID, exp)
+					t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+					t.Fatalf("\t%s\tTest %d:\tShould have the expected subject.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould have the expected subject.", success, testID)
+
+				jwks, err := a.JWKS()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to build the key set: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to build the key set.", success, testID)
+
+				if got := jwks.Keys[0]; got.KeyType != test.keyType || got.Algorithm != test.algorithm {
+					t.Logf("\t\tTest %d:\tgot: %+v", testID, got)
+					t.Fatalf("\t%s\tTest %d:\tShould publish the key as %s.", failed, testID, test.keyType)
+				}
+				t.Logf("\t%s\tTest %d:\tShould publish the key as %s.", success, testID, test.keyType)
+			}
+		}
+	}
+}
+
+func TestCachedRevocations(t *testing.T) {
+	t.Log("Given the need to avoid looking up revocations on every request.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling cached lookups.", testID)
+		{
+			ctx := context.Background()
+			const subject = "5cf37266-3473-4006-984f-9325122678b7"
+			issuedAt := time.Now().Add(-time.Minute)
+
+			list := countingList{RevocationList: auth.NewRevocations()}
+			cache := auth.NewCachedRevocations(&list, time.Hour)
+
+			for i := 0; i < 2; i++ {
+				revoked, err := cache.IsRevoked(ctx, "00000000-0000-0000-0000-000000000000", "1234", subject, issuedAt)
+				if err != nil || revoked {
+					t.Fatalf("\t%s\tTest %d:\tShould not report the token as revoked: %v", failed, testID, err)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould not report the token as revoked.", success, testID)
+
+			if list.lookups != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould look up the token once: %d", failed, testID, list.lookups)
+			}
+			t.Logf("\t%s\tTest %d:\tShould look up the token once.", success, testID)
+
+			if err := cache.RevokeSubject(ctx, "00000000-0000-0000-0000-000000000000", subject, time.Now(), time.Now().Add(time.Hour)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the subject: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to revoke the subject.", success, testID)
+
+			revoked, err := cache.IsRevoked(ctx, "00000000-0000-0000-0000-000000000000", "1234", subject, issuedAt)
+			if err != nil || !revoked {
+				t.Fatalf("\t%s\tTest %d:\tShould report the token as revoked right away: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould report the token as revoked right away.", success, testID)
+		}
+	}
+}
+
+func TestMixedKeys(t *testing.T) {
+	t.Log("Given the need to rotate between different types of keys.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a keystore with an RSA and an EC key.", testID)
+		{
+			rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an RSA key: %v", failed, testID, err)
+			}
+			ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an EC key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create the keys.", success, testID)
+
+			keys := map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey}
+			a, err := auth.New("RS256", keystore.NewMap(keys), nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create an authenticator.", success, testID)
+
+			claims := auth.Claims{
+				StandardClaims: jwt.StandardClaims{
+					Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+					ExpiresAt: jwt.At(time.Now().Add(time.Hour)),
+					IssuedAt:  jwt.Now(),
+				},
+				Auth: auth.StandardClaims{
+					Role: auth.RoleUser,
+				},
+			}
+
+			for kid, alg := range map[string]string{"rsa": "RS256", "ec": "ES384"} {
+				token, err := a.GenerateToken(kid, claims)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to sign with the %s key: %v", failed, testID, kid, err)
+				}
+				if _, err := a.ValidateToken(context.Background(), "00000000-0000-0000-0000-000000000000", token); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to validate the token of the %s key: %v", failed, testID, kid, err)
+				}
+				parsed, _, err := new(jwt.Parser).ParseUnverified(token, &auth.Claims{})
+				if err != nil || parsed.Method.Alg() != alg {
+					t.Fatalf("\t%s\tTest %d:\tShould sign with %s using the %s key: %v", failed, testID, alg, kid, err)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign and validate tokens with the algorithm of each key.", success, testID)
+
+			jwks, err := a.JWKS()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to build the key set: %v", failed, testID, err)
+			}
+			for _, jwk := range jwks.Keys {
+				if (jwk.KeyID == "rsa" && jwk.Algorithm != "RS256") || (jwk.KeyID == "ec" && jwk.Algorithm != "ES384") {
+					t.Logf("\t\tTest %d:\tgot: %+v", testID, jwk)
+					t.Fatalf("\t%s\tTest %d:\tShould publish the algorithm of each key.", failed, testID)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould publish the algorithm of each key.", success, testID)
+
+			forged := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+			forged.Header["kid"] = "ec"
+			ecKey256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an EC key: %v", failed, testID, err)
+			}
+			token, err := forged.SignedString(ecKey256)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to sign a token: %v", failed, testID, err)
+			}
+			if _, err := a.ValidateToken(context.Background(), "00000000-0000-0000-0000-000000000000", token); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject a token using another algorithm than its key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject a token using another algorithm than its key.", success, testID)
+		}
+	}
+}
+
+func TestServiceToken(t *testing.T) {
+	t.Log("Given the need to access the database as a service.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling service tokens.", testID)
+		{
+			const keyID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create a private key.", success, testID)
+
+			a, err := auth.New("RS256", keystore.NewMap(map[string]crypto.Signer{keyID: privateKey}), nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create an authenticator.", success, testID)
+
+			st := a.NewServiceToken("travel-api", time.Hour)
+			token, err := st.Token()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to sign a service token: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to sign a service token.", success, testID)
+
+			if err := a.CheckServiceToken(token); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept the service token: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept the service token.", success, testID)
+
+			again, err := st.Token()
+			if err != nil || again != token {
+				t.Fatalf("\t%s\tTest %d:\tShould reuse the token within half its lifetime: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reuse the token within half its lifetime.", success, testID)
+
+			claims := auth.Claims{
+				StandardClaims: jwt.StandardClaims{
+					Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+					ExpiresAt: jwt.At(time.Now().Add(time.Hour)),
+					IssuedAt:  jwt.Now(),
+				},
+				Auth: auth.StandardClaims{
+					Role: auth.RoleUser,
+				},
+			}
+			userToken, err := a.GenerateToken(keyID, claims)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to generate a JWT.", success, testID)
+
+			if err := a.CheckServiceToken(userToken); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject a token without the ADMIN role.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject a token without the ADMIN role.", success, testID)
+
+			claims.Auth.Role = auth.RoleAdmin
+			claims.ExpiresAt = jwt.At(time.Now().Add(-time.Minute))
+			expired, err := a.GenerateToken(keyID, claims)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to generate a JWT.", success, testID)
+
+			if err := a.CheckServiceToken(expired); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject an expired token.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject an expired token.", success, testID)
+		}
+	}
+}
+
 // =============================================================================
 
+type countingList struct {
+	auth.RevocationList
+	lookups int
+}
+
+func (l *countingList) IsRevoked(ctx context.Context, traceID string, jti string, subject string, issuedAt time.Time) (bool, error) {
+	l.lookups++
+	return l.RevocationList.IsRevoked(ctx, "00000000-0000-0000-0000-000000000000", jti, subject, issuedAt)
+}
+
 type keyStore struct {
-	pk *rsa.PrivateKey
+	pk crypto.Signer
 }
 
-func (ks *keyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
+func (ks *keyStore) PrivateKey(kid string) (crypto.Signer, error) {
 	return ks.pk, nil
 }
 
-func (ks *keyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
-	return &ks.pk.PublicKey, nil
+func (ks *keyStore) PublicKey(kid string) (crypto.PublicKey, error) {
+	return ks.pk.Public(), nil
 }
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/pkg/errors"
+)
+
+// signingMethodEdDSA implements the EdDSA signing method (RFC 8037) using
+// Ed25519 keys. The jwt package doesn't provide it so it's registered here.
+type signingMethodEdDSA struct{}
+
+func init() {
+	jwt.RegisterSigningMethod("EdDSA", func() jwt.SigningMethod {
+		return signingMethodEdDSA{}
+	})
+}
+
+// Alg implements the jwt.SigningMethod interface.
+func (signingMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+// Verify implements the jwt.SigningMethod interface. The key must be an
+// ed25519.PublicKey or a crypto.Signer holding an Ed25519 key.
+func (signingMethodEdDSA) Verify(signingString string, signature string, key interface{}) error {
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	var publicKey ed25519.PublicKey
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		publicKey = k
+	case crypto.Signer:
+		pk, ok := k.Public().(ed25519.PublicKey)
+		if !ok {
+			return jwt.NewInvalidKeyTypeError("ed25519.PublicKey or crypto.Signer", key)
+		}
+		publicKey = pk
+	default:
+		return jwt.NewInvalidKeyTypeError("ed25519.PublicKey or crypto.Signer", key)
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return new(jwt.InvalidSignatureError)
+	}
+
+	return nil
+}
+
+// Sign implements the jwt.SigningMethod interface. The key must be a
+// crypto.Signer holding an Ed25519 key.
+func (signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", jwt.NewInvalidKeyTypeError("ed25519.PrivateKey or crypto.Signer", key)
+	}
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		return "", jwt.NewInvalidKeyTypeError("ed25519.PrivateKey or crypto.Signer", key)
+	}
+
+	// Ed25519 signs the message itself so no hash is used.
+	sig, err := signer.Sign(rand.Reader, []byte(signingString), crypto.Hash(0))
+	if err != nil {
+		return "", errors.Wrap(err, "signing")
+	}
+
+	return jwt.EncodeSegment(sig), nil
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// PublicKeyLister declares a method set of behavior for listing every public
+// key by kid so the keys can be published.
+type PublicKeyLister interface {
+	PublicKeys() map[string]crypto.PublicKey
+}
+
+// JWK represents a public key as a JSON Web Key (RFC 7517). RSA keys use the
+// n and e fields, EC keys the crv, x and y fields and Ed25519 keys (OKP) the
+// crv and x fields.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS represents a JSON Web Key Set that verifiers use to find the public
+// key for the kid in a token's header.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the set of public keys used to verify the tokens we sign. The
+// key lookup provided to New must also implement the PublicKeyLister
+// interface.
+func (a *Auth) JWKS() (JWKS, error) {
+	lister, ok := a.keyLookup.(PublicKeyLister)
+	if !ok {
+		return JWKS{}, errors.New("key lookup can't list public keys")
+	}
+
+	publicKeys := lister.PublicKeys()
+	jwks := JWKS{
+		Keys: make([]JWK, 0, len(publicKeys)),
+	}
+	for kid, publicKey := range publicKeys {
+		jwk, err := newJWK(publicKey, a.rsaAlgorithm)
+		if err != nil {
+			return JWKS{}, errors.Wrapf(err, "kid %s", kid)
+		}
+		jwk.KeyID = kid
+		jwk.Use = "sig"
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	// Keep the order stable so the document can be cached.
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID
+	})
+
+	return jwks, nil
+}
+
+// newJWK encodes the key material of a public key along with the algorithm
+// tokens signed with the key use.
+func newJWK(publicKey crypto.PublicKey, rsaAlgorithm string) (JWK, error) {
+	alg, err := keyAlgorithm(publicKey, rsaAlgorithm)
+	if err != nil {
+		return JWK{}, err
+	}
+
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch pk := publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk := JWK{
+			KeyType:   "RSA",
+			Algorithm: alg,
+			N:         encode(pk.N.Bytes()),
+			E:         encode(big.NewInt(int64(pk.E)).Bytes()),
+		}
+		return jwk, nil
+
+	case *ecdsa.PublicKey:
+		size := (pk.Curve.Params().BitSize + 7) / 8
+		x := make([]byte, size)
+		y := make([]byte, size)
+		jwk := JWK{
+			KeyType:   "EC",
+			Algorithm: alg,
+			Curve:     pk.Curve.Params().Name,
+			X:         encode(pk.X.FillBytes(x)),
+			Y:         encode(pk.Y.FillBytes(y)),
+		}
+		return jwk, nil
+
+	case ed25519.PublicKey:
+		jwk := JWK{
+			KeyType:   "OKP",
+			Algorithm: alg,
+			Curve:     "Ed25519",
+			X:         encode(pk),
+		}
+		return jwk, nil
+	}
+
+	return JWK{}, errors.Errorf("unsupported public key type %T", publicKey)
+}
+
+// keyAlgorithm returns the algorithm of tokens signed with the key. RSA keys
+// use the specified RSA algorithm, EC keys the algorithm of their curve and
+// Ed25519 keys EdDSA.
+func keyAlgorithm(publicKey crypto.PublicKey, rsaAlgorithm string) (string, error) {
+	switch pk := publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsaAlgorithm, nil
+
+	case *ecdsa.PublicKey:
+		switch pk.Curve.Params().Name {
+		case "P-256":
+			return "ES256", nil
+		case "P-384":
+			return "ES384", nil
+		case "P-521":
+			return "ES512", nil
+		}
+		return "", errors.Errorf("unsupported curve %s", pk.Curve.Params().Name)
+
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	}
+
+	return "", errors.Errorf("unsupported public key type %T", publicKey)
+}
+
+// algorithms returns the algorithms of every type of key we support.
+func algorithms(rsaAlgorithm string) []string {
+	return []string{rsaAlgorithm, "ES256", "ES384", "ES512", "EdDSA"}
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Revocations is an in-memory revocation list. It is only suitable when a
+// single instance of the service is running since the revocations are not
+// shared and are lost on restart.
+type Revocations struct {
+	mu       sync.RWMutex
+	tokens   map[string]time.Time
+	subjects map[string]subjectRevocation
+}
+
+// subjectRevocation records when every token for a subject was revoked.
+type subjectRevocation struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+// NewRevocations constructs an empty in-memory revocation list.
+func NewRevocations() *Revocations {
+	return &Revocations{
+		tokens:   make(map[string]time.Time),
+		subjects: make(map[string]subjectRevocation),
+	}
+}
+
+// Revoke implements the RevocationList interface. The entry is kept until
+// the token expires since it can't be used after that.
+func (r *Revocations) Revoke(ctx context.Context, traceID string, jti string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.evict(time.Now())
+	r.tokens[jti] = expiresAt
+
+	return nil
+}
+
+// RevokeSubject implements the RevocationList interface.
+func (r *Revocations) RevokeSubject(ctx context.Context, traceID string, subject string, before time.Time, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.evict(time.Now())
+	r.subjects[subject] = subjectRevocation{
+		before:    before,
+		expiresAt: expiresAt,
+	}
+
+	return nil
+}
+
+// IsRevoked implements the RevocationList interface.
+func (r *Revocations) IsRevoked(ctx context.Context, traceID string, jti string, subject string, issuedAt time.Time) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, exists := r.tokens[jti]; exists {
+		return true, nil
+	}
+
+	if rev, exists := r.subjects[subject]; exists && !issuedAt.After(rev.before) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// evict removes the revocations that have expired.
+func (r *Revocations) evict(now time.Time) {
+	for jti, expiresAt := range r.tokens {
+		if now.After(expiresAt) {
+			delete(r.tokens, jti)
+		}
+	}
+	for subject, rev := range r.subjects {
+		if now.After(rev.expiresAt) {
+			delete(r.subjects, subject)
+		}
+	}
+}
+
+// =============================================================================
+
+// CachedRevocations remembers the lookups of a revocation list for a short
+// time so a shared list isn't queried on every request. A token revoked by
+// another instance of the service is rejected once the lookups cached for it
+// expire. Revocations made through the cache apply immediately.
+type CachedRevocations struct {
+	list RevocationList
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	lookups map[string]cachedLookup
+	evicted time.Time
+	gen     int
+}
+
+// cachedLookup is the result of a lookup and when it was made.
+type cachedLookup struct {
+	revoked bool
+	checked time.Time
+}
+
+// NewCachedRevocations constructs a cache for the revocation list keeping
+// lookups for the specified duration.
+func NewCachedRevocations(list RevocationList, ttl time.Duration) *CachedRevocations {
+	return &CachedRevocations{
+		list:    list,
+		ttl:     ttl,
+		lookups: make(map[string]cachedLookup),
+	}
+}
+
+// Revoke implements the RevocationList interface.
+func (c *CachedRevocations) Revoke(ctx context.Context, traceID string, jti string, expiresAt time.Time) error {
+	defer c.reset()
+	return c.list.Revoke(ctx, traceID, jti, expiresAt)
+}
+
+// RevokeSubject implements the RevocationList interface.
+func (c *CachedRevocations) RevokeSubject(ctx context.Context, traceID string, subject string, before time.Time, expiresAt time.Time) error {
+	defer c.reset()
+	return c.list.RevokeSubject(ctx, traceID, subject, before, expiresAt)
+}
+
+// IsRevoked implements the RevocationList interface. Failed lookups aren't
+// cached.
+func (c *CachedRevocations) IsRevoked(ctx context.Context, traceID string, jti string, subject string, issuedAt time.Time) (bool, error) {
+	key := jti + "|" + subject + "|" + issuedAt.UTC().Format(time.RFC3339Nano)
+	now := time.Now()
+
+	c.mu.Lock()
+	lookup, exists := c.lookups[key]
+	gen := c.gen
+	c.mu.Unlock()
+
+	if exists && now.Sub(lookup.checked) < c.ttl {
+		return lookup.revoked, nil
+	}
+
+	revoked, err := c.list.IsRevoked(ctx, traceID, jti, subject, issuedAt)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A revocation made during the lookup may not be part of its result.
+	if gen != c.gen {
+		return revoked, nil
+	}
+
+	if now.Sub(c.evicted) >= c.ttl {
+		for k, l := range c.lookups {
+			if now.Sub(l.checked) >= c.ttl {
+				delete(c.lookups, k)
+			}
+		}
+		c.evicted = now
+	}
+	c.lookups[key] = cachedLookup{revoked: revoked, checked: now}
+
+	return revoked, nil
+}
+
+// reset forgets every cached lookup.
+func (c *CachedRevocations) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lookups = make(map[string]cachedLookup)
+	c.gen++
+}
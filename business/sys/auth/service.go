@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/pkg/errors"
+)
+
+// ServiceToken provides the ADMIN token a service uses to access the
+// database. The token is signed with the active key and replaced once half
+// of its lifetime has passed, so it keeps working across key rotations and
+// never expires while in use.
+type ServiceToken struct {
+	auth     *Auth
+	subject  string
+	lifetime time.Duration
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewServiceToken constructs a ServiceToken issuing tokens to the subject
+// that are valid for the specified lifetime.
+func (a *Auth) NewServiceToken(subject string, lifetime time.Duration) *ServiceToken {
+	return &ServiceToken{
+		auth:     a,
+		subject:  subject,
+		lifetime: lifetime,
+	}
+}
+
+// Token returns the current token, signing a new one when needed.
+func (s *ServiceToken) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.token != "" && now.Sub(s.issuedAt) < s.lifetime/2 {
+		return s.token, nil
+	}
+
+	kid, err := s.auth.ActiveKID()
+	if err != nil {
+		return "", errors.Wrap(err, "looking up active key")
+	}
+
+	claims := Claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "travel project",
+			Subject:   s.subject,
+			ExpiresAt: jwt.At(now.Add(s.lifetime)),
+			IssuedAt:  jwt.At(now),
+		},
+		Auth: StandardClaims{
+			Role:   RoleAdmin,
+			Scopes: RoleScopes(RoleAdmin),
+		},
+	}
+
+	token, err := s.auth.GenerateToken(kid, claims)
+	if err != nil {
+		return "", errors.Wrap(err, "generating token")
+	}
+
+	s.token = token
+	s.issuedAt = now
+
+	return token, nil
+}
+
+// CheckServiceToken verifies a token configured for a service is signed with
+// one of our keys, hasn't expired and grants the ADMIN role. Revocations
+// aren't checked since the revocation list may need the token itself.
+func (a *Auth) CheckServiceToken(tokenStr string) error {
+	var claims Claims
+	token, err := a.parser.ParseWithClaims(tokenStr, &claims, a.keyFunc)
+	if err != nil {
+		return errors.Wrap(err, "parsing token")
+	}
+
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+
+	if !claims.Authorized(RoleAdmin) {
+		return errors.Errorf("token has role %q, ADMIN is required", claims.Auth.Role)
+	}
+
+	return nil
+}
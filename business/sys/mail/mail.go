@@ -0,0 +1,67 @@
+// Package mail provides support for sending mail to users.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Message represents a mail to be sent to a user.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender declares the behavior for delivering mail.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// =============================================================================
+
+// FileSender writes each message to its own file in a folder instead of
+// delivering it. This is useful for local development and testing.
+type FileSender struct {
+	mu     sync.Mutex
+	folder string
+}
+
+// NewFile constructs a sender that writes messages to the specified folder.
+func NewFile(folder string) *FileSender {
+	return &FileSender{
+		folder: folder,
+	}
+}
+
+// Send implements the Sender interface.
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.folder, 0755); err != nil {
+		return errors.Wrap(err, "creating mail folder")
+	}
+
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405.000000000"), strings.ReplaceAll(msg.To, string(filepath.Separator), "_"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "\r\n%s\r\n", msg.Body)
+
+	if err := os.WriteFile(filepath.Join(s.folder, name), []byte(b.String()), 0600); err != nil {
+		return errors.Wrap(err, "writing mail")
+	}
+
+	return nil
+}
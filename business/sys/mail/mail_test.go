@@ -0,0 +1,60 @@
+package mail_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/travel/business/sys/mail"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// TestFileSender validates messages are written to the mail folder.
+func TestFileSender(t *testing.T) {
+	t.Log("Given the need to send mail during local development.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a single message.", testID)
+		{
+			folder := filepath.Join(t.TempDir(), "mail")
+			sender := mail.NewFile(folder)
+
+			msg := mail.Message{
+				To:      "bill@ardanlabs.com",
+				Subject: "Reset your password",
+				Body:    "token: 1234",
+			}
+			if err := sender.Send(context.Background(), msg); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to send the message: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to send the message.", success, testID)
+
+			files, err := os.ReadDir(folder)
+			if err != nil || len(files) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould have written a single file: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have written a single file.", success, testID)
+
+			data, err := os.ReadFile(filepath.Join(folder, files[0].Name()))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read the file: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to read the file.", success, testID)
+
+			for _, exp := range []string{"To: " + msg.To, "Subject: " + msg.Subject, msg.Body} {
+				if !strings.Contains(string(data), exp) {
+					t.Logf("\t\tTest %d:\tgot: %s", testID, data)
+					t.Fatalf("\t%s\tTest %d:\tShould contain %q.", failed, testID, exp)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould contain the message.", success, testID)
+		}
+	}
+}
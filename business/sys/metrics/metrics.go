@@ -4,7 +4,9 @@ package metrics
 import (
 	"context"
 	"expvar"
+	"strconv"
 	"sync"
+	"time"
 )
 
 // ctxKeyMetric represents the type of value for the context key.
@@ -25,12 +27,21 @@ var (
 // =============================================================================
 
 // Metrics represents the set of metrics we gather. These fields are
-// safe to be accessed concurrently. No extra abstraction is required.
+// safe to be accessed concurrently. No extra abstraction is required. The
+// expvar values are published under /debug/vars and everything is published
+// in the Prometheus text format by the Handler.
 type Metrics struct {
 	Goroutines *expvar.Int
 	Requests   *expvar.Int
 	Errors     *expvar.Int
 	Panics     *expvar.Int
+
+	HTTPRequests   *CounterVec
+	HTTPDuration   *HistogramVec
+	FeedRequests   *CounterVec
+	FeedDuration   *HistogramVec
+	DgraphRequests *CounterVec
+	DgraphDuration *HistogramVec
 }
 
 // New constructs the metrics that will be tracked.
@@ -44,6 +55,13 @@ func New() *Metrics {
 			Requests:   expvar.NewInt("requests"),
 			Errors:     expvar.NewInt("errors"),
 			Panics:     expvar.NewInt("panics"),
+
+			HTTPRequests:   NewCounterVec("travel_http_requests_total", "Number of requests by route, method and status.", "route", "method", "status"),
+			HTTPDuration:   NewHistogramVec("travel_http_request_duration_seconds", "Latency of requests by route and method.", DefaultBuckets, "route", "method"),
+			FeedRequests:   NewCounterVec("travel_feed_requests_total", "Number of calls made to the feeds by result.", "feed", "result"),
+			FeedDuration:   NewHistogramVec("travel_feed_request_duration_seconds", "Latency of calls made to the feeds.", DefaultBuckets, "feed"),
+			DgraphRequests: NewCounterVec("travel_dgraph_requests_total", "Number of calls made to Dgraph by result.", "result"),
+			DgraphDuration: NewHistogramVec("travel_dgraph_request_duration_seconds", "Latency of calls made to Dgraph.", DefaultBuckets),
 		}
 	}
 	return m
@@ -57,3 +75,32 @@ func AddPanics(ctx context.Context) {
 		v.Panics.Add(1)
 	}
 }
+
+// AddRequest records a request handled for the route with its status.
+func (m *Metrics) AddRequest(route string, method string, status int, d time.Duration) {
+	m.HTTPRequests.Inc(route, method, strconv.Itoa(status))
+	m.HTTPDuration.Observe(d.Seconds(), route, method)
+}
+
+// AddFeedCall records a call made to the specified feed. Feeds are called
+// outside of requests so the metrics are looked up directly.
+func AddFeedCall(feed string, d time.Duration, err error) {
+	m := New()
+	m.FeedRequests.Inc(feed, result(err))
+	m.FeedDuration.Observe(d.Seconds(), feed)
+}
+
+// AddDgraphCall records a call made to the database.
+func AddDgraphCall(d time.Duration, err error) {
+	m := New()
+	m.DgraphRequests.Inc(result(err))
+	m.DgraphDuration.Observe(d.Seconds())
+}
+
+// result returns the label value describing the outcome of a call.
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds in seconds used for latency histograms.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// CounterVec is a set of counters partitioned by label values.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec constructs a counter partitioned by the specified labels.
+func NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+}
+
+// Inc increments the counter for the label values by 1. The values must be
+// provided in the order of the labels.
+func (c *CounterVec) Inc(values ...string) {
+	key := labelKey(values)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values[key]++
+}
+
+// write outputs the counters in the Prometheus text format.
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labelPairs(c.labels, key, ""), formatFloat(c.values[key]))
+	}
+}
+
+// =============================================================================
+
+// HistogramVec is a set of histograms partitioned by label values.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogram
+}
+
+// histogram holds the observations for one set of label values.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec constructs a histogram partitioned by the specified labels
+// using the specified bucket upper bounds.
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  make(map[string]*histogram),
+	}
+}
+
+// Observe records a value for the label values. The values must be provided
+// in the order of the labels.
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	key := labelKey(values)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hst, exists := h.values[key]
+	if !exists {
+		hst = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hst
+	}
+
+	for i, bound := range h.buckets {
+		if v <= bound {
+			hst.counts[i]++
+		}
+	}
+	hst.count++
+	hst.sum += v
+}
+
+// write outputs the histograms in the Prometheus text format.
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		hst := h.values[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(h.labels, key, formatFloat(bound)), hst.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(h.labels, key, "+Inf"), hst.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelPairs(h.labels, key, ""), formatFloat(hst.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelPairs(h.labels, key, ""), hst.count)
+	}
+}
+
+// =============================================================================
+
+// Handler returns a handler writing the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WritePrometheus(w)
+	}
+	return http.HandlerFunc(f)
+}
+
+// WritePrometheus writes the metrics in the Prometheus text format. The
+// number of goroutines is sampled at the time of the write.
+func (m *Metrics) WritePrometheus(w io.Writer) {
+	m.Goroutines.Set(int64(runtime.NumGoroutine()))
+
+	gauge := func(name string, help string, typ string, v int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, typ, name, v)
+	}
+	gauge("travel_goroutines", "Number of goroutines.", "gauge", m.Goroutines.Value())
+	gauge("travel_requests_total", "Number of requests handled.", "counter", m.Requests.Value())
+	gauge("travel_errors_total", "Number of requests that failed.", "counter", m.Errors.Value())
+	gauge("travel_panics_total", "Number of panics recovered.", "counter", m.Panics.Value())
+
+	m.HTTPRequests.write(w)
+	m.HTTPDuration.write(w)
+	m.FeedRequests.write(w)
+	m.FeedDuration.write(w)
+	m.DgraphRequests.write(w)
+	m.DgraphDuration.write(w)
+}
+
+// =============================================================================
+
+// labelKey joins label values into a map key. The separator can't appear in
+// valid UTF-8 text.
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs formats the label values of a key for output. The le label is
+// added for histogram buckets.
+func labelPairs(labels []string, key string, le string) string {
+	var pairs []string
+	if len(labels) > 0 {
+		values := strings.Split(key, "\xff")
+		for i, label := range labels {
+			var v string
+			if i < len(values) {
+				v = values[i]
+			}
+			pairs = append(pairs, fmt.Sprintf("%s=%q", label, v))
+		}
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf("le=%q", le))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,75 @@
+package metrics_test
+
+import (
+	"bytes"
+	"expvar"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/travel/business/sys/metrics"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// TestPrometheus validates counters and histograms are written in the
+// Prometheus text format.
+func TestPrometheus(t *testing.T) {
+	t.Log("Given the need to publish metrics for Prometheus.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a counter and a histogram.", testID)
+		{
+			c := metrics.NewCounterVec("requests_total", "Number of requests.", "route", "status")
+			c.Inc("/v1/users/:id", "200")
+			c.Inc("/v1/users/:id", "200")
+			c.Inc("/v1/users", "500")
+
+			h := metrics.NewHistogramVec("duration_seconds", "Latency of requests.", []float64{.1, 1}, "route")
+			h.Observe(.05, "/v1/users")
+			h.Observe(.5, "/v1/users")
+
+			// A local value is used so the metrics of the process are
+			// left untouched.
+			m := metrics.Metrics{
+				Goroutines:     new(expvar.Int),
+				Requests:       new(expvar.Int),
+				Errors:         new(expvar.Int),
+				Panics:         new(expvar.Int),
+				HTTPRequests:   c,
+				HTTPDuration:   h,
+				FeedRequests:   metrics.NewCounterVec("feed_requests_total", "Number of feed calls.", "feed", "result"),
+				FeedDuration:   metrics.NewHistogramVec("feed_duration_seconds", "Latency of feed calls.", []float64{1}, "feed"),
+				DgraphRequests: metrics.NewCounterVec("dgraph_requests_total", "Number of Dgraph calls.", "result"),
+				DgraphDuration: metrics.NewHistogramVec("dgraph_duration_seconds", "Latency of Dgraph calls.", []float64{1}),
+			}
+
+			var buf bytes.Buffer
+			m.WritePrometheus(&buf)
+			out := buf.String()
+
+			exp := []string{
+				"# TYPE requests_total counter",
+				`requests_total{route="/v1/users",status="500"} 1`,
+				`requests_total{route="/v1/users/:id",status="200"} 2`,
+				"# TYPE duration_seconds histogram",
+				`duration_seconds_bucket{route="/v1/users",le="0.1"} 1`,
+				`duration_seconds_bucket{route="/v1/users",le="1"} 2`,
+				`duration_seconds_bucket{route="/v1/users",le="+Inf"} 2`,
+				`duration_seconds_sum{route="/v1/users"} 0.55`,
+				`duration_seconds_count{route="/v1/users"} 2`,
+				"# TYPE travel_requests_total counter",
+			}
+			for _, line := range exp {
+				if !strings.Contains(out, line+"\n") {
+					t.Logf("\t\tTest %d:\tgot: %s", testID, out)
+					t.Fatalf("\t%s\tTest %d:\tShould contain %q.", failed, testID, line)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould write the metrics in the Prometheus text format.", success, testID)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package validate
 import (
 	"reflect"
 	"strings"
+	"unicode"
 
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
@@ -38,6 +39,24 @@ func init() {
 		}
 		return name
 	})
+
+	// Register the rule used to enforce the password policy.
+	validate.RegisterValidation("password", password)
+	validate.RegisterTranslation("password", translator, func(ut ut.Translator) error {
+		return ut.Add("password", "{0} must be at least 8 characters and contain a letter and a number", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("password", fe.Field())
+		return t
+	})
+
+	// The default translations don't cover this rule which is used to make
+	// sure a password is confirmed.
+	validate.RegisterTranslation("required_with", translator, func(ut ut.Translator) error {
+		return ut.Add("required_with", "{0} is required when {1} is provided", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("required_with", fe.Field(), fe.Param())
+		return t
+	})
 }
 
 // Check validates the provided model against it's declared tags.
@@ -65,6 +84,27 @@ func Check(val interface{}) error {
 	return nil
 }
 
+// password checks a password is at least 8 characters long and contains
+// both a letter and a number.
+func password(fl validator.FieldLevel) bool {
+	pass := fl.Field().String()
+	if len(pass) < 8 {
+		return false
+	}
+
+	var letter, number bool
+	for _, r := range pass {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsNumber(r):
+			number = true
+		}
+	}
+
+	return letter && number
+}
+
 // GenerateID generate a unique id for entities.
 func GenerateID() string {
 	return uuid.New().String()
@@ -0,0 +1,154 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/dgraph-io/travel/business/data"
+	"github.com/dgraph-io/travel/business/sys/auth"
+	"github.com/dgraph-io/travel/business/sys/validate"
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/pkg/errors"
+)
+
+// KeyAuthenticator validates an api key and returns the claims granted to
+// the client holding it.
+type KeyAuthenticator func(ctx context.Context, key string) (auth.Claims, error)
+
+// Authenticate validates a JWT from the `Authorization` header. When an api
+// key authenticator is provided, machine clients can instead provide an api
+// key in the `X-API-Key` header.
+func Authenticate(a *auth.Auth, keyAuth KeyAuthenticator) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// If the context is missing this value, request the service
+			// to be shutdown gracefully.
+			v, ok := ctx.Value(web.KeyValues).(*web.Values)
+			if !ok {
+				return web.NewShutdownError("web value missing from context")
+			}
+
+			// Validate the api key if one was provided instead of a token.
+			if key := r.Header.Get("X-API-Key"); key != "" && keyAuth != nil {
+				claims, err := keyAuth(ctx, key)
+				if err != nil {
+					return validate.NewRequestError(err, http.StatusUnauthorized)
+				}
+
+				// The database only understands tokens so the claims granted
+				// to the key are signed for it.
+				kid, err := a.ActiveKID()
+				if err != nil {
+					return errors.Wrap(err, "looking up active key")
+				}
+				tkn, err := a.GenerateToken(kid, claims)
+				if err != nil {
+					return errors.Wrap(err, "generating token for api key")
+				}
+
+				ctx = context.WithValue(ctx, auth.Key, claims)
+				ctx = data.WithAuthToken(ctx, tkn)
+				return handler(ctx, w, r)
+			}
+
+			// Expecting: bearer <token>
+			authStr := r.Header.Get("authorization")
+
+			// Parse the authorization header.
+			parts := strings.Split(authStr, " ")
+			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+				err := errors.New("expected authorization header format: bearer <token>")
+				return validate.NewRequestError(err, http.StatusUnauthorized)
+			}
+
+			// Validate the token is signed by us.
+			claims, err := a.ValidateToken(ctx, v.TraceID, parts[1])
+			if err != nil {
+				return validate.NewRequestError(err, http.StatusUnauthorized)
+			}
+
+			// Add claims to the context so they can be retrieved later. The
+			// token is forwarded to the database on behalf of the caller.
+			ctx = context.WithValue(ctx, auth.Key, claims)
+			ctx = data.WithAuthToken(ctx, parts[1])
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// Authorize validates that an authenticated user has at least one role from a
+// specified list. This method constructs the actual function that is used.
+func Authorize(roles ...string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// If the context is missing this value, the caller was never
+			// authenticated.
+			claims, ok := ctx.Value(auth.Key).(auth.Claims)
+			if !ok {
+				err := errors.New("claims missing from context: Authorize called without/before Authenticate")
+				return validate.NewRequestError(err, http.StatusUnauthorized)
+			}
+
+			if !claims.Authorized(roles...) {
+				err := errors.Wrapf(auth.ErrForbidden, "you are not authorized for that action: claims: %v exp: %v", claims.Auth.Role, roles)
+				return validate.NewRequestError(err, http.StatusForbidden)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// RequireScopes validates that an authenticated user has been granted every
+// scope from a specified list. This method constructs the actual function
+// that is used.
+func RequireScopes(scopes ...string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// If the context is missing this value, the caller was never
+			// authenticated.
+			claims, ok := ctx.Value(auth.Key).(auth.Claims)
+			if !ok {
+				err := errors.New("claims missing from context: RequireScopes called without/before Authenticate")
+				return validate.NewRequestError(err, http.StatusUnauthorized)
+			}
+
+			if !claims.HasScope(scopes...) {
+				err := errors.Wrapf(auth.ErrForbidden, "you are not authorized for that action: scopes: %v exp: %v", claims.Auth.Scopes, scopes)
+				return validate.NewRequestError(err, http.StatusForbidden)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,131 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/pkg/errors"
+)
+
+// CORSConfig represents the cross-origin requests browsers are allowed to
+// make. An origin of * allows any origin. Without any allowed origins no
+// CORS headers are written and browsers only allow same-origin requests.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// Validate checks the configuration can be used safely. Credentials can't be
+// allowed for any origin since every site could then make requests with the
+// cookies of the user.
+func (cfg CORSConfig) Validate() error {
+	if !cfg.AllowCredentials {
+		return nil
+	}
+
+	for _, o := range cfg.AllowedOrigins {
+		if o == "*" {
+			return errors.New("allowed origin * can't be used with credentials")
+		}
+	}
+
+	return nil
+}
+
+// CORS writes the Cross-Origin Resource Sharing headers for requests from
+// allowed origins. Preflight requests are answered here without calling the
+// handler. Register a handler with web.App.HandleOptions so the router sends
+// preflight requests through the middleware. When credentials are allowed
+// the * origin is ignored and only the listed origins are allowed, use
+// CORSConfig.Validate to reject such a configuration at startup.
+func CORS(cfg CORSConfig) web.Middleware {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	// Credentials can't be used with the * origin and echoing the origin of
+	// any request would allow every site, so the * origin is dropped.
+	var wildcard bool
+	var origins []string
+	for _, o := range cfg.AllowedOrigins {
+		if o == "*" {
+			if cfg.AllowCredentials {
+				continue
+			}
+			wildcard = true
+		}
+		origins = append(origins, o)
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// The response depends on the origin so caches must keep
+			// a copy for each one.
+			w.Header().Add("Vary", "Origin")
+
+			allowed := origin != "" && allowedOrigin(origins, origin)
+			if allowed {
+				hdr := w.Header()
+				if wildcard {
+					hdr.Set("Access-Control-Allow-Origin", "*")
+				} else {
+					hdr.Set("Access-Control-Allow-Origin", origin)
+				}
+				if cfg.AllowCredentials {
+					hdr.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if exposed != "" && !preflight {
+					hdr.Set("Access-Control-Expose-Headers", exposed)
+				}
+			}
+
+			if !preflight {
+				return handler(ctx, w, r)
+			}
+
+			// Answer the preflight request. The browser refuses the actual
+			// request when the allow headers are missing.
+			if allowed {
+				hdr := w.Header()
+				hdr.Add("Vary", "Access-Control-Request-Method")
+				hdr.Add("Vary", "Access-Control-Request-Headers")
+				hdr.Set("Access-Control-Allow-Methods", methods)
+				hdr.Set("Access-Control-Allow-Headers", headers)
+				if cfg.MaxAge > 0 {
+					hdr.Set("Access-Control-Max-Age", maxAge)
+				}
+			}
+
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// allowedOrigin reports whether the origin is in the list of allowed origins.
+func allowedOrigin(origins []string, origin string) bool {
+	for _, o := range origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package mid_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/business/sys/validate"
+	"github.com/dgraph-io/travel/business/web/mid"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/pkg/errors"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// newApp constructs an app with the CORS and security headers middleware
+// serving a route that succeeds and a route that fails.
+func newApp(t *testing.T, cors mid.CORSConfig, secure mid.SecureHeadersConfig) *web.App {
+	log, err := logger.New(io.Discard, logger.Config{Service: "TEST"})
+	if err != nil {
+		t.Fatalf("Should be able to construct a logger: %v", err)
+	}
+
+	shutdown := make(chan os.Signal, 1)
+	app := web.NewApp(shutdown, nil, mid.Errors(log), mid.SecureHeaders(secure), mid.CORS(cors))
+
+	options := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+	app.HandleOptions(options)
+
+	ok := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, struct{}{}, http.StatusOK)
+	}
+	app.Handle(http.MethodGet, "/ok", ok)
+
+	fail := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return validate.NewRequestError(errors.New("not found"), http.StatusNotFound)
+	}
+	app.Handle(http.MethodGet, "/fail", fail)
+
+	return app
+}
+
+// TestCORS validates the CORS headers are written for allowed origins.
+func TestCORS(t *testing.T) {
+	cfg := mid.CORSConfig{
+		AllowedOrigins: []string{"https://travel.example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"Retry-After"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	tt := []struct {
+		name      string
+		cfg       mid.CORSConfig
+		method    string
+		path      string
+		origin    string
+		preflight bool
+		status    int
+		headers   map[string]string
+	}{
+		{
+			name: "preflight allowed", cfg: cfg, method: http.MethodOptions, path: "/ok",
+			origin: "https://travel.example.com", preflight: true, status: http.StatusNoContent,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":   "https://travel.example.com",
+				"Access-Control-Allow-Methods":  "GET, POST",
+				"Access-Control-Allow-Headers":  "Authorization, Content-Type",
+				"Access-Control-Max-Age":        "600",
+				"Access-Control-Expose-Headers": "",
+			},
+		},
+		{
+			name: "preflight origin case", cfg: cfg, method: http.MethodOptions, path: "/ok",
+			origin: "HTTPS://TRAVEL.EXAMPLE.COM", preflight: true, status: http.StatusNoContent,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "HTTPS://TRAVEL.EXAMPLE.COM",
+				"Access-Control-Allow-Methods": "GET, POST",
+			},
+		},
+		{
+			name: "preflight refused", cfg: cfg, method: http.MethodOptions, path: "/ok",
+			origin: "https://evil.example.com", preflight: true, status: http.StatusNoContent,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			name: "request allowed", cfg: cfg, method: http.MethodGet, path: "/ok",
+			origin: "https://travel.example.com", status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":   "https://travel.example.com",
+				"Access-Control-Expose-Headers": "Retry-After",
+				"Access-Control-Allow-Methods":  "",
+				"Vary":                          "Origin",
+			},
+		},
+		{
+			name: "request refused", cfg: cfg, method: http.MethodGet, path: "/ok",
+			origin: "https://evil.example.com", status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin": "",
+				"Vary":                        "Origin",
+			},
+		},
+		{
+			name: "request without origin", cfg: cfg, method: http.MethodGet, path: "/ok",
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name: "error response", cfg: cfg, method: http.MethodGet, path: "/fail",
+			origin: "https://travel.example.com", status: http.StatusNotFound,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":   "https://travel.example.com",
+				"Access-Control-Expose-Headers": "Retry-After",
+			},
+		},
+		{
+			name: "wildcard", cfg: mid.CORSConfig{AllowedOrigins: []string{"*"}}, method: http.MethodGet, path: "/ok",
+			origin: "https://any.example.com", status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name: "wildcard with credentials", cfg: mid.CORSConfig{AllowedOrigins: []string{"*", "https://travel.example.com"}, AllowCredentials: true}, method: http.MethodGet, path: "/ok",
+			origin: "https://any.example.com", status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name: "listed with credentials", cfg: mid.CORSConfig{AllowedOrigins: []string{"*", "https://travel.example.com"}, AllowCredentials: true}, method: http.MethodGet, path: "/ok",
+			origin: "https://travel.example.com", status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "https://travel.example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+	}
+
+	t.Log("Given the need to allow browsers to make cross-origin requests.")
+	{
+		for testID, tst := range tt {
+			t.Logf("\tTest %d:\tWhen handling a %s request to %s (%s).", testID, tst.method, tst.path, tst.name)
+			{
+				app := newApp(t, tst.cfg, mid.SecureHeadersConfig{})
+
+				r := httptest.NewRequest(tst.method, tst.path, nil)
+				if tst.origin != "" {
+					r.Header.Set("Origin", tst.origin)
+				}
+				if tst.preflight {
+					r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				}
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != tst.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d : got %d.", failed, testID, tst.status, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a status code of %d.", success, testID, tst.status)
+
+				for k, exp := range tst.headers {
+					if got := w.Header().Get(k); got != exp {
+						t.Fatalf("\t%s\tTest %d:\tShould set %s to %q : got %q.", failed, testID, k, exp, got)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould set the expected CORS headers.", success, testID)
+			}
+		}
+	}
+}
+
+// TestCORSValidate validates credentials can't be allowed for any origin.
+func TestCORSValidate(t *testing.T) {
+	tt := []struct {
+		name string
+		cfg  mid.CORSConfig
+		ok   bool
+	}{
+		{"wildcard", mid.CORSConfig{AllowedOrigins: []string{"*"}}, true},
+		{"listed with credentials", mid.CORSConfig{AllowedOrigins: []string{"https://travel.example.com"}, AllowCredentials: true}, true},
+		{"wildcard with credentials", mid.CORSConfig{AllowedOrigins: []string{"https://travel.example.com", "*"}, AllowCredentials: true}, false},
+	}
+
+	t.Log("Given the need to reject unsafe CORS configurations.")
+	{
+		for testID, tst := range tt {
+			t.Logf("\tTest %d:\tWhen validating the %s configuration.", testID, tst.name)
+			{
+				err := tst.cfg.Validate()
+				if (err == nil) != tst.ok {
+					t.Fatalf("\t%s\tTest %d:\tShould accept the configuration %v : got %v.", failed, testID, tst.ok, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould accept the configuration %v.", success, testID, tst.ok)
+			}
+		}
+	}
+}
@@ -2,10 +2,10 @@ package mid
 
 import (
 	"context"
-	"log"
 	"net/http"
 
 	"github.com/dgraph-io/travel/business/sys/validate"
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/dgraph-io/travel/foundation/web"
 	"github.com/pkg/errors"
 )
@@ -13,7 +13,7 @@ import (
 // Errors handles errors coming out of the call chain. It detects normal
 // application errors which are used to respond to the client in a uniform way.
 // Unexpected errors (status >= 500) are logged.
-func Errors(log *log.Logger) web.Middleware {
+func Errors(log *logger.Logger) web.Middleware {
 
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
@@ -32,29 +32,10 @@ func Errors(log *log.Logger) web.Middleware {
 			if err := handler(ctx, w, r); err != nil {
 
 				// Log the error.
-				log.Printf("%s: ERROR: %s", v.TraceID, err)
+				log.Error("request failed", "traceid", v.TraceID, "error", err)
 
 				// Build out the error response.
-				var er validate.ErrorResponse
-				var status int
-				switch act := errors.Cause(err).(type) {
-				case validate.FieldErrors:
-					er = validate.ErrorResponse{
-						Error:  "data validation error",
-						Fields: act.Error(),
-					}
-					status = http.StatusBadRequest
-				case *validate.RequestError:
-					er = validate.ErrorResponse{
-						Error: act.Error(),
-					}
-					status = act.Status
-				default:
-					er = validate.ErrorResponse{
-						Error: http.StatusText(http.StatusInternalServerError),
-					}
-					status = http.StatusInternalServerError
-				}
+				er, status := errorResponse(err)
 
 				// Respond with the error back to the client.
 				if err := web.Respond(ctx, w, er, status); err != nil {
@@ -77,3 +58,32 @@ func Errors(log *log.Logger) web.Middleware {
 
 	return m
 }
+
+// errorResponse returns the response and status code for an error flowing
+// through the request. Expected application errors are reported to the
+// client, anything else is an internal error.
+func errorResponse(err error) (validate.ErrorResponse, int) {
+	switch act := errors.Cause(err).(type) {
+	case validate.FieldErrors:
+		er := validate.ErrorResponse{
+			Error:  "data validation error",
+			Fields: act.Error(),
+		}
+		return er, http.StatusBadRequest
+	case *validate.RequestError:
+		er := validate.ErrorResponse{
+			Error: act.Error(),
+		}
+		return er, act.Status
+	case *web.DecodeError:
+		er := validate.ErrorResponse{
+			Error: act.Error(),
+		}
+		return er, act.Status
+	default:
+		er := validate.ErrorResponse{
+			Error: http.StatusText(http.StatusInternalServerError),
+		}
+		return er, http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,65 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/web"
+)
+
+// SecureHeadersConfig represents the security headers written on every
+// response. HSTS is only sent when a max age is set since it must only be
+// used when the service is served over TLS. Empty values are not written.
+type SecureHeadersConfig struct {
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	ContentSecurityPolicy string
+	FrameOptions          string
+	ReferrerPolicy        string
+}
+
+// SecureHeaders writes headers asking browsers to apply their security
+// protections to the responses. Content type sniffing is always disabled.
+func SecureHeaders(cfg SecureHeadersConfig) web.Middleware {
+	var hsts string
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	headers := map[string]string{
+		"Strict-Transport-Security": hsts,
+		"Content-Security-Policy":   cfg.ContentSecurityPolicy,
+		"X-Frame-Options":           cfg.FrameOptions,
+		"Referrer-Policy":           cfg.ReferrerPolicy,
+		"X-Content-Type-Options":    "nosniff",
+	}
+	for k, v := range headers {
+		if v == "" {
+			delete(headers, k)
+		}
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// Set the headers before the handler writes the response.
+			for k, v := range headers {
+				w.Header().Set(k, v)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,85 @@
+package mid_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/business/web/mid"
+)
+
+// TestSecureHeaders validates the security headers are written on every
+// response.
+func TestSecureHeaders(t *testing.T) {
+	full := mid.SecureHeadersConfig{
+		HSTSMaxAge:            24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'none'",
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+	}
+
+	tt := []struct {
+		name    string
+		cfg     mid.SecureHeadersConfig
+		path    string
+		headers map[string]string
+	}{
+		{
+			name: "configured", cfg: full, path: "/ok",
+			headers: map[string]string{
+				"Strict-Transport-Security": "max-age=86400; includeSubDomains",
+				"Content-Security-Policy":   "default-src 'none'",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "no-referrer",
+				"X-Content-Type-Options":    "nosniff",
+			},
+		},
+		{
+			name: "error response", cfg: full, path: "/fail",
+			headers: map[string]string{
+				"Strict-Transport-Security": "max-age=86400; includeSubDomains",
+				"Content-Security-Policy":   "default-src 'none'",
+				"X-Content-Type-Options":    "nosniff",
+			},
+		},
+		{
+			name: "empty", cfg: mid.SecureHeadersConfig{}, path: "/ok",
+			headers: map[string]string{
+				"Strict-Transport-Security": "",
+				"Content-Security-Policy":   "",
+				"X-Frame-Options":           "",
+				"Referrer-Policy":           "",
+				"X-Content-Type-Options":    "nosniff",
+			},
+		},
+		{
+			name: "hsts without subdomains", cfg: mid.SecureHeadersConfig{HSTSMaxAge: time.Hour}, path: "/ok",
+			headers: map[string]string{
+				"Strict-Transport-Security": "max-age=3600",
+			},
+		},
+	}
+
+	t.Log("Given the need to ask browsers to protect the responses.")
+	{
+		for testID, tst := range tt {
+			t.Logf("\tTest %d:\tWhen handling a request to %s (%s).", testID, tst.path, tst.name)
+			{
+				app := newApp(t, mid.CORSConfig{}, tst.cfg)
+
+				r := httptest.NewRequest(http.MethodGet, tst.path, nil)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				for k, exp := range tst.headers {
+					if got := w.Header().Get(k); got != exp {
+						t.Fatalf("\t%s\tTest %d:\tShould set %s to %q : got %q.", failed, testID, k, exp, got)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould set the expected security headers.", success, testID)
+			}
+		}
+	}
+}
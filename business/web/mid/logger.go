@@ -2,16 +2,16 @@ package mid
 
 import (
 	"context"
-	"log"
 	"net/http"
 	"time"
 
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/dgraph-io/travel/foundation/web"
 )
 
-// Logger writes some information about the request to the logs in the
-// format: TraceID : (200) GET /foo -> IP ADDR (latency)
-func Logger(log *log.Logger) web.Middleware {
+// Logger writes an entry when the request starts and completes with the
+// trace id, method, path, remote address, status code and latency as fields.
+func Logger(log *logger.Logger) web.Middleware {
 
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
@@ -26,18 +26,13 @@ func Logger(log *log.Logger) web.Middleware {
 				return web.NewShutdownError("web value missing from context")
 			}
 
-			log.Printf("%s: started: %s %s -> %s",
-				v.TraceID,
-				r.Method, r.URL.Path, r.RemoteAddr,
-			)
+			log.Info("request started", "traceid", v.TraceID, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
 
 			// Call the next handler.
 			err := handler(ctx, w, r)
 
-			log.Printf("%s: completed: %s %s -> %s (%d) (%s)",
-				v.TraceID,
-				r.Method, r.URL.Path, r.RemoteAddr,
-				v.StatusCode, time.Since(v.Now),
+			log.Info("request completed", "traceid", v.TraceID, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr,
+				"statuscode", v.StatusCode, "since", time.Since(v.Now),
 			)
 
 			// Return the error so it can be handled further up the chain.
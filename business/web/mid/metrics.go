@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"runtime"
+	"time"
 
 	"github.com/dgraph-io/travel/business/sys/metrics"
 	"github.com/dgraph-io/travel/foundation/web"
@@ -22,6 +23,7 @@ func Metrics(data *metrics.Metrics) web.Middleware {
 			ctx = context.WithValue(ctx, metrics.Key, data)
 
 			// Call the next handler.
+			start := time.Now()
 			err := handler(ctx, w, r)
 
 			// Handle updating the metrics that can be handled here.
@@ -34,6 +36,10 @@ func Metrics(data *metrics.Metrics) web.Middleware {
 				data.Errors.Add(1)
 			}
 
+			// Record the request against the route pattern so paths with
+			// ids don't create a series each.
+			data.AddRequest(web.Route(r), r.Method, status(ctx, err), time.Since(start))
+
 			// Update the count for the number of active goroutines every 100 requests.
 			if data.Requests.Value()%100 == 0 {
 				data.Goroutines.Set(int64(runtime.NumGoroutine()))
@@ -48,3 +54,18 @@ func Metrics(data *metrics.Metrics) web.Middleware {
 
 	return m
 }
+
+// status returns the status code of the response. The response has not been
+// written yet when an error is flowing through the request, so the status
+// the Errors middleware will respond with is used.
+func status(ctx context.Context, err error) int {
+	if err != nil {
+		_, status := errorResponse(err)
+		return status
+	}
+
+	if v, ok := ctx.Value(web.KeyValues).(*web.Values); ok {
+		return v.StatusCode
+	}
+	return 0
+}
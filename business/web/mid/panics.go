@@ -2,17 +2,17 @@ package mid
 
 import (
 	"context"
-	"log"
 	"net/http"
 
 	"github.com/dgraph-io/travel/business/sys/metrics"
+	"github.com/dgraph-io/travel/foundation/logger"
 	"github.com/dgraph-io/travel/foundation/web"
 	"github.com/pkg/errors"
 )
 
 // Panics recovers from panics and converts the panic to an error so it is
 // reported in Metrics and handled in Errors.
-func Panics(log *log.Logger) web.Middleware {
+func Panics(log *logger.Logger) web.Middleware {
 
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
@@ -0,0 +1,111 @@
+package mid
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/dgraph-io/travel/business/sys/auth"
+	"github.com/dgraph-io/travel/business/sys/validate"
+	"github.com/dgraph-io/travel/foundation/ratelimit"
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/pkg/errors"
+)
+
+// ErrRateLimited is returned when a client has made too many requests.
+var ErrRateLimited = errors.New("too many requests")
+
+// RateLimit refuses requests once the client has used up the specified limit
+// for the route. Clients are identified by the subject of their claims when
+// authenticated, which includes clients using a verified api key, and by
+// their IP address otherwise. Run it after Authenticate to limit
+// authenticated clients by subject.
+func RateLimit(store *ratelimit.Store, limit ratelimit.Limit) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// If the context is missing this value, request the service
+			// to be shutdown gracefully.
+			v, ok := ctx.Value(web.KeyValues).(*web.Values)
+			if !ok {
+				return web.NewShutdownError("web value missing from context")
+			}
+
+			// Each route has its own bucket for the client.
+			key := r.Method + " " + web.Route(r) + " " + client(ctx, r)
+
+			allowed, wait := store.Allow(key, limit, v.Now)
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				return validate.NewRequestError(ErrRateLimited, http.StatusTooManyRequests)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// RateLimitIP refuses requests once the IP address of the client has used up
+// the specified limit, across every route it's used on. Run it before
+// Authenticate so clients failing to authenticate, or cycling through
+// credentials, are limited before their credentials are checked.
+func RateLimitIP(store *ratelimit.Store, limit ratelimit.Limit) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// If the context is missing this value, request the service
+			// to be shutdown gracefully.
+			v, ok := ctx.Value(web.KeyValues).(*web.Values)
+			if !ok {
+				return web.NewShutdownError("web value missing from context")
+			}
+
+			allowed, wait := store.Allow("ip:"+remoteIP(r), limit, v.Now)
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				return validate.NewRequestError(ErrRateLimited, http.StatusTooManyRequests)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// client returns the identity the requests are limited by. Headers the
+// client sends, such as an api key, are only trusted once Authenticate has
+// verified them and put the claims in the context. Otherwise any client
+// could get a new bucket with every request.
+func client(ctx context.Context, r *http.Request) string {
+	if claims, ok := ctx.Value(auth.Key).(auth.Claims); ok && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+
+	return "ip:" + remoteIP(r)
+}
+
+// remoteIP returns the IP address the request came from.
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
@@ -0,0 +1,148 @@
+package mid_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/business/sys/auth"
+	"github.com/dgraph-io/travel/business/web/mid"
+	"github.com/dgraph-io/travel/foundation/logger"
+	"github.com/dgraph-io/travel/foundation/ratelimit"
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/dgrijalva/jwt-go/v4"
+)
+
+// claimsFromHeader stands in for Authenticate by trusting the subject in the
+// X-Subject header.
+func claimsFromHeader(handler web.Handler) web.Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if sub := r.Header.Get("X-Subject"); sub != "" {
+			claims := auth.Claims{
+				StandardClaims: jwt.StandardClaims{
+					Subject: sub,
+				},
+			}
+			ctx = context.WithValue(ctx, auth.Key, claims)
+		}
+		return handler(ctx, w, r)
+	}
+}
+
+// TestRateLimit validates clients are refused once they use up their limit.
+func TestRateLimit(t *testing.T) {
+	log, err := logger.New(io.Discard, logger.Config{Service: "TEST"})
+	if err != nil {
+		t.Fatalf("Should be able to construct a logger: %v", err)
+	}
+
+	// Each bucket allows a single request and refills after a minute so
+	// the second request always fails.
+	store := ratelimit.NewStore()
+	limit := mid.RateLimit(store, ratelimit.Every(time.Minute, 1))
+	limitIP := mid.RateLimitIP(store, ratelimit.Every(time.Minute, 2))
+
+	shutdown := make(chan os.Signal, 1)
+	app := web.NewApp(shutdown, nil, mid.Errors(log))
+
+	ok := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+	app.Handle(http.MethodGet, "/a", ok, claimsFromHeader, limit)
+	app.Handle(http.MethodGet, "/b", ok, claimsFromHeader, limit)
+	app.Handle(http.MethodGet, "/ip/a", ok, limitIP)
+	app.Handle(http.MethodGet, "/ip/b", ok, limitIP)
+
+	type request struct {
+		path    string
+		addr    string
+		subject string
+		apiKey  string
+		status  int
+	}
+
+	tt := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "ip",
+			requests: []request{
+				{path: "/a", addr: "10.0.0.1:1000", status: http.StatusNoContent},
+				{path: "/a", addr: "10.0.0.1:2000", status: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "route",
+			requests: []request{
+				{path: "/a", addr: "10.0.0.2:1000", status: http.StatusNoContent},
+				{path: "/b", addr: "10.0.0.2:1000", status: http.StatusNoContent},
+				{path: "/b", addr: "10.0.0.2:1000", status: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "subject",
+			requests: []request{
+				{path: "/a", addr: "10.0.0.3:1000", subject: "user-1", status: http.StatusNoContent},
+				{path: "/a", addr: "10.0.0.3:1000", subject: "user-2", status: http.StatusNoContent},
+				{path: "/a", addr: "10.0.0.3:1000", status: http.StatusNoContent},
+				{path: "/a", addr: "10.0.0.4:1000", subject: "user-1", status: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "spoofed api key",
+			requests: []request{
+				{path: "/a", addr: "10.0.0.5:1000", apiKey: "key-1", status: http.StatusNoContent},
+				{path: "/a", addr: "10.0.0.5:1000", apiKey: "key-2", status: http.StatusTooManyRequests},
+				{path: "/a", addr: "10.0.0.5:1000", apiKey: "key-3", status: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "ip across routes",
+			requests: []request{
+				{path: "/ip/a", addr: "10.0.0.6:1000", status: http.StatusNoContent},
+				{path: "/ip/b", addr: "10.0.0.6:1000", apiKey: "key-1", status: http.StatusNoContent},
+				{path: "/ip/a", addr: "10.0.0.6:1000", apiKey: "key-2", status: http.StatusTooManyRequests},
+				{path: "/ip/b", addr: "10.0.0.7:1000", status: http.StatusNoContent},
+			},
+		},
+	}
+
+	t.Log("Given the need to limit the requests of each client.")
+	{
+		for testID, tst := range tt {
+			t.Logf("\tTest %d:\tWhen handling requests limited by %s.", testID, tst.name)
+			{
+				for i, req := range tst.requests {
+					r := httptest.NewRequest(http.MethodGet, req.path, nil)
+					r.RemoteAddr = req.addr
+					if req.subject != "" {
+						r.Header.Set("X-Subject", req.subject)
+					}
+					if req.apiKey != "" {
+						r.Header.Set("X-API-Key", req.apiKey)
+					}
+					w := httptest.NewRecorder()
+					app.ServeHTTP(w, r)
+
+					if w.Code != req.status {
+						t.Fatalf("\t%s\tTest %d:\tShould receive a status code of %d for request %d : got %d.", failed, testID, req.status, i, w.Code)
+					}
+
+					retry := w.Header().Get("Retry-After")
+					switch {
+					case req.status == http.StatusTooManyRequests && retry != "60":
+						t.Fatalf("\t%s\tTest %d:\tShould ask request %d to retry after 60 seconds : got %q.", failed, testID, i, retry)
+					case req.status != http.StatusTooManyRequests && retry != "":
+						t.Fatalf("\t%s\tTest %d:\tShould not ask request %d to retry : got %q.", failed, testID, i, retry)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould allow and refuse the requests with a Retry-After header.", success, testID)
+			}
+		}
+	}
+}
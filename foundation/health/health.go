@@ -0,0 +1,154 @@
+// Package health provides support for checking the health of the components
+// a service depends on.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Set of statuses reported for components and services.
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+// defaultTimeout is used for checks registered without a timeout.
+const defaultTimeout = time.Second
+
+// A Checker reports whether a component is healthy.
+type Checker func(ctx context.Context) error
+
+// Check is a named health check. A service is down when a critical check
+// fails and degraded when any other check fails.
+type Check struct {
+	Name     string
+	Critical bool
+	Timeout  time.Duration
+	Checker  Checker
+}
+
+// Component is the result of a check.
+type Component struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Critical bool    `json:"critical"`
+	Latency  float64 `json:"latency_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Report is the result of running every registered check.
+type Report struct {
+	Status     string      `json:"status"`
+	Checked    time.Time   `json:"checked"`
+	Cached     bool        `json:"cached"`
+	Components []Component `json:"components"`
+}
+
+// Registry holds the checks of a service. The report is cached so frequent
+// probes, or many at once, don't overload the components being checked.
+type Registry struct {
+	ttl    time.Duration
+	mu     sync.Mutex
+	checks []Check
+	report Report
+}
+
+// NewRegistry constructs a registry caching reports for the specified
+// duration. A zero duration runs the checks on every call.
+func NewRegistry(ttl time.Duration) *Registry {
+	return &Registry{
+		ttl: ttl,
+	}
+}
+
+// Register adds the checks to the registry.
+func (r *Registry) Register(checks ...Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, checks...)
+	r.report = Report{}
+}
+
+// Check returns the health of the registered components. The checks run
+// concurrently, each within its own timeout. Callers arriving while the
+// checks run wait for the same report.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if !r.report.Checked.IsZero() && now.Sub(r.report.Checked) < r.ttl {
+		report := r.report
+		report.Cached = true
+		return report
+	}
+
+	components := make([]Component, len(r.checks))
+
+	var wg sync.WaitGroup
+	wg.Add(len(r.checks))
+	for i, c := range r.checks {
+		go func(i int, c Check) {
+			defer wg.Done()
+			components[i] = run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	status := StatusUp
+	for _, c := range components {
+		if c.Status == StatusUp {
+			continue
+		}
+		if c.Critical {
+			status = StatusDown
+			break
+		}
+		status = StatusDegraded
+	}
+
+	report := Report{
+		Status:     status,
+		Checked:    now,
+		Components: components,
+	}
+
+	// A report cut short by the caller going away isn't kept since it
+	// doesn't reflect the health of the components.
+	if ctx.Err() == nil {
+		r.report = report
+	}
+
+	return report
+}
+
+// run executes the check and measures how long it took.
+func run(ctx context.Context, c Check) Component {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Checker(ctx)
+
+	comp := Component{
+		Name:     c.Name,
+		Status:   StatusUp,
+		Critical: c.Critical,
+		Latency:  float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		comp.Status = StatusDown
+		comp.Error = err.Error()
+	}
+
+	return comp
+}
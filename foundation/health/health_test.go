@@ -0,0 +1,122 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/health"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// TestRegistry validates the report built from the registered checks.
+func TestRegistry(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("unreachable") }
+
+	tt := []struct {
+		name   string
+		checks []health.Check
+		status string
+	}{
+		{"up", []health.Check{{Name: "db", Critical: true, Checker: up}, {Name: "feed", Checker: up}}, health.StatusUp},
+		{"degraded", []health.Check{{Name: "db", Critical: true, Checker: up}, {Name: "feed", Checker: down}}, health.StatusDegraded},
+		{"down", []health.Check{{Name: "db", Critical: true, Checker: down}, {Name: "feed", Checker: up}}, health.StatusDown},
+	}
+
+	t.Log("Given the need to report the health of components.")
+	{
+		for testID, test := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen the service is %s.", testID, test.name)
+				{
+					checks := health.NewRegistry(0)
+					checks.Register(test.checks...)
+
+					report := checks.Check(context.Background())
+					if report.Status != test.status {
+						t.Fatalf("\t%s\tTest %d:\tShould report the service is %s : got %s", failed, testID, test.status, report.Status)
+					}
+					t.Logf("\t%s\tTest %d:\tShould report the service is %s.", success, testID, test.status)
+
+					if len(report.Components) != len(test.checks) {
+						t.Fatalf("\t%s\tTest %d:\tShould report every component : got %d", failed, testID, len(report.Components))
+					}
+					for i, c := range report.Components {
+						if c.Name != test.checks[i].Name || (c.Status == health.StatusDown) != (c.Error != "") {
+							t.Fatalf("\t%s\tTest %d:\tShould report every component : got %+v", failed, testID, c)
+						}
+					}
+					t.Logf("\t%s\tTest %d:\tShould report every component.", success, testID)
+				}
+			}
+			t.Run(test.name, tf)
+		}
+	}
+}
+
+// TestCache validates concurrent callers share a cached report.
+func TestCache(t *testing.T) {
+	t.Log("Given the need to avoid overloading the components being checked.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen many callers check at once.", testID)
+		{
+			var calls int32
+			slow := func(ctx context.Context) error {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			}
+
+			checks := health.NewRegistry(time.Minute)
+			checks.Register(health.Check{Name: "db", Critical: true, Checker: slow})
+
+			var wg sync.WaitGroup
+			wg.Add(10)
+			for i := 0; i < 10; i++ {
+				go func() {
+					defer wg.Done()
+					checks.Check(context.Background())
+				}()
+			}
+			wg.Wait()
+
+			if n := atomic.LoadInt32(&calls); n != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould run the check once : got %d", failed, testID, n)
+			}
+			t.Logf("\t%s\tTest %d:\tShould run the check once.", success, testID)
+
+			if report := checks.Check(context.Background()); !report.Cached {
+				t.Fatalf("\t%s\tTest %d:\tShould report the result is cached.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould report the result is cached.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen a check takes longer than its timeout.", testID)
+		{
+			blocked := func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+
+			checks := health.NewRegistry(0)
+			checks.Register(health.Check{Name: "feed", Timeout: 10 * time.Millisecond, Checker: blocked})
+
+			report := checks.Check(context.Background())
+			if report.Status != health.StatusDegraded || report.Components[0].Status != health.StatusDown {
+				t.Fatalf("\t%s\tTest %d:\tShould report the component is down : got %+v", failed, testID, report)
+			}
+			t.Logf("\t%s\tTest %d:\tShould report the component is down.", success, testID)
+		}
+	}
+}
@@ -3,47 +3,93 @@
 package keystore
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
 	"io"
 	"io/fs"
 	"path"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/dgrijalva/jwt-go/v4"
 	"github.com/pkg/errors"
 )
 
+// ActiveFile is the name of the file, inside a directory of PEM files, that
+// holds the kid of the active signing key. The other keys are retired and
+// only used to verify tokens signed before the active key was rotated in.
+const ActiveFile = "active"
+
 // KeyStore represents an in memory store implementation of the
 // KeyStorer interface for use with the auth package.
 type KeyStore struct {
-	mu    sync.RWMutex
-	store map[string]*rsa.PrivateKey
+	mu      sync.RWMutex
+	store   map[string]crypto.Signer
+	active  string
+	fsys    fs.FS
+	version string
 }
 
 // New constructs an empty KeyStore ready for use.
 func New() *KeyStore {
 	return &KeyStore{
-		store: make(map[string]*rsa.PrivateKey),
+		store: make(map[string]crypto.Signer),
 	}
 }
 
-// NewMap constructs a KeyStore with an initial set of keys.
-func NewMap(store map[string]*rsa.PrivateKey) *KeyStore {
-	return &KeyStore{
+// NewMap constructs a KeyStore with an initial set of keys. When the map
+// holds a single key, that key is the active signing key.
+func NewMap(store map[string]crypto.Signer) *KeyStore {
+	ks := KeyStore{
 		store: store,
 	}
+	if len(store) == 1 {
+		for kid := range store {
+			ks.active = kid
+		}
+	}
+	return &ks
 }
 
 // NewFS constructs a KeyStore based on a set of PEM files rooted inside
 // of a directory. The name of each PEM file will be used as the key id.
+// The active signing key is named by the ActiveFile in the directory. When
+// that file doesn't exist, the directory must hold a single key.
 // Example: keystore.NewFS(os.DirFS("/zarf/keys/"))
 // Example: /zarf/keys/54bb2165-71e1-41a6-af3e-7da4a0e1e2c1.pem
 func NewFS(fsys fs.FS) (*KeyStore, error) {
 	ks := KeyStore{
-		store: make(map[string]*rsa.PrivateKey),
+		fsys: fsys,
+	}
+
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+
+	return &ks, nil
+}
+
+// Reload reads the set of PEM files and the active kid again from the
+// directory the KeyStore was constructed with. The keys are replaced only
+// if the whole directory can be read.
+func (ks *KeyStore) Reload() error {
+	if ks.fsys == nil {
+		return errors.New("keystore not constructed from a directory")
 	}
 
+	version, err := ks.fingerprint()
+	if err != nil {
+		return err
+	}
+
+	store := make(map[string]crypto.Signer)
+
 	fn := func(fileName string, dirEntry fs.DirEntry, err error) error {
 		if err != nil {
 			return errors.Wrap(err, "walkdir failure")
@@ -57,7 +103,7 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 			return nil
 		}
 
-		file, err := fsys.Open(fileName)
+		file, err := ks.fsys.Open(fileName)
 		if err != nil {
 			return errors.Wrap(err, "open key file")
 		}
@@ -68,24 +114,136 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 			return errors.Wrap(err, "reading auth private key")
 		}
 
-		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+		privateKey, err := ParsePrivateKey(privatePEM)
 		if err != nil {
-			return errors.Wrap(err, "parsing auth private key")
+			return errors.Wrapf(err, "parsing auth private key %s", fileName)
 		}
 
-		ks.store[strings.TrimRight(dirEntry.Name(), ".pem")] = privateKey
+		store[strings.TrimSuffix(dirEntry.Name(), ".pem")] = privateKey
 		return nil
 	}
 
-	if err := fs.WalkDir(fsys, ".", fn); err != nil {
-		return nil, errors.Wrap(err, "walking directory")
+	if err := fs.WalkDir(ks.fsys, ".", fn); err != nil {
+		return errors.Wrap(err, "walking directory")
 	}
 
-	return &ks, nil
+	active, err := fs.ReadFile(ks.fsys, ActiveFile)
+	switch {
+	case err == nil:
+	case errors.Is(err, fs.ErrNotExist) && len(store) == 1:
+		for kid := range store {
+			active = []byte(kid)
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		return errors.Errorf("%s file is required with %d keys", ActiveFile, len(store))
+	default:
+		return errors.Wrap(err, "reading active kid")
+	}
+
+	kid := strings.TrimSpace(string(active))
+	if _, exists := store[kid]; !exists {
+		return errors.Errorf("active kid %q has no key", kid)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.store = store
+	ks.active = kid
+	ks.version = version
+
+	return nil
+}
+
+// Watch polls the directory the KeyStore was constructed with at the
+// specified interval and reloads the keys when any of the files change. It
+// blocks until the context is cancelled. Errors are reported to the
+// provided function and the previous keys remain in use.
+func (ks *KeyStore) Watch(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		version, err := ks.fingerprint()
+		if err != nil {
+			onError(err)
+			continue
+		}
+
+		ks.mu.RLock()
+		changed := version != ks.version
+		ks.mu.RUnlock()
+
+		if !changed {
+			continue
+		}
+
+		if err := ks.Reload(); err != nil {
+			onError(err)
+		}
+	}
+}
+
+// fingerprint summarizes the name, size and modification time of every
+// file in the directory so changes can be detected.
+func (ks *KeyStore) fingerprint() (string, error) {
+	var b strings.Builder
+
+	fn := func(fileName string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Wrap(err, "walkdir failure")
+		}
+
+		if dirEntry.IsDir() {
+			return nil
+		}
+
+		info, err := dirEntry.Info()
+		if err != nil {
+			return errors.Wrap(err, "reading file info")
+		}
+
+		fmt.Fprintf(&b, "%s:%d:%d;", fileName, info.Size(), info.ModTime().UnixNano())
+		return nil
+	}
+
+	if err := fs.WalkDir(ks.fsys, ".", fn); err != nil {
+		return "", errors.Wrap(err, "walking directory")
+	}
+
+	return b.String(), nil
+}
+
+// ActiveKID returns the kid of the key used to sign new tokens.
+func (ks *KeyStore) ActiveKID() string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.active
+}
+
+// SetActive makes the key with the specified kid the one used to sign new
+// tokens. The key must already be in the store.
+func (ks *KeyStore) SetActive(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, exists := ks.store[kid]; !exists {
+		return errors.New("kid lookup failed")
+	}
+
+	ks.active = kid
+	return nil
 }
 
 // Add adds a private key and combination kid to the store.
-func (ks *KeyStore) Add(privateKey *rsa.PrivateKey, kid string) {
+func (ks *KeyStore) Add(privateKey crypto.Signer, kid string) {
 	ks.mu.Lock()
 	defer ks.mu.Unlock()
 
@@ -102,7 +260,7 @@ func (ks *KeyStore) Remove(kid string) {
 
 // PrivateKey searches the key store for a given kid and returns
 // the private key.
-func (ks *KeyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
+func (ks *KeyStore) PrivateKey(kid string) (crypto.Signer, error) {
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
@@ -115,7 +273,7 @@ func (ks *KeyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
 
 // PublicKey searches the key store for a given kid and returns
 // the public key.
-func (ks *KeyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
+func (ks *KeyStore) PublicKey(kid string) (crypto.PublicKey, error) {
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
@@ -123,5 +281,61 @@ func (ks *KeyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
 	if !found {
 		return nil, errors.New("kid lookup failed")
 	}
-	return &privateKey.PublicKey, nil
+	return privateKey.Public(), nil
+}
+
+// PublicKeys returns the public key for every kid in the store so they can
+// be published to the services verifying our tokens.
+func (ks *KeyStore) PublicKeys() map[string]crypto.PublicKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	publicKeys := make(map[string]crypto.PublicKey, len(ks.store))
+	for kid, privateKey := range ks.store {
+		publicKeys[kid] = privateKey.Public()
+	}
+	return publicKeys
+}
+
+// ParsePrivateKey parses a PEM encoded RSA, ECDSA or Ed25519 private key.
+// PKCS1 (RSA PRIVATE KEY), SEC1 (EC PRIVATE KEY) and PKCS8 (PRIVATE KEY)
+// blocks are supported.
+func ParsePrivateKey(privatePEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(privatePEM)
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			return key, nil
+		case *ecdsa.PrivateKey:
+			return key, nil
+		case ed25519.PrivateKey:
+			return key, nil
+		}
+		return nil, errors.Errorf("unsupported private key type %T", key)
+	}
+
+	return nil, errors.Errorf("unsupported PEM block type %q", block.Type)
 }
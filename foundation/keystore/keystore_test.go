@@ -3,6 +3,15 @@ package keystore_test
 import (
 	_ "embed" // Embed all sql documents
 
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"strings"
 	"testing"
 	"testing/fstest"
@@ -23,7 +32,7 @@ func TestRead(t *testing.T) {
 	t.Log("Given the need to parse a directory of private key files.")
 	{
 		fileName := "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1.pem"
-		keyID := strings.TrimRight(fileName, ".pem")
+		keyID := strings.TrimSuffix(fileName, ".pem")
 		fsys := fstest.MapFS{}
 		fsys[fileName] = &fstest.MapFile{Data: keyDoc}
 
@@ -42,10 +51,163 @@ func TestRead(t *testing.T) {
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to find key in store.", success, testID)
 
-			if err := pk.Validate(); err != nil {
+			rsaKey, ok := pk.(*rsa.PrivateKey)
+			if !ok {
+				t.Fatalf("\t%s\tTest %d:\tShould be an RSA key: got %T", failed, testID, pk)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be an RSA key.", success, testID)
+
+			if err := rsaKey.Validate(); err != nil {
 				t.Fatalf("\t%s\tTest %d:\tShould be able to validate the key: %v", failed, testID, err)
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to validate the key.", success, testID)
 		}
 	}
 }
+
+func TestActive(t *testing.T) {
+	t.Log("Given the need to sign with the active key of a rotated directory.")
+	{
+		const (
+			retiredKID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+			activeKID  = "4754d86b-7a6d-4df5-9c65-224741361492"
+		)
+		fsys := fstest.MapFS{}
+		fsys[retiredKID+".pem"] = &fstest.MapFile{Data: keyDoc}
+		fsys[activeKID+".pem"] = &fstest.MapFile{Data: keyDoc}
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a directory of %d key(s).", testID, 2)
+		{
+			if _, err := keystore.NewFS(fsys); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not be able to construct key store without an active file.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not be able to construct key store without an active file.", success, testID)
+
+			fsys[keystore.ActiveFile] = &fstest.MapFile{Data: []byte(activeKID + "\n")}
+
+			ks, err := keystore.NewFS(fsys)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct key store: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to construct key store.", success, testID)
+
+			if got := ks.ActiveKID(); got != activeKID {
+				t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+				t.Logf("\t\tTest %d:\texp: %v", testID, activeKID)
+				t.Fatalf("\t%s\tTest %d:\tShould have the active kid.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have the active kid.", success, testID)
+
+			if _, err := ks.PublicKey(retiredKID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould keep the retired key for verification: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep the retired key for verification.", success, testID)
+
+			delete(fsys, retiredKID+".pem")
+			if err := ks.Reload(); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reload the key store: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to reload the key store.", success, testID)
+
+			if _, err := ks.PublicKey(retiredKID); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not find the removed key after a reload.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not find the removed key after a reload.", success, testID)
+
+			fsys[keystore.ActiveFile] = &fstest.MapFile{Data: []byte(retiredKID)}
+			if err := ks.Reload(); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not reload with an active kid that has no key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not reload with an active kid that has no key.", success, testID)
+
+			if got := ks.ActiveKID(); got != activeKID {
+				t.Fatalf("\t%s\tTest %d:\tShould keep the previous keys after a failed reload: got %s", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep the previous keys after a failed reload.", success, testID)
+		}
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	type tableTest struct {
+		name     string
+		generate func() (crypto.Signer, error)
+		block    func(key crypto.Signer) (*pem.Block, error)
+	}
+
+	pkcs8 := func(key crypto.Signer) (*pem.Block, error) {
+		b, err := x509.MarshalPKCS8PrivateKey(key)
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: b}, err
+	}
+
+	tt := []tableTest{
+		{
+			"rsa pkcs1",
+			func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 2048) },
+			func(key crypto.Signer) (*pem.Block, error) {
+				return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey))}, nil
+			},
+		},
+		{
+			"ecdsa sec1",
+			func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) },
+			func(key crypto.Signer) (*pem.Block, error) {
+				b, err := x509.MarshalECPrivateKey(key.(*ecdsa.PrivateKey))
+				return &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}, err
+			},
+		},
+		{
+			"ecdsa pkcs8",
+			func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P384(), rand.Reader) },
+			pkcs8,
+		},
+		{
+			"ed25519 pkcs8",
+			func() (crypto.Signer, error) {
+				_, pk, err := ed25519.GenerateKey(rand.Reader)
+				return pk, err
+			},
+			pkcs8,
+		},
+	}
+
+	t.Log("Given the need to parse private keys of different types.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen handling a %s key.", testID, test.name)
+			{
+				key, err := test.generate()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to generate a key: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to generate a key.", success, testID)
+
+				block, err := test.block(key)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to encode the key: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to encode the key.", success, testID)
+
+				parsed, err := keystore.ParsePrivateKey(pem.EncodeToMemory(block))
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to parse the key: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to parse the key.", success, testID)
+
+				exp, err := x509.MarshalPKIXPublicKey(key.Public())
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to marshal the public key: %v", failed, testID, err)
+				}
+				got, err := x509.MarshalPKIXPublicKey(parsed.Public())
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to marshal the parsed public key: %v", failed, testID, err)
+				}
+				if !bytes.Equal(exp, got) {
+					t.Fatalf("\t%s\tTest %d:\tShould parse the same key.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould parse the same key.", success, testID)
+			}
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Package logger provides support for structured logging with levels. Each
+// entry is written as a single line of JSON or as human readable text.
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Level represents the severity of a log entry.
+type Level int
+
+// Set of levels supported by the logger.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// ParseLevel returns the level for the specified name.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, errors.Errorf("unknown log level %q", name)
+}
+
+// Set of formats supported by the logger.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Config represents the settings for constructing a logger.
+type Config struct {
+	Service string
+	Level   Level
+	Format  string
+}
+
+// output is shared by a logger and every logger derived from it so lines
+// written from different goroutines never interleave.
+type output struct {
+	mu      sync.Mutex
+	w       io.Writer
+	service string
+	level   Level
+	format  string
+}
+
+// Logger writes structured log entries. Each entry has a message and a list
+// of alternating key/value pairs describing it.
+type Logger struct {
+	out    *output
+	fields []interface{}
+}
+
+// New constructs a logger writing entries at or above the configured level
+// to the specified writer.
+func New(w io.Writer, cfg Config) (*Logger, error) {
+	switch cfg.Format {
+	case "":
+		cfg.Format = FormatJSON
+	case FormatJSON, FormatConsole:
+	default:
+		return nil, errors.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	out := output{
+		w:       w,
+		service: cfg.Service,
+		level:   cfg.Level,
+		format:  cfg.Format,
+	}
+
+	return &Logger{out: &out}, nil
+}
+
+// With returns a logger adding the key/value pairs to every entry.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(kv))
+	fields = append(fields, l.fields...)
+	fields = append(fields, kv...)
+
+	return &Logger{
+		out:    l.out,
+		fields: fields,
+	}
+}
+
+// Enabled reports whether entries at the specified level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.out.level
+}
+
+// Debug writes an entry for information useful when diagnosing a problem.
+func (l *Logger) Debug(msg string, kv ...interface{}) {
+	l.write(LevelDebug, msg, kv)
+}
+
+// Info writes an entry for normal operation of the service.
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.write(LevelInfo, msg, kv)
+}
+
+// Warn writes an entry for an unexpected situation the service recovered from.
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.write(LevelWarn, msg, kv)
+}
+
+// Error writes an entry for a failure.
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.write(LevelError, msg, kv)
+}
+
+// StdLogger returns a standard library logger writing each line as an entry
+// at the specified level. It's used by packages like net/http that require a
+// *log.Logger.
+func (l *Logger) StdLogger(level Level) *log.Logger {
+	return log.New(stdWriter{log: l, level: level}, "", 0)
+}
+
+// stdWriter adapts the logger to the io.Writer used by a standard library
+// logger.
+type stdWriter struct {
+	log   *Logger
+	level Level
+}
+
+// Write implements the io.Writer interface.
+func (w stdWriter) Write(p []byte) (int, error) {
+	w.log.write(w.level, strings.TrimSpace(string(p)), nil)
+	return len(p), nil
+}
+
+// write formats the entry and writes it as a single line.
+func (l *Logger) write(level Level, msg string, kv []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	// Skip write and the level method to find the caller.
+	caller := "???"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		caller = filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+
+	fields := make([]interface{}, 0, len(l.fields)+len(kv))
+	fields = append(fields, l.fields...)
+	fields = append(fields, kv...)
+
+	var b strings.Builder
+	now := time.Now().UTC()
+
+	switch l.out.format {
+	case FormatConsole:
+		b.WriteString(now.Format("2006-01-02T15:04:05.000000Z07:00"))
+		fmt.Fprintf(&b, " %-5s %s %s: %s", level, l.out.service, caller, msg)
+		for i := 0; i < len(fields); i += 2 {
+			key, val := pair(fields, i)
+			b.WriteString(" " + key + "=" + consoleValue(val))
+		}
+
+	default:
+		b.WriteString(`{"time":`)
+		b.WriteString(jsonValue(now.Format(time.RFC3339Nano)))
+		b.WriteString(`,"level":` + jsonValue(level.String()))
+		b.WriteString(`,"service":` + jsonValue(l.out.service))
+		b.WriteString(`,"caller":` + jsonValue(caller))
+		b.WriteString(`,"msg":` + jsonValue(msg))
+		for i := 0; i < len(fields); i += 2 {
+			key, val := pair(fields, i)
+			b.WriteString("," + jsonValue(key) + ":" + jsonValue(val))
+		}
+		b.WriteString("}")
+	}
+	b.WriteString("\n")
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+
+	io.WriteString(l.out.w, b.String())
+}
+
+// pair returns the key/value pair starting at the specified index. A missing
+// value is reported rather than dropping the key.
+func pair(fields []interface{}, i int) (string, interface{}) {
+	key, ok := fields[i].(string)
+	if !ok {
+		key = fmt.Sprint(fields[i])
+	}
+	if i+1 >= len(fields) {
+		return key, "!MISSING"
+	}
+	return key, fields[i+1]
+}
+
+// jsonValue returns the JSON encoding of the value. Errors and durations are
+// written as text.
+func jsonValue(v interface{}) string {
+	switch act := v.(type) {
+	case error:
+		v = act.Error()
+	case time.Duration:
+		v = act.String()
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		buf.Reset()
+		enc.Encode(fmt.Sprint(v))
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// consoleValue returns the text form of the value, quoted when it contains
+// spaces so the fields stay readable.
+func consoleValue(v interface{}) string {
+	var s string
+	switch act := v.(type) {
+	case error:
+		s = act.Error()
+	case string:
+		s = act
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/travel/foundation/logger"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// TestLogger validates entries are written as structured data and filtered
+// by level.
+func TestLogger(t *testing.T) {
+	t.Log("Given the need to write structured log entries.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen writing JSON entries.", testID)
+		{
+			var buf bytes.Buffer
+			log, err := logger.New(&buf, logger.Config{Service: "travel-api", Level: logger.LevelInfo, Format: logger.FormatJSON})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct a logger: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to construct a logger.", success, testID)
+
+			log = log.With("traceid", "4bf92f3577b34da6a3ce929d0e0e4736")
+			log.Debug("not written")
+			log.Error("request failed", "status", 500, "error", errors.New("db not ready"))
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould only write entries at or above the level: %d", failed, testID, len(lines))
+			}
+			t.Logf("\t%s\tTest %d:\tShould only write entries at or above the level.", success, testID)
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to decode the entry: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to decode the entry.", success, testID)
+
+			exp := map[string]interface{}{
+				"level":   "ERROR",
+				"service": "travel-api",
+				"msg":     "request failed",
+				"traceid": "4bf92f3577b34da6a3ce929d0e0e4736",
+				"status":  float64(500),
+				"error":   "db not ready",
+			}
+			for k, v := range exp {
+				if entry[k] != v {
+					t.Logf("\t\tTest %d:\tgot: %v", testID, entry[k])
+					t.Logf("\t\tTest %d:\texp: %v", testID, v)
+					t.Fatalf("\t%s\tTest %d:\tShould have the %s field.", failed, testID, k)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould have the expected fields.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen writing console entries.", testID)
+		{
+			var buf bytes.Buffer
+			log, err := logger.New(&buf, logger.Config{Service: "travel-admin", Level: logger.LevelDebug, Format: logger.FormatConsole})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct a logger: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to construct a logger.", success, testID)
+
+			log.Debug("adding city", "city", "new york")
+
+			got := buf.String()
+			if !strings.Contains(got, "DEBUG travel-admin") || !strings.HasSuffix(got, `adding city city="new york"`+"\n") {
+				t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+				t.Fatalf("\t%s\tTest %d:\tShould write the entry as text.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould write the entry as text.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen using an unknown format.", testID)
+		{
+			if _, err := logger.New(&bytes.Buffer{}, logger.Config{Format: "xml"}); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the format.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the format.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package ratelimit provides support for limiting the rate of requests made
+// by clients using token buckets held in memory.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit represents the rate at which tokens are added to a bucket and the
+// number of tokens the bucket holds. The burst is the number of requests a
+// client can make at once after being idle. A zero rate means no limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every returns a limit allowing one request per interval with the specified
+// burst.
+func Every(interval time.Duration, burst int) Limit {
+	if interval <= 0 {
+		return Limit{}
+	}
+	return Limit{
+		Rate:  1 / interval.Seconds(),
+		Burst: burst,
+	}
+}
+
+// bucket tracks the tokens left for one client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Store holds a token bucket for each key. Buckets that are full again after
+// being idle are removed by Evict since they behave like a new bucket.
+type Store struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewStore constructs an empty store.
+func NewStore() *Store {
+	return &Store{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket for the key. When the bucket is empty
+// the request is refused and the time until a token is available is
+// returned.
+func (s *Store) Allow(key string, limit Limit, now time.Time) (bool, time.Duration) {
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+
+	// Add the tokens earned since the last request.
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed.Seconds()*limit.Rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait
+}
+
+// Evict removes the buckets not used since the specified time and returns
+// the number removed.
+func (s *Store) Evict(before time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var evicted int
+	for key, b := range s.buckets {
+		if b.last.Before(before) {
+			delete(s.buckets, key)
+			evicted++
+		}
+	}
+
+	return evicted
+}
+
+// Len returns the number of buckets in the store.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.buckets)
+}
+
+// Run evicts the buckets idle for longer than the specified duration at each
+// interval. It blocks until the context is cancelled. The idle duration
+// should be long enough for a bucket of the slowest limit to fill up again.
+func (s *Store) Run(ctx context.Context, interval time.Duration, idle time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Evict(now.Add(-idle))
+		}
+	}
+}
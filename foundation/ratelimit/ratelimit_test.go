@@ -0,0 +1,68 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/ratelimit"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// TestStore validates clients are limited to the rate of their bucket.
+func TestStore(t *testing.T) {
+	t.Log("Given the need to limit the requests of clients.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a client making a burst of requests.", testID)
+		{
+			store := ratelimit.NewStore()
+			limit := ratelimit.Every(time.Second, 2)
+			now := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+			for i := 0; i < 2; i++ {
+				if ok, _ := store.Allow("ip:10.0.0.1", limit, now); !ok {
+					t.Fatalf("\t%s\tTest %d:\tShould allow request %d of the burst.", failed, testID, i)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould allow the burst.", success, testID)
+
+			ok, wait := store.Allow("ip:10.0.0.1", limit, now.Add(250*time.Millisecond))
+			if ok || wait != 750*time.Millisecond {
+				t.Fatalf("\t%s\tTest %d:\tShould refuse the request until a token is added: %v %v", failed, testID, ok, wait)
+			}
+			t.Logf("\t%s\tTest %d:\tShould refuse the request until a token is added.", success, testID)
+
+			if ok, _ := store.Allow("ip:10.0.0.2", limit, now); !ok {
+				t.Fatalf("\t%s\tTest %d:\tShould allow requests of other clients.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould allow requests of other clients.", success, testID)
+
+			if ok, _ := store.Allow("ip:10.0.0.1", limit, now.Add(time.Second)); !ok {
+				t.Fatalf("\t%s\tTest %d:\tShould allow the request once a token is added.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould allow the request once a token is added.", success, testID)
+
+			if n := store.Evict(now.Add(500 * time.Millisecond)); n != 1 || store.Len() != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould evict the idle client: %d", failed, testID, n)
+			}
+			t.Logf("\t%s\tTest %d:\tShould evict the idle client.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen handling a route without a limit.", testID)
+		{
+			store := ratelimit.NewStore()
+			for i := 0; i < 100; i++ {
+				if ok, _ := store.Allow("ip:10.0.0.1", ratelimit.Limit{}, time.Now()); !ok {
+					t.Fatalf("\t%s\tTest %d:\tShould allow every request.", failed, testID)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould allow every request.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package tracer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WriterExporter writes spans as JSON lines. Writing to stdout is useful for
+// local development.
+type WriterExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter constructs an exporter writing spans to the specified writer.
+func NewWriter(w io.Writer) *WriterExporter {
+	return &WriterExporter{
+		w: w,
+	}
+}
+
+// Export implements the Exporter interface.
+func (e *WriterExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	enc := json.NewEncoder(e.w)
+	for _, span := range spans {
+		if err := enc.Encode(span); err != nil {
+			return errors.Wrap(err, "writing span")
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// OTLPExporter posts spans to an OpenTelemetry collector using the OTLP/HTTP
+// protocol with JSON encoding.
+// https://opentelemetry.io/docs/specs/otlp/#otlphttp
+type OTLPExporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewOTLP constructs an exporter posting spans to the specified url, such as
+// http://localhost:4318/v1/traces.
+func NewOTLP(url string) *OTLPExporter {
+	return &OTLPExporter{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Export implements the Exporter interface.
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	data, err := json.Marshal(otlpRequest(spans))
+	if err != nil {
+		return errors.Wrap(err, "marshal spans")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "new request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "client do")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("otlp: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// These types represent the subset of the OTLP trace request used to
+// deliver spans.
+type (
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// otlpStatusError is the OTLP status code for a failed span.
+const otlpStatusError = 2
+
+// otlpRequest groups the spans by service into an OTLP trace request.
+func otlpRequest(spans []SpanData) otlpTraces {
+	var req otlpTraces
+	services := make(map[string]int)
+
+	for _, span := range spans {
+		i, exists := services[span.Service]
+		if !exists {
+			i = len(req.ResourceSpans)
+			services[span.Service] = i
+			req.ResourceSpans = append(req.ResourceSpans, otlpResourceSpans{
+				Resource: otlpResource{
+					Attributes: []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: span.Service}}},
+				},
+				ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/dgraph-io/travel/foundation/tracer"}}},
+			})
+		}
+
+		s := otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentID,
+			Name:              span.Name,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		}
+		for k, v := range span.Attributes {
+			s.Attributes = append(s.Attributes, otlpAttribute{Key: k, Value: otlpValue{StringValue: v}})
+		}
+		sort.Slice(s.Attributes, func(a, b int) bool { return s.Attributes[a].Key < s.Attributes[b].Key })
+		if span.Error != "" {
+			s.Status = otlpStatus{Code: otlpStatusError, Message: span.Error}
+		}
+
+		scope := &req.ResourceSpans[i].ScopeSpans[0]
+		scope.Spans = append(scope.Spans, s)
+	}
+
+	return req
+}
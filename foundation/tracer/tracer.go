@@ -0,0 +1,424 @@
+// Package tracer provides support for tracing requests across services using
+// the W3C Trace Context standard. Finished spans are handed to an exporter in
+// batches.
+//
+// This is a small hand-written tracer rather than OpenTelemetry since the
+// OpenTelemetry modules could not be vendored into the project. It only
+// implements what the services need: traceparent propagation, parent/child
+// spans and exporting batches as JSON or OTLP/HTTP. Replacing it with the
+// OpenTelemetry SDK should only require changing this package.
+// https://w3c.github.io/trace-context/
+package tracer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Header is the name of the header carrying the trace context.
+const Header = "traceparent"
+
+// TraceID identifies a trace across all of the services it touches.
+type TraceID [16]byte
+
+// String returns the hex encoding of the trace id.
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// String returns the hex encoding of the span id.
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext is the part of a span that is propagated to other services.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// String returns the span context in the traceparent header format.
+func (sc SpanContext) String() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// Parse reads a span context in the traceparent header format.
+func Parse(traceparent string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+
+	// Version 00 has exactly four fields. Later versions may add more.
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if n, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil || n != len(sc.TraceID) || len(parts[1]) != 32 {
+		return SpanContext{}, false
+	}
+	if n, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil || n != len(sc.SpanID) || len(parts[2]) != 16 {
+		return SpanContext{}, false
+	}
+	if sc.TraceID == (TraceID{}) || sc.SpanID == (SpanID{}) {
+		return SpanContext{}, false
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+
+	return sc, true
+}
+
+// Extract returns the span context of the caller found in the headers.
+func Extract(h http.Header) (SpanContext, bool) {
+	return Parse(h.Get(Header))
+}
+
+// Inject adds the span context of the span in the context to the headers so
+// the trace continues in the service being called.
+func Inject(ctx context.Context, h http.Header) {
+	if span := SpanFromContext(ctx); span != nil {
+		h.Set(Header, span.sc.String())
+	}
+}
+
+// =============================================================================
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// Set of keys used to store/retrieve values from a context.
+const (
+	spanKey ctxKey = iota + 1
+	remoteKey
+)
+
+// ContextWithSpan returns a new context carrying the span. It's used to
+// continue a trace in work that outlives the original context.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey, span)
+}
+
+// SpanFromContext returns the current span in the context or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// ContextWithRemote returns a new context carrying the span context of a
+// caller in another service. The next span started becomes its child.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey, sc)
+}
+
+// Start begins a new child span of the span in the context using the same
+// tracer. When the context holds no span, the returned span is nil which is
+// safe to use and records nothing.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name)
+}
+
+// =============================================================================
+
+// SpanData is the record of a finished span handed to the exporter.
+type SpanData struct {
+	Service    string            `json:"service"`
+	Name       string            `json:"name"`
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	ParentID   string            `json:"parent_id,omitempty"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Span represents a unit of work within a trace. All methods are safe to
+// call on a nil span.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the identity of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute records a key/value pair describing the work of the span.
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]string)
+	}
+	s.data.Attributes[key] = value
+}
+
+// RecordError marks the span as failed with the specified error.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Error = err.Error()
+}
+
+// End finishes the span and hands it to the tracer for export. Only the
+// first call has any effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.sc.Sampled {
+		s.tracer.export(data)
+	}
+}
+
+// =============================================================================
+
+// Exporter declares the behavior for delivering finished spans somewhere.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// Config represents the settings for batching spans to the exporter.
+type Config struct {
+	Service   string
+	Exporter  Exporter
+	BatchSize int
+	Interval  time.Duration
+	OnError   func(err error)
+}
+
+// Tracer starts spans and exports them in batches once they end. A nil
+// Tracer still starts spans so trace ids are available, but never exports.
+type Tracer struct {
+	cfg     Config
+	spans   chan SpanData
+	done    chan struct{}
+	dropped uint64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New constructs a Tracer and starts the goroutine exporting the spans.
+func New(cfg Config) *Tracer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+
+	t := Tracer{
+		cfg:   cfg,
+		spans: make(chan SpanData, cfg.BatchSize*10),
+		done:  make(chan struct{}),
+	}
+
+	go t.run()
+
+	return &t
+}
+
+// Start begins a new span. The span is a child of the span in the context or
+// of the remote caller. Otherwise it starts a new trace.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	var parentID SpanID
+	sc := SpanContext{Sampled: true}
+
+	switch parent := SpanFromContext(ctx); {
+	case parent != nil:
+		sc.TraceID = parent.sc.TraceID
+		sc.Sampled = parent.sc.Sampled
+		parentID = parent.sc.SpanID
+	default:
+		if remote, ok := ctx.Value(remoteKey).(SpanContext); ok {
+			sc.TraceID = remote.TraceID
+			sc.Sampled = remote.Sampled
+			parentID = remote.SpanID
+		} else {
+			rand.Read(sc.TraceID[:])
+		}
+	}
+	rand.Read(sc.SpanID[:])
+
+	span := Span{
+		tracer: t,
+		sc:     sc,
+		data: SpanData{
+			Name:    name,
+			TraceID: sc.TraceID.String(),
+			SpanID:  sc.SpanID.String(),
+			Start:   time.Now(),
+		},
+	}
+	if parentID != (SpanID{}) {
+		span.data.ParentID = parentID.String()
+	}
+	if t != nil {
+		span.data.Service = t.cfg.Service
+	}
+
+	return ContextWithSpan(ctx, &span), &span
+}
+
+// Shutdown stops accepting spans and waits for the remaining spans to be
+// exported or for the context to be done.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.spans)
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Dropped returns the number of spans dropped since the tracer started.
+func (t *Tracer) Dropped() uint64 {
+	if t == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&t.dropped)
+}
+
+// export queues the span for the next batch. Spans are dropped if the queue
+// is full so tracing never blocks a request. Drops are counted and reported
+// once per interval instead of for every span.
+func (t *Tracer) export(data SpanData) {
+	if t == nil {
+		return
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return
+	}
+
+	select {
+	case t.spans <- data:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+// run collects spans into batches and exports them when the batch is full or
+// the interval expires.
+func (t *Tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.cfg.Interval)
+	defer ticker.Stop()
+
+	// reported is the number of dropped spans already reported.
+	var reported uint64
+	reportDropped := func() {
+		dropped := atomic.LoadUint64(&t.dropped)
+		if dropped > reported {
+			t.error(fmt.Errorf("span queue full: dropped %d spans", dropped-reported))
+			reported = dropped
+		}
+	}
+
+	batch := make([]SpanData, 0, t.cfg.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := t.cfg.Exporter.Export(ctx, batch); err != nil {
+			t.error(err)
+		}
+		batch = make([]SpanData, 0, t.cfg.BatchSize)
+	}
+
+	for {
+		select {
+		case data, ok := <-t.spans:
+			if !ok {
+				flush()
+				reportDropped()
+				return
+			}
+			batch = append(batch, data)
+			if len(batch) >= t.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			reportDropped()
+		}
+	}
+}
+
+func (t *Tracer) error(err error) {
+	if t.cfg.OnError != nil {
+		t.cfg.OnError(err)
+	}
+}
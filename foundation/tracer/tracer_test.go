@@ -0,0 +1,230 @@
+package tracer_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/tracer"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// TestParse validates traceparent headers are read and written.
+func TestParse(t *testing.T) {
+	tt := []struct {
+		name        string
+		traceparent string
+		valid       bool
+	}{
+		{"valid", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"notsampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true},
+		{"zerotrace", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"shorttrace", "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", false},
+		{"badversion", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"garbage", "not a traceparent", false},
+	}
+
+	t.Log("Given the need to propagate the trace context between services.")
+	{
+		for testID, test := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen handling a %s traceparent.", testID, test.name)
+				{
+					sc, ok := tracer.Parse(test.traceparent)
+					if ok != test.valid {
+						t.Fatalf("\t%s\tTest %d:\tShould get back valid %v : %v", failed, testID, test.valid, ok)
+					}
+					t.Logf("\t%s\tTest %d:\tShould get back valid %v.", success, testID, test.valid)
+
+					if ok && sc.String() != test.traceparent {
+						t.Logf("\t\tTest %d:\tgot: %v", testID, sc.String())
+						t.Logf("\t\tTest %d:\texp: %v", testID, test.traceparent)
+						t.Fatalf("\t%s\tTest %d:\tShould write back the same traceparent.", failed, testID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould write back the same traceparent.", success, testID)
+				}
+			}
+			t.Run(test.name, tf)
+		}
+	}
+}
+
+// TestTracer validates spans continue the trace of the caller and are
+// exported once they end.
+func TestTracer(t *testing.T) {
+	t.Log("Given the need to trace requests.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a remote parent and a child span.", testID)
+		{
+			var buf bytes.Buffer
+			tr := tracer.New(tracer.Config{
+				Service:  "travel-api",
+				Exporter: tracer.NewWriter(&buf),
+			})
+
+			remote, _ := tracer.Parse("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			ctx := tracer.ContextWithRemote(context.Background(), remote)
+
+			ctx, root := tr.Start(ctx, "GET /v1/users")
+			_, child := tracer.Start(ctx, "user.QueryAll")
+			child.SetAttribute("rows", "20")
+			child.End()
+			root.End()
+
+			if root.SpanContext().TraceID != remote.TraceID || child.SpanContext().TraceID != remote.TraceID {
+				t.Fatalf("\t%s\tTest %d:\tShould continue the trace of the caller.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould continue the trace of the caller.", success, testID)
+
+			h := make(http.Header)
+			tracer.Inject(ctx, h)
+			if sc, ok := tracer.Extract(h); !ok || sc.SpanID != root.SpanContext().SpanID {
+				t.Fatalf("\t%s\tTest %d:\tShould inject the current span into the headers: %v", failed, testID, h)
+			}
+			t.Logf("\t%s\tTest %d:\tShould inject the current span into the headers.", success, testID)
+
+			if err := tr.Shutdown(context.Background()); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to shutdown the tracer: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to shutdown the tracer.", success, testID)
+
+			var spans []tracer.SpanData
+			s := bufio.NewScanner(&buf)
+			for s.Scan() {
+				var span tracer.SpanData
+				if err := json.Unmarshal(s.Bytes(), &span); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to decode the span: %v", failed, testID, err)
+				}
+				spans = append(spans, span)
+			}
+
+			if len(spans) != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould export both spans: %d", failed, testID, len(spans))
+			}
+			t.Logf("\t%s\tTest %d:\tShould export both spans.", success, testID)
+
+			exp := []string{root.SpanContext().SpanID.String(), remote.SpanID.String()}
+			if spans[0].ParentID != exp[0] || spans[1].ParentID != exp[1] {
+				t.Logf("\t\tTest %d:\tgot: %v %v", testID, spans[0].ParentID, spans[1].ParentID)
+				t.Logf("\t\tTest %d:\texp: %v", testID, exp)
+				t.Fatalf("\t%s\tTest %d:\tShould record the parent of each span.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould record the parent of each span.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen handling an OTLP collector.", testID)
+		{
+			received := make(chan []byte, 1)
+			f := func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				received <- data
+			}
+			server := httptest.NewServer(http.HandlerFunc(f))
+			t.Cleanup(server.Close)
+
+			tr := tracer.New(tracer.Config{
+				Service:  "travel-api",
+				Exporter: tracer.NewOTLP(server.URL),
+				Interval: time.Hour,
+			})
+
+			_, span := tr.Start(context.Background(), "GET /v1/users")
+			span.End()
+
+			if err := tr.Shutdown(context.Background()); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to shutdown the tracer: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to shutdown the tracer.", success, testID)
+
+			var req struct {
+				ResourceSpans []struct {
+					ScopeSpans []struct {
+						Spans []struct {
+							TraceID string `json:"traceId"`
+							Name    string `json:"name"`
+						} `json:"spans"`
+					} `json:"scopeSpans"`
+				} `json:"resourceSpans"`
+			}
+			if err := json.Unmarshal(<-received, &req); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to decode the request: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to decode the request.", success, testID)
+
+			if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould post the span.", failed, testID)
+			}
+			if got := req.ResourceSpans[0].ScopeSpans[0].Spans[0]; got.TraceID != span.SpanContext().TraceID.String() {
+				t.Fatalf("\t%s\tTest %d:\tShould post the span with its trace id: %s", failed, testID, got.TraceID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould post the span.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen handling a full span queue.", testID)
+		{
+			release := make(chan struct{})
+			var mu sync.Mutex
+			var errs []string
+
+			tr := tracer.New(tracer.Config{
+				Service:   "travel-api",
+				Exporter:  blockingExporter(release),
+				BatchSize: 1,
+				Interval:  time.Hour,
+				OnError: func(err error) {
+					mu.Lock()
+					defer mu.Unlock()
+					errs = append(errs, err.Error())
+				},
+			})
+
+			for i := 0; i < 50; i++ {
+				_, span := tr.Start(context.Background(), "GET /v1/users")
+				span.End()
+			}
+			close(release)
+
+			if err := tr.Shutdown(context.Background()); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to shutdown the tracer: %v", failed, testID, err)
+			}
+
+			dropped := tr.Dropped()
+			if dropped == 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould count the dropped spans.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould count the dropped spans.", success, testID)
+
+			exp := fmt.Sprintf("span queue full: dropped %d spans", dropped)
+			if len(errs) != 1 || errs[0] != exp {
+				t.Logf("\t\tTest %d:\tgot: %v", testID, errs)
+				t.Logf("\t\tTest %d:\texp: %v", testID, exp)
+				t.Fatalf("\t%s\tTest %d:\tShould report the drops once.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould report the drops once.", success, testID)
+		}
+	}
+}
+
+// blockingExporter holds every export until the channel is closed.
+type blockingExporter chan struct{}
+
+func (b blockingExporter) Export(ctx context.Context, spans []tracer.SpanData) error {
+	<-b
+	return nil
+}
@@ -0,0 +1,200 @@
+package web
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// textMarshaler is used to find values with their own text form, such as
+// time.Time.
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// CSV is the Encoder for the text/csv media type. The value must be a struct
+// or a slice of structs. Each struct is written as a record below a header
+// of the json names of the fields. The fields of nested structs are named
+// parent.child and slices of simple values are joined with a semicolon.
+// Cells a spreadsheet would run as a formula are prefixed with a quote.
+func CSV(w io.Writer, data interface{}) error {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	var rows []reflect.Value
+	t := v.Type()
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		t = t.Elem()
+		for i := 0; i < v.Len(); i++ {
+			rows = append(rows, v.Index(i))
+		}
+	default:
+		rows = append(rows, v)
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return errors.Errorf("csv: unsupported type %s", v.Type())
+	}
+
+	columns := csvColumns(t, "", nil)
+
+	cw := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, c := range columns {
+			cell, err := csvCell(fieldByIndex(row, c.index))
+			if err != nil {
+				return errors.Wrapf(err, "csv: field %s", c.name)
+			}
+			record[i] = escapeFormula(cell)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvColumns flattens the fields of the struct type into columns.
+func csvColumns(t reflect.Type, prefix string, index []int) []field {
+	var columns []field
+	for _, f := range fields(t) {
+		f.name = prefix + f.name
+		f.index = append(append([]int{}, index...), f.index...)
+
+		ft := t.FieldByIndex(f.index[len(index):]).Type
+		if ft.Kind() == reflect.Struct && !ft.Implements(textMarshaler) {
+			columns = append(columns, csvColumns(ft, f.name+".", f.index)...)
+			continue
+		}
+		columns = append(columns, f)
+	}
+
+	return columns
+}
+
+// fieldByIndex returns the nested field of the value. An invalid value is
+// returned when a pointer along the way is nil.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// csvCell formats the value of a field as the text of a cell.
+func csvCell(v reflect.Value) (string, error) {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", nil
+	}
+
+	if v.Type().Implements(textMarshaler) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Slice, reflect.Array:
+		if simple(v.Type().Elem()) {
+			cells := make([]string, v.Len())
+			for i := range cells {
+				cell, err := csvCell(v.Index(i))
+				if err != nil {
+					return "", err
+				}
+				cells[i] = cell
+			}
+			return strings.Join(cells, ";"), nil
+		}
+	}
+
+	// Anything else is written in its JSON form.
+	b, err := json.Marshal(v.Interface())
+	return string(b), err
+}
+
+// escapeFormula prefixes a cell starting like a formula with a quote so
+// spreadsheets show the text instead of running it. Numbers, such as a
+// negative latitude, are left alone since they can't run anything.
+// https://owasp.org/www-community/attacks/CSV_Injection
+func escapeFormula(cell string) string {
+	if cell == "" {
+		return cell
+	}
+
+	switch cell[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		if _, err := strconv.ParseFloat(cell, 64); err == nil {
+			return cell
+		}
+		return "'" + cell
+	}
+
+	return cell
+}
+
+// simple reports whether values of the type fit in a cell as text.
+func simple(t reflect.Type) bool {
+	if t.Implements(textMarshaler) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
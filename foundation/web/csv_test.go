@@ -0,0 +1,51 @@
+package web_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dgraph-io/travel/foundation/web"
+)
+
+// TestCSVFormula validates cells a spreadsheet would run as a formula are
+// written as text.
+func TestCSVFormula(t *testing.T) {
+	type row struct {
+		Name string  `json:"name"`
+		Lat  float64 `json:"lat"`
+	}
+
+	tt := []struct {
+		name string
+		row  row
+		exp  string
+	}{
+		{"plain", row{"Bar", 1.5}, "name,lat\nBar,1.5\n"},
+		{"equals", row{"=HYPERLINK(\"http://evil\")", 1}, "name,lat\n\"'=HYPERLINK(\"\"http://evil\"\")\",1\n"},
+		{"plus", row{"+1+1", 1}, "name,lat\n'+1+1,1\n"},
+		{"minus", row{"-1+1", 1}, "name,lat\n'-1+1,1\n"},
+		{"at", row{"@SUM(A1)", 1}, "name,lat\n'@SUM(A1),1\n"},
+		{"tab", row{"\t=1", 1}, "name,lat\n'\t=1,1\n"},
+		{"negative number", row{"-12.5", -33.8688}, "name,lat\n-12.5,-33.8688\n"},
+	}
+
+	t.Log("Given the need to write CSV that is safe to open in a spreadsheet.")
+	{
+		for testID, tst := range tt {
+			t.Logf("\tTest %d:\tWhen writing a %s cell.", testID, tst.name)
+			{
+				var buf bytes.Buffer
+				if err := web.CSV(&buf, []row{tst.row}); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to write the CSV: %v", failed, testID, err)
+				}
+
+				if got := buf.String(); got != tst.exp {
+					t.Logf("\t\tTest %d:\tgot: %q", testID, got)
+					t.Logf("\t\tTest %d:\texp: %q", testID, tst.exp)
+					t.Fatalf("\t%s\tTest %d:\tShould escape formula cells.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould escape formula cells.", success, testID)
+			}
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An Encoder writes a Go value to the response body in the format of the
+// media type it is registered for.
+type Encoder func(w io.Writer, data interface{}) error
+
+// mediaEncoder is an encoder registered for a media type.
+type mediaEncoder struct {
+	contentType string
+	mediaType   string
+	encode      Encoder
+}
+
+// jsonEncoder is used when the response can't be negotiated.
+var jsonEncoder = mediaEncoder{
+	contentType: "application/json",
+	mediaType:   "application/json",
+	encode:      JSON,
+}
+
+// JSON is the Encoder for the application/json media type.
+func JSON(w io.Writer, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// RegisterEncoder sets the encoder used for responses when the client
+// accepts the specified media type. The media type is used as the content
+// type of the response so it may include parameters, such as
+// text/csv; charset=utf-8. JSON is registered by NewApp and is used when
+// the client accepts any type or none of the registered types. Register the
+// encoders before the app starts serving.
+func (a *App) RegisterEncoder(mediaType string, enc Encoder) {
+	base, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		base = mediaType
+	}
+
+	e := mediaEncoder{
+		contentType: mediaType,
+		mediaType:   strings.ToLower(base),
+		encode:      enc,
+	}
+
+	for i := range a.encoders {
+		if a.encoders[i].mediaType == e.mediaType {
+			a.encoders[i] = e
+			return
+		}
+	}
+	a.encoders = append(a.encoders, e)
+}
+
+// =============================================================================
+
+// negotiation holds what Respond needs to pick the encoding of the response
+// to a request.
+type negotiation struct {
+	encoders       []mediaEncoder
+	accept         string
+	acceptEncoding string
+}
+
+// encoder returns the registered encoder the client prefers. The first
+// registered encoder is used when the client doesn't state a preference
+// or doesn't accept any of the registered types.
+func (n *negotiation) encoder() mediaEncoder {
+	if n == nil || len(n.encoders) == 0 {
+		return jsonEncoder
+	}
+
+	accepted := parseAccept(n.accept)
+
+	// Types the client explicitly refuses can't be picked by a wildcard.
+	refused := make(map[string]bool)
+	for _, a := range accepted {
+		if a.q == 0 {
+			refused[a.value] = true
+		}
+	}
+
+	for _, a := range accepted {
+		if a.q == 0 {
+			continue
+		}
+		for _, e := range n.encoders {
+			if refused[e.mediaType] {
+				continue
+			}
+			if matchMediaType(a.value, e.mediaType) {
+				return e
+			}
+		}
+	}
+
+	return n.encoders[0]
+}
+
+// coding returns the content coding the client prefers for a body of the
+// specified size. An empty coding means the body is sent as is.
+func (n *negotiation) coding(size int) string {
+	if n == nil || size < compressMinBytes {
+		return ""
+	}
+
+	for _, a := range parseAccept(n.acceptEncoding) {
+		if a.q == 0 {
+			continue
+		}
+		switch a.value {
+		case "gzip", "*":
+			return "gzip"
+		case "deflate":
+			return "deflate"
+		}
+	}
+
+	return ""
+}
+
+// matchMediaType reports whether the accepted media range, such as text/*,
+// includes the media type.
+func matchMediaType(mediaRange string, mediaType string) bool {
+	switch {
+	case mediaRange == "*/*":
+		return true
+	case strings.HasSuffix(mediaRange, "/*"):
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return mediaRange == mediaType
+}
+
+// accepted is a value of an Accept or Accept-Encoding header with its
+// quality.
+type accepted struct {
+	value string
+	q     float64
+}
+
+// parseAccept returns the values of the header ordered by preference. Values
+// with the same quality keep their order except that more specific media
+// ranges are preferred.
+func parseAccept(header string) []accepted {
+	var values []accepted
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, p := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+			if len(kv) == 2 && strings.EqualFold(kv[0], "q") {
+				if f, err := strconv.ParseFloat(kv[1], 64); err == nil && f >= 0 && f <= 1 {
+					q = f
+				}
+			}
+		}
+
+		values = append(values, accepted{value: value, q: q})
+	}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		if values[i].q != values[j].q {
+			return values[i].q > values[j].q
+		}
+		return specificity(values[i].value) > specificity(values[j].value)
+	})
+
+	return values
+}
+
+// specificity ranks media ranges so a type is preferred over a wildcard.
+func specificity(mediaRange string) int {
+	switch {
+	case mediaRange == "*/*" || mediaRange == "*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*"):
+		return 1
+	}
+	return 2
+}
+
+// =============================================================================
+
+// field describes a struct field that is encoded.
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// fields returns the fields of the struct type that are encoded, named by
+// their json tags like encoding/json does. The fields of embedded structs
+// without a tag are promoted.
+func fields(t reflect.Type) []field {
+	var fs []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx != -1 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			for _, f := range fields(sf.Type) {
+				f.index = append([]int{i}, f.index...)
+				fs = append(fs, f)
+			}
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fs = append(fs, field{
+			name:      name,
+			index:     sf.Index,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+
+	return fs
+}
+
+// isEmpty reports whether the value is empty as defined by the omitempty
+// option of encoding/json.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
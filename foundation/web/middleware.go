@@ -1,5 +1,15 @@
 package web
 
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/dgraph-io/travel/foundation/tracer"
+)
+
 // Middleware is a function designed to run some code before and/or after
 // another Handler. It is designed to remove boilerplate or other concerns not
 // direct to any given Handler.
@@ -16,9 +26,42 @@ func wrapMiddleware(mw []Middleware, handler Handler) Handler {
 	for i := len(mw) - 1; i >= 0; i-- {
 		h := mw[i]
 		if h != nil {
-			handler = h(handler)
+			handler = traced(funcName(h), h(handler))
 		}
 	}
 
 	return handler
 }
+
+// traced wraps a handler with a span covering its execution.
+func traced(name string, handler Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		ctx, span := tracer.Start(ctx, name)
+		defer span.End()
+
+		err := handler(ctx, w, r)
+		span.RecordError(err)
+
+		return err
+	}
+}
+
+// funcName returns a short name for a function to name its span, such as
+// mid.Logger or handlers.userGroup.token.
+func funcName(f interface{}) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	if i := strings.Index(name, ".func"); i >= 0 {
+		name = name[:i]
+	}
+
+	return name
+}
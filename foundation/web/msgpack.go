@@ -0,0 +1,287 @@
+package web
+
+import (
+	"bufio"
+	"encoding"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// MessagePack is the Encoder for the application/msgpack media type. Values
+// are encoded the way encoding/json would encode them: structs become maps
+// keyed by the json names of their fields and values with a text form, such
+// as time.Time, become strings.
+func MessagePack(w io.Writer, data interface{}) error {
+	bw := bufio.NewWriter(w)
+
+	e := msgpackEncoder{w: bw}
+	if err := e.encode(reflect.ValueOf(data)); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// msgpackEncoder writes values in the MessagePack format described at
+// https://github.com/msgpack/msgpack/blob/master/spec.md.
+type msgpackEncoder struct {
+	w   *bufio.Writer
+	buf [9]byte
+}
+
+// encode writes the value.
+func (e *msgpackEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		return e.w.WriteByte(0xc0)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return e.w.WriteByte(0xc0)
+		}
+	}
+
+	if v.Type().Implements(textMarshaler) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		return e.encodeString(string(b))
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return e.encode(v.Elem())
+
+	case reflect.Bool:
+		if v.Bool() {
+			return e.w.WriteByte(0xc3)
+		}
+		return e.w.WriteByte(0xc2)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return e.encodeInt(v.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return e.encodeUint(v.Uint())
+
+	case reflect.Float32:
+		e.buf[0] = 0xca
+		putUint(e.buf[1:5], uint64(math.Float32bits(float32(v.Float()))))
+		return e.write(5)
+
+	case reflect.Float64:
+		e.buf[0] = 0xcb
+		putUint(e.buf[1:9], math.Float64bits(v.Float()))
+		return e.write(9)
+
+	case reflect.String:
+		return e.encodeString(v.String())
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return e.w.WriteByte(0xc0)
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return e.encodeBinary(v.Bytes())
+		}
+		return e.encodeArray(v)
+
+	case reflect.Array:
+		return e.encodeArray(v)
+
+	case reflect.Map:
+		if v.IsNil() {
+			return e.w.WriteByte(0xc0)
+		}
+		return e.encodeMap(v)
+
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	}
+
+	return errors.Errorf("msgpack: unsupported type %s", v.Type())
+}
+
+// encodeInt writes the integer in the smallest format that holds it.
+func (e *msgpackEncoder) encodeInt(i int64) error {
+	switch {
+	case i >= 0:
+		return e.encodeUint(uint64(i))
+	case i >= -32:
+		return e.w.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		return e.writeHeader(0xd0, uint64(uint8(i)), 1)
+	case i >= math.MinInt16:
+		return e.writeHeader(0xd1, uint64(uint16(i)), 2)
+	case i >= math.MinInt32:
+		return e.writeHeader(0xd2, uint64(uint32(i)), 4)
+	}
+	return e.writeHeader(0xd3, uint64(i), 8)
+}
+
+// encodeUint writes the unsigned integer in the smallest format that holds
+// it.
+func (e *msgpackEncoder) encodeUint(u uint64) error {
+	switch {
+	case u <= math.MaxInt8:
+		return e.w.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		return e.writeHeader(0xcc, u, 1)
+	case u <= math.MaxUint16:
+		return e.writeHeader(0xcd, u, 2)
+	case u <= math.MaxUint32:
+		return e.writeHeader(0xce, u, 4)
+	}
+	return e.writeHeader(0xcf, u, 8)
+}
+
+// encodeString writes the string as a str.
+func (e *msgpackEncoder) encodeString(s string) error {
+	n := len(s)
+	var err error
+	switch {
+	case n < 32:
+		err = e.w.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		err = e.writeHeader(0xd9, uint64(n), 1)
+	case n <= math.MaxUint16:
+		err = e.writeHeader(0xda, uint64(n), 2)
+	default:
+		err = e.writeHeader(0xdb, uint64(n), 4)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.WriteString(s)
+	return err
+}
+
+// encodeBinary writes the bytes as a bin.
+func (e *msgpackEncoder) encodeBinary(b []byte) error {
+	n := len(b)
+	var err error
+	switch {
+	case n <= math.MaxUint8:
+		err = e.writeHeader(0xc4, uint64(n), 1)
+	case n <= math.MaxUint16:
+		err = e.writeHeader(0xc5, uint64(n), 2)
+	default:
+		err = e.writeHeader(0xc6, uint64(n), 4)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(b)
+	return err
+}
+
+// encodeArray writes the elements of the slice or array as an array.
+func (e *msgpackEncoder) encodeArray(v reflect.Value) error {
+	if err := e.encodeLen(0x90, 0xdc, v.Len()); err != nil {
+		return err
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeMap writes the map. String keys are sorted so the output is stable
+// like encoding/json.
+func (e *msgpackEncoder) encodeMap(v reflect.Value) error {
+	if err := e.encodeLen(0x80, 0xde, v.Len()); err != nil {
+		return err
+	}
+
+	keys := v.MapKeys()
+	if v.Type().Key().Kind() == reflect.String {
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	}
+
+	for _, k := range keys {
+		if err := e.encode(k); err != nil {
+			return err
+		}
+		if err := e.encode(v.MapIndex(k)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeStruct writes the struct as a map keyed by the json names of its
+// fields.
+func (e *msgpackEncoder) encodeStruct(v reflect.Value) error {
+	var values []reflect.Value
+	var names []string
+	for _, f := range fields(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		if f.omitEmpty && isEmpty(fv) {
+			continue
+		}
+		values = append(values, fv)
+		names = append(names, f.name)
+	}
+
+	if err := e.encodeLen(0x80, 0xde, len(values)); err != nil {
+		return err
+	}
+
+	for i := range values {
+		if err := e.encodeString(names[i]); err != nil {
+			return err
+		}
+		if err := e.encode(values[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeLen writes the header of an array or map. Small lengths use the
+// fix format and larger ones the 16 or 32 bit format following it.
+func (e *msgpackEncoder) encodeLen(fix byte, code byte, n int) error {
+	switch {
+	case n < 16:
+		return e.w.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		return e.writeHeader(code, uint64(n), 2)
+	}
+	return e.writeHeader(code+1, uint64(n), 4)
+}
+
+// writeHeader writes the format code followed by the big endian value of the
+// specified size.
+func (e *msgpackEncoder) writeHeader(code byte, u uint64, size int) error {
+	e.buf[0] = code
+	putUint(e.buf[1:1+size], u)
+	return e.write(1 + size)
+}
+
+// write writes the first n bytes of the buffer.
+func (e *msgpackEncoder) write(n int) error {
+	_, err := e.w.Write(e.buf[:n])
+	return err
+}
+
+// putUint stores the value big endian in all the bytes of b.
+func putUint(b []byte, u uint64) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(u)
+		u >>= 8
+	}
+}
@@ -2,27 +2,147 @@ package web
 
 import (
 	"encoding/json"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/dimfeld/httptreemux/v5"
+	"github.com/pkg/errors"
 )
 
+// DefaultMaxBodyBytes is the largest request body an App accepts unless
+// configured otherwise.
+const DefaultMaxBodyBytes = 1 << 20
+
+// errBodyTooLarge is the message of the error http.MaxBytesReader returns
+// once the limit is exceeded.
+const errBodyTooLarge = "http: request body too large"
+
+// Validator is implemented by request models that can check their own
+// fields. Decode calls Validate once the body is decoded.
+type Validator interface {
+	Validate() error
+}
+
+// DecodeError is returned by Decode when the request body can't be decoded.
+// The status is the HTTP status code the client should receive.
+type DecodeError struct {
+	Err    error
+	Status int
+}
+
+// Error implements the error interface.
+func (err *DecodeError) Error() string {
+	return err.Err.Error()
+}
+
+// newDecodeError constructs a DecodeError with a formatted message.
+func newDecodeError(status int, format string, args ...interface{}) error {
+	return &DecodeError{
+		Err:    errors.Errorf(format, args...),
+		Status: status,
+	}
+}
+
 // Param returns the web call parameters from the request.
 func Param(r *http.Request, key string) string {
 	m := httptreemux.ContextParams(r.Context())
 	return m[key]
 }
 
+// Route returns the pattern of the route handling the request, such as
+// /v1/users/:id.
+func Route(r *http.Request) string {
+	return httptreemux.ContextRoute(r.Context())
+}
+
 // Decode reads the body of an HTTP request looking for a JSON document. The
 // body is decoded into the provided value.
 //
-// If the provided value is a struct then it is checked for validation tags.
+// The request must have a JSON content type and the body must hold a single
+// document without unknown fields. The size of the body is limited by the
+// App. These failures are reported with a *DecodeError. If the provided
+// value implements Validator then it is validated and the error of Validate
+// is returned as is.
 func Decode(r *http.Request, val interface{}) error {
+	if err := checkContentType(r); err != nil {
+		return err
+	}
+
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(val); err != nil {
-		return err
+		return decodeError(err)
+	}
+
+	// Anything after the document is most likely a mistake of the client.
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		if err != nil && err.Error() == errBodyTooLarge {
+			return decodeError(err)
+		}
+		return newDecodeError(http.StatusBadRequest, "body must only contain a single JSON document")
+	}
+
+	if v, ok := val.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkContentType makes sure the request body is declared as JSON. Types
+// using the +json suffix, such as application/merge-patch+json, are
+// accepted.
+func checkContentType(r *http.Request) error {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return newDecodeError(http.StatusUnsupportedMediaType, "content type must be application/json")
+	}
+
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return newDecodeError(http.StatusUnsupportedMediaType, "invalid content type %q", ct)
+	}
+
+	if mt != "application/json" && !strings.HasSuffix(mt, "+json") {
+		return newDecodeError(http.StatusUnsupportedMediaType, "content type %q is not supported: use application/json", mt)
 	}
 
 	return nil
 }
+
+// decodeError converts the error of the JSON decoder into a DecodeError
+// with a message that is safe to show to the client.
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case err == io.EOF:
+		return newDecodeError(http.StatusBadRequest, "body must not be empty")
+
+	case err == io.ErrUnexpectedEOF:
+		return newDecodeError(http.StatusBadRequest, "body contains malformed JSON")
+
+	case errors.As(err, &syntaxErr):
+		return newDecodeError(http.StatusBadRequest, "body contains malformed JSON at offset %d", syntaxErr.Offset)
+
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return newDecodeError(http.StatusBadRequest, "body contains an invalid value for the field %q", typeErr.Field)
+		}
+		return newDecodeError(http.StatusBadRequest, "body contains an invalid value at offset %d", typeErr.Offset)
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return newDecodeError(http.StatusBadRequest, "body contains the unknown field %s", field)
+
+	case err.Error() == errBodyTooLarge:
+		return newDecodeError(http.StatusRequestEntityTooLarge, "body is too large")
+	}
+
+	return errors.Wrap(err, "reading body")
+}
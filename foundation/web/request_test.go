@@ -0,0 +1,102 @@
+package web_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/travel/foundation/web"
+	"github.com/pkg/errors"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// errNoName is returned by the test model when the name is missing.
+var errNoName = errors.New("name is required")
+
+// person is the model decoded by the tests.
+type person struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// Validate implements the web.Validator interface.
+func (p person) Validate() error {
+	if p.Name == "" {
+		return errNoName
+	}
+	return nil
+}
+
+// TestDecode validates request bodies are limited, typed and validated.
+func TestDecode(t *testing.T) {
+	tt := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		err         error
+	}{
+		{"valid", "application/json", `{"name": "Bill", "age": 40}`, http.StatusOK, nil},
+		{"charset", "application/json; charset=utf-8", `{"name": "Bill"}`, http.StatusOK, nil},
+		{"suffix", "application/merge-patch+json", `{"name": "Bill"}`, http.StatusOK, nil},
+		{"no content type", "", `{"name": "Bill"}`, http.StatusUnsupportedMediaType, nil},
+		{"wrong content type", "text/plain", `{"name": "Bill"}`, http.StatusUnsupportedMediaType, nil},
+		{"empty", "application/json", ``, http.StatusBadRequest, nil},
+		{"malformed", "application/json", `{"name": "Bill"`, http.StatusBadRequest, nil},
+		{"syntax", "application/json", `{"name" "Bill"}`, http.StatusBadRequest, nil},
+		{"type", "application/json", `{"name": "Bill", "age": "forty"}`, http.StatusBadRequest, nil},
+		{"unknown field", "application/json", `{"name": "Bill", "email": "bill@ardanlabs.com"}`, http.StatusBadRequest, nil},
+		{"two documents", "application/json", `{"name": "Bill"} {"name": "Jill"}`, http.StatusBadRequest, nil},
+		{"too large", "application/json", `{"name": "` + strings.Repeat("a", 128) + `"}`, http.StatusRequestEntityTooLarge, nil},
+		{"invalid", "application/json", `{"age": 40}`, http.StatusOK, errNoName},
+	}
+
+	t.Log("Given the need to decode request bodies.")
+	{
+		for testID, test := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen decoding a %s body.", testID, test.name)
+				{
+					var got error
+					h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+						var p person
+						got = web.Decode(r, &p)
+						return nil
+					}
+
+					app := web.NewApp(nil, nil)
+					app.SetMaxBodyBytes(64)
+					app.Handle(http.MethodPost, "/people", h)
+
+					r := httptest.NewRequest(http.MethodPost, "/people", strings.NewReader(test.body))
+					if test.contentType != "" {
+						r.Header.Set("Content-Type", test.contentType)
+					}
+					app.ServeHTTP(httptest.NewRecorder(), r)
+
+					status := http.StatusOK
+					if de, ok := errors.Cause(got).(*web.DecodeError); ok {
+						status = de.Status
+					}
+					if status != test.status {
+						t.Fatalf("\t%s\tTest %d:\tShould report a %d status : got %d : %v", failed, testID, test.status, status, got)
+					}
+					t.Logf("\t%s\tTest %d:\tShould report a %d status.", success, testID, test.status)
+
+					if test.status == http.StatusOK && errors.Cause(got) != test.err {
+						t.Fatalf("\t%s\tTest %d:\tShould return the validation error : got %v", failed, testID, got)
+					}
+					t.Logf("\t%s\tTest %d:\tShould return the validation error.", success, testID)
+				}
+			}
+			t.Run(test.name, tf)
+		}
+	}
+}
@@ -1,12 +1,22 @@
 package web
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
-	"encoding/json"
+	"io"
 	"net/http"
 )
 
-// Respond converts a Go value to JSON and sends it to the client.
+// compressMinBytes is the smallest response body worth compressing. Smaller
+// bodies fit in a single packet anyway.
+const compressMinBytes = 1024
+
+// Respond encodes a Go value and sends it to the client. The encoder is
+// picked from the ones registered on the App by the Accept header of the
+// request, falling back to JSON. The body is compressed with gzip or
+// deflate when the Accept-Encoding header allows it.
 func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statusCode int) error {
 
 	// Set the status code for the request logger middleware.
@@ -22,22 +32,67 @@ func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statu
 		return nil
 	}
 
-	// Convert the response value to JSON.
-	jsonData, err := json.Marshal(data)
-	if err != nil {
+	// Encode the response value in the format the client prefers.
+	n, _ := ctx.Value(keyNegotiation).(*negotiation)
+	enc := n.encoder()
+
+	var body bytes.Buffer
+	if err := enc.encode(&body, data); err != nil {
 		return err
 	}
 
-	// Set the content type and headers once we know marshaling has succeeded.
-	w.Header().Set("Content-Type", "application/json")
+	// Set the content type and headers once we know encoding has succeeded.
+	h := w.Header()
+	h.Set("Content-Type", enc.contentType)
+
+	b := body.Bytes()
+	if n != nil {
+
+		// The response depends on these headers so caches must keep a
+		// copy for each of their values.
+		h.Add("Vary", "Accept")
+		h.Add("Vary", "Accept-Encoding")
+
+		if coding := n.coding(len(b)); coding != "" && h.Get("Content-Encoding") == "" {
+			compressed, err := compress(coding, b)
+			if err != nil {
+				return err
+			}
+			h.Set("Content-Encoding", coding)
+			b = compressed
+		}
+	}
 
 	// Write the status code to the response.
 	w.WriteHeader(statusCode)
 
 	// Send the result back to the client.
-	if _, err := w.Write(jsonData); err != nil {
+	if _, err := w.Write(b); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// compress returns the data compressed with the content coding. The deflate
+// coding of HTTP is the zlib format.
+func compress(coding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var zw io.WriteCloser
+	switch coding {
+	case "gzip":
+		zw = gzip.NewWriter(&buf)
+	default:
+		zw = zlib.NewWriter(&buf)
+	}
+
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
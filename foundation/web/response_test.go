@@ -0,0 +1,185 @@
+package web_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/web"
+)
+
+// city is used to test the encoding of nested structs.
+type city struct {
+	ID string `json:"id"`
+}
+
+// place is the model encoded by the tests.
+type place struct {
+	Name     string    `json:"name"`
+	City     city      `json:"city"`
+	Stars    float64   `json:"stars"`
+	Types    []string  `json:"types"`
+	Visited  time.Time `json:"visited"`
+	Internal string    `json:"-"`
+}
+
+// TestRespond validates responses are encoded the way the client prefers.
+func TestRespond(t *testing.T) {
+	visited := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	places := []place{
+		{Name: "Bar, Inc", City: city{ID: "0x1"}, Stars: 4.5, Types: []string{"bar", "food"}, Visited: visited, Internal: "secret"},
+	}
+
+	tt := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"default", "", "application/json", `[{"name":"Bar, Inc","city":{"id":"0x1"},"stars":4.5,"types":["bar","food"],"visited":"2026-10-19T00:00:00Z"}]`},
+		{"any", "*/*", "application/json", `[{"name":"Bar, Inc","city":{"id":"0x1"},"stars":4.5,"types":["bar","food"],"visited":"2026-10-19T00:00:00Z"}]`},
+		{"unknown", "application/xml", "application/json", `[{"name":"Bar, Inc","city":{"id":"0x1"},"stars":4.5,"types":["bar","food"],"visited":"2026-10-19T00:00:00Z"}]`},
+		{"csv", "text/csv", "text/csv; charset=utf-8", "name,city.id,stars,types,visited\n\"Bar, Inc\",0x1,4.5,bar;food,2026-10-19T00:00:00Z\n"},
+		{"quality", "application/json;q=0.5, text/*", "text/csv; charset=utf-8", "name,city.id,stars,types,visited\n\"Bar, Inc\",0x1,4.5,bar;food,2026-10-19T00:00:00Z\n"},
+		{"refused", "application/json;q=0, */*", "text/csv; charset=utf-8", "name,city.id,stars,types,visited\n\"Bar, Inc\",0x1,4.5,bar;food,2026-10-19T00:00:00Z\n"},
+	}
+
+	t.Log("Given the need to negotiate the format of responses.")
+	{
+		for testID, test := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen accepting %q.", testID, test.accept)
+				{
+					h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+						return web.Respond(ctx, w, places, http.StatusOK)
+					}
+
+					app := web.NewApp(nil, nil)
+					app.RegisterEncoder("text/csv; charset=utf-8", web.CSV)
+					app.Handle(http.MethodGet, "/places", h)
+
+					r := httptest.NewRequest(http.MethodGet, "/places", nil)
+					if test.accept != "" {
+						r.Header.Set("Accept", test.accept)
+					}
+					w := httptest.NewRecorder()
+					app.ServeHTTP(w, r)
+
+					if got := w.Header().Get("Content-Type"); got != test.contentType {
+						t.Fatalf("\t%s\tTest %d:\tShould respond with %s : got %s", failed, testID, test.contentType, got)
+					}
+					t.Logf("\t%s\tTest %d:\tShould respond with %s.", success, testID, test.contentType)
+
+					if got := w.Body.String(); got != test.body {
+						t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+						t.Logf("\t\tTest %d:\texp: %v", testID, test.body)
+						t.Fatalf("\t%s\tTest %d:\tShould encode the body.", failed, testID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould encode the body.", success, testID)
+				}
+			}
+			t.Run(test.name, tf)
+		}
+	}
+}
+
+// TestCompress validates large responses are compressed when the client
+// accepts it.
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("travel", 1000)
+
+	h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, body, http.StatusOK)
+	}
+
+	app := web.NewApp(nil, nil)
+	app.Handle(http.MethodGet, "/travel", h)
+
+	t.Log("Given the need to compress responses.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen accepting gzip.", testID)
+		{
+			r := httptest.NewRequest(http.MethodGet, "/travel", nil)
+			r.Header.Set("Accept-Encoding", "deflate;q=0.5, gzip")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+				t.Fatalf("\t%s\tTest %d:\tShould compress the body with gzip : got %q", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould compress the body with gzip.", success, testID)
+
+			zr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read the body : %v", failed, testID, err)
+			}
+			got, err := io.ReadAll(zr)
+			if err != nil || string(got) != `"`+body+`"` {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read the body : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to read the body.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen not accepting any coding.", testID)
+		{
+			r := httptest.NewRequest(http.MethodGet, "/travel", nil)
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould not compress the body : got %q", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not compress the body.", success, testID)
+		}
+	}
+}
+
+// TestMessagePack validates values are encoded in the MessagePack format.
+func TestMessagePack(t *testing.T) {
+	tt := []struct {
+		name string
+		data interface{}
+		exp  []byte
+	}{
+		{"nil", nil, []byte{0xc0}},
+		{"fixint", 7, []byte{0x07}},
+		{"negative", -33, []byte{0xd0, 0xdf}},
+		{"uint16", 300, []byte{0xcd, 0x01, 0x2c}},
+		{"float", 4.5, []byte{0xcb, 0x40, 0x12, 0, 0, 0, 0, 0, 0}},
+		{"string", "bar", []byte{0xa3, 'b', 'a', 'r'}},
+		{"array", []bool{true, false}, []byte{0x92, 0xc3, 0xc2}},
+		{"struct", city{ID: "0x1"}, []byte{0x81, 0xa2, 'i', 'd', 0xa3, '0', 'x', '1'}},
+		{"map", map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+	}
+
+	t.Log("Given the need to encode values as MessagePack.")
+	{
+		for testID, test := range tt {
+			tf := func(t *testing.T) {
+				t.Logf("\tTest %d:\tWhen encoding a %s.", testID, test.name)
+				{
+					var buf bytes.Buffer
+					if err := web.MessagePack(&buf, test.data); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to encode the value : %v", failed, testID, err)
+					}
+
+					if got := buf.Bytes(); !bytes.Equal(got, test.exp) {
+						t.Logf("\t\tTest %d:\tgot: % x", testID, got)
+						t.Logf("\t\tTest %d:\texp: % x", testID, test.exp)
+						t.Fatalf("\t%s\tTest %d:\tShould encode the value.", failed, testID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould encode the value.", success, testID)
+				}
+			}
+			t.Run(test.name, tf)
+		}
+	}
+}
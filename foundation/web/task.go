@@ -0,0 +1,139 @@
+package web
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/tracer"
+	"github.com/pkg/errors"
+)
+
+// ErrShuttingDown is returned when work is refused because the app is
+// shutting down.
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// Task describes background work started by a handler.
+type Task struct {
+	Name    string
+	TraceID string
+	Started time.Time
+}
+
+// task is a running task with the function cancelling its context.
+type task struct {
+	Task
+	cancel context.CancelFunc
+}
+
+// tasks tracks the background work of the app so shutdown can wait for it.
+type tasks struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	running  map[int]*task
+	nextID   int
+	draining bool
+}
+
+// Go runs the function in its own goroutine and tracks it until it returns.
+// The function receives a context derived from the one provided, which is
+// cancelled when the task is abandoned during shutdown. Since the task
+// outlives the request, don't pass the context of the request; use
+// tracer.ContextWithSpan to continue its trace instead. ErrShuttingDown is
+// returned once shutdown has started.
+func (a *App) Go(ctx context.Context, name string, fn func(ctx context.Context)) error {
+	t := task{
+		Task: Task{
+			Name:    name,
+			Started: time.Now(),
+		},
+	}
+	if sc := tracer.SpanFromContext(ctx).SpanContext(); sc.TraceID != (tracer.TraceID{}) {
+		t.TraceID = sc.TraceID.String()
+	}
+	ctx, t.cancel = context.WithCancel(ctx)
+
+	a.tasks.mu.Lock()
+	if a.tasks.draining {
+		a.tasks.mu.Unlock()
+		t.cancel()
+		return ErrShuttingDown
+	}
+	if a.tasks.running == nil {
+		a.tasks.running = make(map[int]*task)
+	}
+	id := a.tasks.nextID
+	a.tasks.nextID++
+	a.tasks.running[id] = &t
+	a.tasks.wg.Add(1)
+	a.tasks.mu.Unlock()
+
+	go func() {
+		defer func() {
+			t.cancel()
+
+			a.tasks.mu.Lock()
+			delete(a.tasks.running, id)
+			a.tasks.mu.Unlock()
+
+			a.tasks.wg.Done()
+		}()
+
+		fn(ctx)
+	}()
+
+	return nil
+}
+
+// Shutdown refuses new requests and tasks and waits for the running tasks to
+// return. Once the context is done the remaining tasks are cancelled and
+// returned as abandoned, oldest first. Shutdown doesn't wait for the
+// cancelled tasks to return.
+func (a *App) Shutdown(ctx context.Context) []Task {
+	a.Drain()
+
+	done := make(chan struct{})
+	go func() {
+		a.tasks.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	a.tasks.mu.Lock()
+	defer a.tasks.mu.Unlock()
+
+	abandoned := make([]Task, 0, len(a.tasks.running))
+	for _, t := range a.tasks.running {
+		t.cancel()
+		abandoned = append(abandoned, t.Task)
+	}
+	sort.Slice(abandoned, func(i, j int) bool {
+		return abandoned[i].Started.Before(abandoned[j].Started)
+	})
+
+	return abandoned
+}
+
+// Drain refuses new requests and tasks from now on. Call it as soon as
+// shutdown starts so the requests still running while the server shuts down
+// can't start new tasks.
+func (a *App) Drain() {
+	a.tasks.mu.Lock()
+	defer a.tasks.mu.Unlock()
+
+	a.tasks.draining = true
+}
+
+// draining reports whether the app is shutting down.
+func (a *App) draining() bool {
+	a.tasks.mu.Lock()
+	defer a.tasks.mu.Unlock()
+
+	return a.tasks.draining
+}
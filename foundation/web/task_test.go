@@ -0,0 +1,114 @@
+package web_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/travel/foundation/web"
+)
+
+// TestShutdown validates shutdown waits for tasks and refuses new work.
+func TestShutdown(t *testing.T) {
+	t.Log("Given the need to drain the work of the app on shutdown.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the tasks complete before the deadline.", testID)
+		{
+			app := web.NewApp(nil, nil)
+
+			done := make(chan struct{})
+			f := func(ctx context.Context) {
+				time.Sleep(50 * time.Millisecond)
+				close(done)
+			}
+			if err := app.Go(context.Background(), "sleep", f); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to start the task : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to start the task.", success, testID)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if abandoned := app.Shutdown(ctx); len(abandoned) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould not abandon any task : %v", failed, testID, abandoned)
+			}
+			select {
+			case <-done:
+			default:
+				t.Fatalf("\t%s\tTest %d:\tShould wait for the task to complete.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould wait for the task to complete.", success, testID)
+
+			if err := app.Go(context.Background(), "late", func(ctx context.Context) {}); err != web.ErrShuttingDown {
+				t.Fatalf("\t%s\tTest %d:\tShould refuse new tasks : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould refuse new tasks.", success, testID)
+
+			h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.Respond(ctx, w, nil, http.StatusNoContent)
+			}
+			app.Handle(http.MethodGet, "/test", h)
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+			if w.Code != http.StatusServiceUnavailable {
+				t.Fatalf("\t%s\tTest %d:\tShould refuse new requests : got %d", failed, testID, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould refuse new requests.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen a task is still running at the deadline.", testID)
+		{
+			app := web.NewApp(nil, nil)
+
+			cancelled := make(chan struct{})
+			f := func(ctx context.Context) {
+				<-ctx.Done()
+				close(cancelled)
+			}
+			if err := app.Go(context.Background(), "blocked", f); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to start the task : %v", failed, testID, err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			abandoned := app.Shutdown(ctx)
+			if len(abandoned) != 1 || abandoned[0].Name != "blocked" {
+				t.Fatalf("\t%s\tTest %d:\tShould report the abandoned task : %v", failed, testID, abandoned)
+			}
+			t.Logf("\t%s\tTest %d:\tShould report the abandoned task.", success, testID)
+
+			select {
+			case <-cancelled:
+			case <-time.After(time.Second):
+				t.Fatalf("\t%s\tTest %d:\tShould cancel the context of the task.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould cancel the context of the task.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen the app is drained before the server shuts down.", testID)
+		{
+			app := web.NewApp(nil, nil)
+			app.Drain()
+
+			if err := app.Go(context.Background(), "late", func(ctx context.Context) {}); err != web.ErrShuttingDown {
+				t.Fatalf("\t%s\tTest %d:\tShould refuse new tasks : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould refuse new tasks.", success, testID)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			if abandoned := app.Shutdown(ctx); len(abandoned) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould not abandon any task : %v", failed, testID, abandoned)
+			}
+			t.Logf("\t%s\tTest %d:\tShould shutdown without any task.", success, testID)
+		}
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/dgraph-io/travel/foundation/tracer"
 	"github.com/dimfeld/httptreemux/v5"
-	"github.com/google/uuid"
 )
 
 // ctxKey represents the type of value for the context key.
@@ -18,6 +20,10 @@ type ctxKey int
 // KeyValues is how request values are stored/retrieved.
 const KeyValues ctxKey = 1
 
+// keyNegotiation is how the encoding preferences of the request are
+// stored/retrieved.
+const keyNegotiation ctxKey = 2
+
 // Values represent state for each request.
 type Values struct {
 	TraceID    string
@@ -35,35 +41,72 @@ type Handler func(ctx context.Context, w http.ResponseWriter, r *http.Request) e
 type App struct {
 	*httptreemux.ContextMux
 	shutdown chan os.Signal
+	tracer   *tracer.Tracer
 	mw       []Middleware
+	maxBody  int64
+	encoders []mediaEncoder
+	tasks    tasks
 }
 
 // NewApp creates an App value that handle a set of routes for the application.
-func NewApp(shutdown chan os.Signal, mw ...Middleware) *App {
-
-	// Create an OpenTelemetry HTTP Handler which wraps our router. This will start
-	// the initial span and annotate it with information about the request/response.
-	//
-	// This is configured to use the W3C TraceContext standard to set the remote
-	// parent if an client request includes the appropriate headers.
-	// https://w3c.github.io/trace-context/
-
+// Every request is traced with the specified tracer. A nil tracer still
+// provides trace ids but spans are not exported.
+func NewApp(shutdown chan os.Signal, tracer *tracer.Tracer, mw ...Middleware) *App {
 	return &App{
 		ContextMux: httptreemux.NewContextMux(),
 		shutdown:   shutdown,
+		tracer:     tracer,
 		mw:         mw,
+		maxBody:    DefaultMaxBodyBytes,
+		encoders:   []mediaEncoder{jsonEncoder},
 	}
 }
 
+// SetMaxBodyBytes sets the largest request body the handlers can read. Reads
+// past the limit fail and Decode reports them with a 413 status. A limit of
+// zero or less removes the limit. Set it before the app starts serving.
+func (a *App) SetMaxBodyBytes(n int64) {
+	a.maxBody = n
+}
+
 // SignalShutdown is used to gracefully shutdown the app when an integrity
-// issue is identified.
+// issue is identified. New requests and tasks are refused from now on.
 func (a *App) SignalShutdown() {
-	a.shutdown <- syscall.SIGTERM
+	a.Drain()
+
+	// The signal only needs to be delivered once so don't block when
+	// shutdown has already been signaled.
+	select {
+	case a.shutdown <- syscall.SIGTERM:
+	default:
+	}
 }
 
 // Handle sets a handler function for a given HTTP method and path pair
 // to the application server mux.
 func (a *App) Handle(method string, path string, handler Handler, mw ...Middleware) {
+	a.ContextMux.Handle(method, path, a.handler(method, path, handler, mw))
+}
+
+// HandleOptions sets the handler for OPTIONS requests to any route without
+// its own OPTIONS handler, such as CORS preflight requests. The route of
+// these requests is reported as *.
+func (a *App) HandleOptions(handler Handler, mw ...Middleware) {
+	h := a.handler(http.MethodOptions, "*", handler, mw)
+	a.ContextMux.OptionsHandler = func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		ctx := httptreemux.AddRouteToContext(r.Context(), "*")
+		ctx = httptreemux.AddParamsToContext(ctx, params)
+		h(w, r.WithContext(ctx))
+	}
+}
+
+// handler wraps the handler with its middleware and the application's
+// general middleware into a function the mux can call.
+func (a *App) handler(method string, path string, handler Handler, mw []Middleware) http.HandlerFunc {
+
+	// Trace the handler on its own so its time can be told apart from
+	// the middleware.
+	handler = traced(funcName(handler), handler)
 
 	// First wrap handler specific middleware around this handler.
 	handler = wrapMiddleware(mw, handler)
@@ -74,20 +117,63 @@ func (a *App) Handle(method string, path string, handler Handler, mw ...Middlewa
 	// The function to execute for each request.
 	h := func(w http.ResponseWriter, r *http.Request) {
 
+		// Turn away requests arriving while the app is shutting down and
+		// ask the client to use a new connection.
+		if a.draining() {
+			w.Header().Set("Connection", "close")
+			resp := struct {
+				Error string `json:"error"`
+			}{
+				Error: ErrShuttingDown.Error(),
+			}
+			Respond(r.Context(), w, resp, http.StatusServiceUnavailable)
+			return
+		}
+
+		// Start the span for the request. This uses the W3C Trace Context
+		// standard to continue the trace of the client if the request
+		// includes the appropriate headers.
+		ctx := r.Context()
+		if sc, ok := tracer.Extract(r.Header); ok {
+			ctx = tracer.ContextWithRemote(ctx, sc)
+		}
+		ctx, span := a.tracer.Start(ctx, method+" "+path)
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+		defer span.End()
+
 		// Set the context with the required values to
 		// process the request.
 		v := Values{
-			TraceID: uuid.New().String(),
+			TraceID: span.SpanContext().TraceID.String(),
 			Now:     time.Now(),
 		}
-		ctx := context.WithValue(r.Context(), KeyValues, &v)
+		ctx = context.WithValue(ctx, KeyValues, &v)
+
+		// Keep what Respond needs to encode the response the way the
+		// client prefers.
+		n := negotiation{
+			encoders:       a.encoders,
+			accept:         strings.Join(r.Header.Values("Accept"), ","),
+			acceptEncoding: strings.Join(r.Header.Values("Accept-Encoding"), ","),
+		}
+		ctx = context.WithValue(ctx, keyNegotiation, &n)
+
+		// Limit the body so a client can't exhaust the memory of the
+		// service.
+		if a.maxBody > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, a.maxBody)
+		}
 
 		// Call the wrapped handler functions.
 		if err := handler(ctx, w, r); err != nil {
+			span.RecordError(err)
 			a.SignalShutdown()
 			return
 		}
+
+		span.SetAttribute("http.status_code", strconv.Itoa(v.StatusCode))
 	}
 
-	a.ContextMux.Handle(method, path, h)
+	return h
 }